@@ -1,3 +1,7 @@
 module assetid
 
 go 1.21.0
+
+require toolkit v0.0.0
+
+replace toolkit => ../../toolkit
@@ -1,4 +1,4 @@
-package main
+package cdsanalysis
 
 import (
 	"cdsanalysis/integration"
@@ -14,6 +14,8 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+var dataFolder = "./data/"
+
 type CDSInput struct {
 	ID              string                   `json:"issuer"`
 	UpfrontPayments map[Tenor]float64        `json:"spreads"`
@@ -42,7 +44,7 @@ func loadCDSData(issuers []string) map[string]CDSInput {
 	// Load CDS data
 	for _, issuer := range issuers {
 		// Load CDS data for issuer
-		path := "./data/" + issuer + ".json"
+		path := dataFolder + issuer + ".json"
 		content, err := os.ReadFile(path)
 		if err != nil {
 			log.Errorf("could not read %s: %v", path, err)
@@ -0,0 +1,9 @@
+// Command cdsanalysis runs the cdsanalysis script on its own, outside of the
+// validation command.
+package main
+
+import "cdsanalysis"
+
+func main() {
+	cdsanalysis.Main()
+}
@@ -1,4 +1,4 @@
-package main
+package cdsanalysis
 
 import (
 	"context"
@@ -10,7 +10,7 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-const (
+var (
 	scalpelURL     = "http://scalpel.service.consul/credit/%s/%s/timeseries?from=%s&to=%s"
 	scalpelURLPROD = "https://api.edgelab.ch/scalpel/credit/%s/%s/timeseries?from=%s&to=%s"
 
@@ -44,9 +44,14 @@ func requestCreditCurves(issuerIDs []string) (map[string]CreditCurve, error) {
 }
 
 func requestCreditCurve(issuerID string) (CreditCurve, error) {
+	host := scalpelURL
+	if environment == "PROD" {
+		host = scalpelURLPROD
+	}
+
 	curve := make(CreditCurve, len(tenors))
 	for _, tenor := range tenors {
-		url := fmt.Sprintf(scalpelURL, issuerID, tenor, fromDate, toDate)
+		url := fmt.Sprintf(host, issuerID, tenor, fromDate, toDate)
 
 		// Fetch the credit curve from Scalpel.
 		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
@@ -1,4 +1,4 @@
-package main
+package cdsanalysis
 
 import (
 	"cdsanalysis/optimization"
@@ -0,0 +1,9 @@
+// Command curveassets runs the curveassets script on its own, outside of the
+// validation command.
+package main
+
+import "curveassets"
+
+func main() {
+	curveassets.Main()
+}
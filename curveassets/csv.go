@@ -1,4 +1,4 @@
-package main
+package curveassets
 
 import (
 	"encoding/csv"
@@ -8,12 +8,14 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func outputToCsv(outputFolder string, outputChan chan IssuerCount, nbIssuers int) error {
+// outputToCsv writes the counts as they come and returns the number of issuers
+// written.
+func outputToCsv(outputFolder string, outputChan chan IssuerCount, nbIssuers int) (int, error) {
 	log.Infof("Building output csvs")
 
 	csvFile, err := os.Create(outputFolder + "output.csv")
 	if err != nil {
-		return fmt.Errorf("error while creating report file: %s", err)
+		return 0, fmt.Errorf("error while creating report file: %s", err)
 	}
 	defer csvFile.Close()
 
@@ -21,12 +23,12 @@ func outputToCsv(outputFolder string, outputChan chan IssuerCount, nbIssuers int
 	defer csvwriter.Flush()
 
 	if err := csvwriter.Write([]string{"id", "asset used"}); err != nil {
-		return fmt.Errorf("error while writing 1st line: %s", err)
+		return 0, fmt.Errorf("error while writing 1st line: %s", err)
 	}
 
 	csvFileComplete, err := os.Create(outputFolder + "output-complete.csv")
 	if err != nil {
-		return fmt.Errorf("error while creating report file: %s", err)
+		return 0, fmt.Errorf("error while creating report file: %s", err)
 	}
 	defer csvFileComplete.Close()
 
@@ -34,7 +36,7 @@ func outputToCsv(outputFolder string, outputChan chan IssuerCount, nbIssuers int
 	defer csvwriterComplete.Flush()
 
 	if err := csvwriterComplete.Write([]string{"id", "asset used", "assets"}); err != nil {
-		return fmt.Errorf("error while writing 1st line: %s", err)
+		return 0, fmt.Errorf("error while writing 1st line: %s", err)
 	}
 
 	count := 0
@@ -46,13 +48,13 @@ func outputToCsv(outputFolder string, outputChan chan IssuerCount, nbIssuers int
 
 		err := csvwriter.Write(strings)
 		if err != nil {
-			return fmt.Errorf("error while writing results: %s", err)
+			return 0, fmt.Errorf("error while writing results: %s", err)
 		}
 
 		strings = append(strings, fmt.Sprintf("%v", issuerCount.Assets))
 		err = csvwriterComplete.Write(strings)
 		if err != nil {
-			return fmt.Errorf("error while writing complete results: %s", err)
+			return 0, fmt.Errorf("error while writing complete results: %s", err)
 		}
 
 		count++
@@ -61,5 +63,5 @@ func outputToCsv(outputFolder string, outputChan chan IssuerCount, nbIssuers int
 		}
 	}
 
-	return nil
+	return count, nil
 }
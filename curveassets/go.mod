@@ -8,3 +8,7 @@ require (
 )
 
 require golang.org/x/sys v0.0.0-20220722155257-8c9f86f7a55f // indirect
+
+require toolkit v0.0.0
+
+replace toolkit => ../toolkit
@@ -1,4 +1,4 @@
-package main
+package curveassets
 
 import (
 	"context"
@@ -11,12 +11,14 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-const (
+var (
 	scalpelURL     = "http://scalpel.service.consul/credit/%s/curveassets?from=%s&to=%s"
 	scalpelURLPROD = "https://api.edgelab.ch/scalpel/credit/%s/curveassets?from=%s&to=%s"
 
 	fromDate = "2022-09-01"
 	toDate   = "2024-10-02"
+
+	workers = 4
 )
 
 type IssuerCount struct {
@@ -40,7 +42,7 @@ func CurveAssetsCount(issuersIDs []string) (chan IssuerCount, error) {
 	// Prepare the tasks and launch the workers.
 	eg, _ := errgroup.WithContext(context.Background())
 
-	for i := 0; i < 4; i++ {
+	for i := 0; i < workers; i++ {
 		eg.Go(func() error {
 			for issuerID := range issuerChan {
 				log.Infof("Fetching curve assets for issuer %s", issuerID)
@@ -0,0 +1,310 @@
+package scripts
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"toolkit/risk"
+)
+
+const (
+	interceptFactor = "(intercept)"
+	residualFactor  = "(residual)"
+)
+
+// Attribution explains the ES of a scenario range at a confidence level by the
+// positions and the risk factors.
+//
+// Risk factors are attributed through a weighted linear regression of the P&L
+// on the shocks of the scenario definitions: the contribution of a factor in a
+// scenario is its coefficient times its shock. The intercept and the part left
+// unexplained are reported as factors of their own, so that the contributions
+// add up to the ES.
+type Attribution struct {
+	Range      string  `json:"range"`
+	Confidence float64 `json:"confidence"`
+	ES         float64 `json:"es"`
+
+	Positions []risk.Contribution `json:"positions"`
+	Factors   []risk.Contribution `json:"factors,omitempty"`
+
+	// Coefficients are the regression coefficients per factor, R2 the share
+	// of the P&L variance they explain and Undefined the number of
+	// scenarios without definition, left out of the regression.
+	Coefficients map[string]float64 `json:"coefficients,omitempty"`
+	R2           float64            `json:"r2,omitempty"`
+	Undefined    int                `json:"undefined,omitempty"`
+
+	Tail []TailScenario `json:"tail"`
+}
+
+// TailScenario is a scenario of the ES tail with the P&L of every position and
+// its largest factor contributions.
+type TailScenario struct {
+	ID        uint32             `json:"id"`
+	PnL       float64            `json:"pnl"`
+	Weight    float64            `json:"weight"`
+	Positions map[string]float64 `json:"positions"`
+	Factors   []FactorPnL        `json:"factors,omitempty"`
+}
+
+// FactorPnL is the P&L explained by the shock of a risk factor in a scenario.
+type FactorPnL struct {
+	Factor string  `json:"factor"`
+	Shock  float64 `json:"shock"`
+	PnL    float64 `json:"pnl"`
+}
+
+// scenarioDefinitions are the risk-factor shocks per scenario ID.
+type scenarioDefinitions map[uint32]map[string]float64
+
+// readDefinitions reads scenario definitions from a JSON object mapping
+// scenario IDs to shocks per risk factor:
+//
+//	{"6500": {"spot:4968068a-...": -0.041, "rate:CHF:Y10": 0.0012}}
+func readDefinitions(path string) (scenarioDefinitions, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	var defs scenarioDefinitions
+	if err := json.Unmarshal(content, &defs); err != nil {
+		return nil, fmt.Errorf("could not unmarshal %s: %w", path, err)
+	}
+
+	return defs, nil
+}
+
+// attribute attributes the ES of the observations to the positions and, with
+// definitions, to the risk factors. At most nbFactors factors are listed per
+// tail scenario.
+func attribute(r scenarioRange, confidence float64, obs []risk.Observation, parts map[string]map[uint32]float64, defs scenarioDefinitions, nbFactors int) (Attribution, error) {
+	a := Attribution{Range: r.String(), Confidence: confidence}
+
+	var err error
+	a.Positions, a.ES, err = risk.Contributions(obs, parts, confidence)
+	if err != nil {
+		return a, err
+	}
+
+	tail, err := risk.Tail(obs, confidence)
+	if err != nil {
+		return a, err
+	}
+
+	var fitted map[string]map[uint32]float64
+	if defs != nil {
+		var components map[string]map[uint32]float64
+		a.Coefficients, components, a.R2, a.Undefined, err = regress(obs, defs)
+		if err != nil {
+			return a, err
+		}
+
+		a.Factors, _, err = risk.Contributions(obs, components, confidence)
+		if err != nil {
+			return a, err
+		}
+
+		fitted = components
+	}
+
+	for _, o := range tail {
+		ts := TailScenario{ID: o.ID, PnL: o.PnL, Weight: o.Weight, Positions: make(map[string]float64, len(parts))}
+		for name, pnl := range parts {
+			ts.Positions[name] = pnl[o.ID]
+		}
+
+		for factor, shock := range defs[o.ID] {
+			ts.Factors = append(ts.Factors, FactorPnL{Factor: factor, Shock: shock, PnL: fitted[factor][o.ID]})
+		}
+
+		sort.SliceStable(ts.Factors, func(i, j int) bool {
+			if math.Abs(ts.Factors[i].PnL) != math.Abs(ts.Factors[j].PnL) {
+				return math.Abs(ts.Factors[i].PnL) > math.Abs(ts.Factors[j].PnL)
+			}
+
+			return ts.Factors[i].Factor < ts.Factors[j].Factor
+		})
+
+		if len(ts.Factors) > nbFactors {
+			ts.Factors = ts.Factors[:nbFactors]
+		}
+
+		a.Tail = append(a.Tail, ts)
+	}
+
+	return a, nil
+}
+
+// regress fits the P&L on the shocks by weighted least squares with an
+// intercept, over the scenarios having a definition. It returns the
+// coefficients, the P&L explained by each factor per scenario along with the
+// intercept and the residual, the R² of the fit and the number of scenarios
+// without definition, whose P&L is left to the residual.
+func regress(obs []risk.Observation, defs scenarioDefinitions) (map[string]float64, map[string]map[uint32]float64, float64, int, error) {
+	factorSet := make(map[string]bool)
+	for _, o := range obs {
+		for f := range defs[o.ID] {
+			factorSet[f] = true
+		}
+	}
+
+	factors := make([]string, 0, len(factorSet))
+	for f := range factorSet {
+		factors = append(factors, f)
+	}
+	sort.Strings(factors)
+
+	// Normal equations of the weighted regression, the intercept first.
+	k := len(factors) + 1
+	xtx := make([][]float64, k)
+	for i := range xtx {
+		xtx[i] = make([]float64, k)
+	}
+	xty := make([]float64, k)
+
+	x := make([]float64, k)
+	undefined := 0
+	var weight, mean float64
+	for _, o := range obs {
+		shocks, ok := defs[o.ID]
+		if !ok {
+			undefined++
+
+			continue
+		}
+
+		x[0] = 1
+		for i, f := range factors {
+			x[i+1] = shocks[f]
+		}
+
+		for i := 0; i < k; i++ {
+			xty[i] += o.Weight * x[i] * o.PnL
+			for j := 0; j < k; j++ {
+				xtx[i][j] += o.Weight * x[i] * x[j]
+			}
+		}
+
+		weight += o.Weight
+		mean += o.Weight * o.PnL
+	}
+
+	if weight == 0 {
+		return nil, nil, 0, undefined, fmt.Errorf("no scenario of the range has a definition")
+	}
+	mean /= weight
+
+	beta, err := solve(xtx, xty)
+	if err != nil {
+		return nil, nil, 0, undefined, err
+	}
+
+	coefficients := make(map[string]float64, len(factors)+1)
+	coefficients[interceptFactor] = beta[0]
+	for i, f := range factors {
+		coefficients[f] = beta[i+1]
+	}
+
+	components := make(map[string]map[uint32]float64, len(factors)+2)
+	for _, name := range append([]string{interceptFactor, residualFactor}, factors...) {
+		components[name] = make(map[uint32]float64, len(obs))
+	}
+
+	var ssRes, ssTot float64
+	for _, o := range obs {
+		shocks, ok := defs[o.ID]
+		if !ok {
+			components[residualFactor][o.ID] = o.PnL
+
+			continue
+		}
+
+		fit := beta[0]
+		components[interceptFactor][o.ID] = beta[0]
+		for i, f := range factors {
+			v := beta[i+1] * shocks[f]
+			components[f][o.ID] = v
+			fit += v
+		}
+
+		components[residualFactor][o.ID] = o.PnL - fit
+		ssRes += o.Weight * (o.PnL - fit) * (o.PnL - fit)
+		ssTot += o.Weight * (o.PnL - mean) * (o.PnL - mean)
+	}
+
+	r2 := 0.0
+	if ssTot > 0 {
+		r2 = 1 - ssRes/ssTot
+	}
+
+	return coefficients, components, r2, undefined, nil
+}
+
+// solve solves the symmetric system by Gaussian elimination with partial
+// pivoting. Factors whose shocks are constant or collinear with others get a
+// zero coefficient rather than failing the regression.
+func solve(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+
+	scale := 0.0
+	for i := 0; i < n; i++ {
+		scale = math.Max(scale, math.Abs(a[i][i]))
+	}
+	if scale == 0 {
+		return nil, fmt.Errorf("regression matrix is zero")
+	}
+	eps := 1e-12 * scale
+
+	m := make([][]float64, n)
+	for i := range a {
+		m[i] = append(append(make([]float64, 0, n+1), a[i]...), b[i])
+	}
+
+	pivots := make([]int, n)
+	for i := range pivots {
+		pivots[i] = -1
+	}
+
+	row := 0
+	for col := 0; col < n && row < n; col++ {
+		best := row
+		for i := row + 1; i < n; i++ {
+			if math.Abs(m[i][col]) > math.Abs(m[best][col]) {
+				best = i
+			}
+		}
+
+		if math.Abs(m[best][col]) <= eps {
+			continue
+		}
+
+		m[row], m[best] = m[best], m[row]
+		for i := 0; i < n; i++ {
+			if i == row {
+				continue
+			}
+
+			f := m[i][col] / m[row][col]
+			for j := col; j <= n; j++ {
+				m[i][j] -= f * m[row][j]
+			}
+		}
+
+		pivots[col] = row
+		row++
+	}
+
+	x := make([]float64, n)
+	for col, r := range pivots {
+		if r >= 0 {
+			x[col] = m[r][n] / m[r][col]
+		}
+	}
+
+	return x, nil
+}
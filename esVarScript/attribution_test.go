@@ -0,0 +1,54 @@
+package scripts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"toolkit/risk"
+)
+
+func Test_attribute(t *testing.T) {
+	t.Parallel()
+
+	// The P&L is 2·spot - rate + 0.1, the rate being constant.
+	defs := make(scenarioDefinitions)
+	obs := make([]risk.Observation, 0, 100)
+	parts := map[string]map[uint32]float64{"a": {}, "b": {}}
+	for i := uint32(0); i < 100; i++ {
+		spot := float64(int(i*37%100)-50) / 100
+		defs[i] = map[string]float64{"spot": spot, "rate": 0.01}
+
+		pnl := 2*spot - 0.01 + 0.1
+		obs = append(obs, risk.Observation{ID: i, PnL: pnl, Weight: 1})
+		parts["a"][i] = pnl - 1
+		parts["b"][i] = 1
+	}
+
+	a, err := attribute(scenarioRange{0, 99}, 0.9, obs, parts, defs, 2)
+	require.NoError(t, err)
+
+	// The 10 lowest spots are -0.50..-0.41.
+	assert.InDelta(t, 2*-0.455+0.09, a.ES, 1e-9)
+	assert.InDelta(t, 1.0, a.R2, 1e-9)
+	assert.InDelta(t, 2.0, a.Coefficients["spot"], 1e-9)
+	assert.Zero(t, a.Coefficients["rate"])
+
+	require.Len(t, a.Positions, 2)
+	assert.Equal(t, "a", a.Positions[0].Name)
+	assert.InDelta(t, a.ES-1, a.Positions[0].ES, 1e-9)
+
+	assert.Equal(t, "spot", a.Factors[0].Name)
+	assert.InDelta(t, 2*-0.455, a.Factors[0].ES, 1e-9)
+
+	sum := 0.0
+	for _, c := range a.Factors {
+		sum += c.ES
+	}
+	assert.InDelta(t, a.ES, sum, 1e-9)
+
+	require.Len(t, a.Tail, 10)
+	assert.Len(t, a.Tail[0].Factors, 2)
+	assert.Equal(t, "spot", a.Tail[0].Factors[0].Factor)
+}
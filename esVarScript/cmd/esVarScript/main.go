@@ -0,0 +1,9 @@
+// Command esVarScript runs the esVarScript script on its own, outside of the
+// validation command.
+package main
+
+import "scripts"
+
+func main() {
+	scripts.Main()
+}
@@ -1,187 +1,274 @@
-package main
+package scripts
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
-	"math"
 	"os"
-	"slices"
-)
+	"strconv"
+	"strings"
 
-const (
-	jsonPath         = "./dev-result.json"
-	lowerScenarioID  = 6500
-	higherScenarioID = 7000
+	"toolkit/health"
+	"toolkit/manifest"
+	"toolkit/risk"
 )
 
-type PricingResult struct {
-	ResultsMap Results `json:"results"`
-}
+var (
+	jsonPath     = "./dev-result.json"
+	quantities   = ""
+	definitions  = ""
+	nbFactors    = 5
+	resamples    = 0
+	jackknife    = false
+	seed         = int64(1)
+	ciLevel      = 0.95
+	parametricES = false
+	deviation    = 0.5
+	minHealth    = 0.0
+	scenarios    = "6500-7000"
+	confidences  = "0.9"
+	mode         = "relative"
+	statusPolicy = statusSkip
+	weightsPath  = ""
+	outputPath   = ""
+	nbWorst      = 10
+)
 
-/*
-	type PricingResult struct {
-		ResultsMap Results `json:"results"`
+// Main parses the command line flags and runs the script.
+func Main() {
+	flag.StringVar(&jsonPath, "input", jsonPath, "Eve pricing result file, or comma-separated files of the positions of a portfolio")
+	flag.StringVar(&quantities, "quantities", quantities, "comma-separated quantities of the positions, 1 by default")
+	flag.StringVar(&definitions, "definitions", definitions, "optional JSON scenario definitions (risk-factor shocks per scenario ID) to attribute the ES tail")
+	flag.IntVar(&nbFactors, "factors", nbFactors, "number of risk factors listed per tail scenario")
+	flag.StringVar(&scenarios, "scenarios", scenarios, "comma-separated inclusive ranges of scenario IDs, e.g. 2501-3000,6500-6999")
+	flag.StringVar(&confidences, "confidence", confidences, "comma-separated confidence levels, e.g. 0.9,0.975")
+	flag.StringVar(&mode, "mode", mode, "P&L against the NPV: relative (value/NPV-1) or absolute (value-NPV)")
+	flag.StringVar(&statusPolicy, "status", statusPolicy, "scenarios not in Success: skip, fail or npv (zero P&L)")
+	flag.StringVar(&weightsPath, "weights", weightsPath, "optional scenario weights, JSON object or id,weight CSV")
+	flag.StringVar(&outputPath, "output", outputPath, "optional .json or .csv report")
+	flag.IntVar(&nbWorst, "worst", nbWorst, "number of worst scenarios listed in the JSON report")
+	flag.IntVar(&resamples, "bootstrap", resamples, "number of bootstrap resamples of the confidence intervals, 0 to skip them")
+	flag.BoolVar(&jackknife, "jackknife", jackknife, "compute jackknife confidence intervals")
+	flag.Int64Var(&seed, "seed", seed, "seed of the bootstrap")
+	flag.Float64Var(&ciLevel, "interval", ciLevel, "level of the confidence intervals")
+	flag.BoolVar(&parametricES, "parametric", parametricES, "compare the ES with Gaussian, Student-t and Cornish-Fisher ones, per position for a portfolio")
+	flag.Float64Var(&deviation, "deviation", deviation, "relative gap between the historical and a parametric ES over which a check is flagged")
+	flag.Float64Var(&minHealth, "min-health", minHealth, "warn when the health score of a range, in percent of clean scenarios, is under this level")
+	flag.Parse()
+
+	run := manifest.New("esvar")
+	run.RecordFlags(flag.CommandLine)
+
+	if mode != "relative" && mode != "absolute" {
+		log.Fatalf("Unknown mode %q", mode)
 	}
-*/
 
-type Results struct {
-	Main      MainResult                `json:"main"`
-	Scenarios map[uint32]ScenarioResult `json:"scenarios"`
-}
-
-type MainResult struct {
-	NPV struct {
-		Value  float64 `json:"value"`
-		Status string  `json:"status"`
-	} `json:"NPV"`
-	NotionalNPV struct {
-		Value  float64 `json:"value"`
-		Status string  `json:"status"`
-	} `json:"notionalNPV"`
-}
-
-type ScenarioResult struct {
-	Value  float64 `json:"value"`
-	Status string  `json:"status"`
-	id     uint32
-}
-
-func main() {
-	content, err := os.ReadFile(jsonPath)
+	ranges, err := parseRanges(scenarios)
 	if err != nil {
-		log.Fatal("Error when opening file: ", err)
+		log.Fatal(err)
 	}
 
-	fmt.Println("File: ", jsonPath)
-	fmt.Println("---------------------")
-
-	var results PricingResult
-	// var results Results
-
-	err = json.Unmarshal(content, &results)
+	levels, err := parseConfidences(confidences)
 	if err != nil {
-		log.Fatal("Error during Unmarshal(): ", err)
+		log.Fatal(err)
 	}
 
-	scenarioValues := results.ResultsMap.Scenarios
-	// scenarioValues := results.Scenarios
-	maxScenarioValue := math.Inf(-1)
-	maxScenarioID := uint32(0)
+	var weights map[uint32]float64
+	if weightsPath != "" {
+		weights, err = readWeights(weightsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	maxScenarioValue2 := math.Inf(-1)
-	maxScenarioID2 := uint32(0)
+		if err := run.AddInput(weightsPath); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	for scenarioId, scenarioResult := range scenarioValues {
-		if scenarioId < lowerScenarioID || scenarioId > higherScenarioID {
-			continue
+	var defs scenarioDefinitions
+	if definitions != "" {
+		defs, err = readDefinitions(definitions)
+		if err != nil {
+			log.Fatal(err)
 		}
 
-		if scenarioResult.Value > maxScenarioValue {
-			maxScenarioValue2 = maxScenarioValue
-			maxScenarioID2 = maxScenarioID
+		if err := run.AddInput(definitions); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-			maxScenarioValue = scenarioResult.Value
-			maxScenarioID = scenarioId
+	paths := strings.Split(jsonPath, ",")
+	amounts, err := parseFloats(quantities)
+	if err != nil {
+		log.Fatalf("Invalid quantities: %v", err)
+	}
 
-			continue
-		}
+	if len(amounts) > len(paths) {
+		log.Fatalf("%d quantities for %d positions", len(amounts), len(paths))
+	}
 
-		if scenarioResult.Value > maxScenarioValue2 {
-			maxScenarioValue2 = scenarioResult.Value
-			maxScenarioID2 = scenarioId
-		}
+	positions, err := loadPositions(paths, amounts)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	fmt.Println("Max scenario ID: ", maxScenarioID)
-	fmt.Println("Max scenario value: ", maxScenarioValue)
+	report := Report{Input: jsonPath, Mode: mode}
+	for _, p := range positions {
+		report.NPV += p.quantity * p.results.Main.NPV.Value
+	}
 
-	fmt.Println("Second max scenario ID: ", maxScenarioID2)
-	fmt.Println("Second max scenario value: ", maxScenarioValue2)
+	healthRanges := make([]health.Range, len(ranges))
+	for i, r := range ranges {
+		healthRanges[i] = health.Range{From: r.from, To: r.to}
+	}
 
-	fmt.Println("---------------------")
+	scores := make(map[string]float64, len(ranges))
+	for i, path := range paths {
+		if err := run.AddInput(path); err != nil {
+			log.Fatal(err)
+		}
 
-	minScenarioValue := math.Inf(1)
-	minScenarioID := uint32(0)
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	minScenarioValue2 := math.Inf(1)
-	minScenarioID2 := uint32(0)
+		h, err := health.Analyze(raw, health.Options{Ranges: healthRanges})
+		if err != nil {
+			log.Fatalf("Health of %s: %v", path, err)
+		}
+		h.Input = positions[i].name
+		report.Health = append(report.Health, h)
 
-	for scenarioId, scenarioResult := range scenarioValues {
-		if scenarioId < lowerScenarioID || scenarioId > higherScenarioID {
-			continue
+		for _, r := range h.Ranges {
+			if score, ok := scores[r.Range]; !ok || r.Score < score {
+				scores[r.Range] = r.Score
+			}
 		}
+	}
 
-		if scenarioResult.Value < minScenarioValue {
-			minScenarioValue2 = minScenarioValue
-			minScenarioID2 = maxScenarioID
+	attributed := defs != nil || len(positions) > 1
+	for _, r := range ranges {
+		obs, parts, statuses, excluded, err := observations(positions, r, mode == "relative", weights, statusPolicy)
+		if err != nil {
+			log.Fatalf("Scenarios %s: %v", r, err)
+		}
 
-			minScenarioValue = scenarioResult.Value
-			minScenarioID = scenarioId
+		run.Count(r.String(), len(obs)+excluded, len(obs))
 
-			continue
+		worst := risk.Sorted(obs)
+		if len(worst) > nbWorst {
+			worst = worst[:nbWorst]
 		}
 
-		if scenarioResult.Value < minScenarioValue2 {
-			minScenarioValue2 = scenarioResult.Value
-			minScenarioID2 = scenarioId
+		for _, level := range levels {
+			measures, err := risk.Compute(obs, level)
+			if err != nil {
+				log.Fatalf("Scenarios %s at %v: %v", r, level, err)
+			}
+
+			result := Result{
+				Range:    r.String(),
+				Measures: measures,
+				Statuses: statuses,
+				Excluded: excluded,
+				Health:   scores[r.String()],
+				Worst:    worst,
+			}
+
+			if result.Health < minHealth {
+				log.Printf("Scenarios %s: health %.2f%% under %v%%, the measures rest on broken scenarios", r, result.Health, minHealth)
+			}
+
+			if resamples > 0 {
+				b, err := risk.Bootstrap(obs, level, ciLevel, resamples, seed)
+				if err != nil {
+					log.Fatalf("Bootstrap of scenarios %s at %v: %v", r, level, err)
+				}
+
+				result.Bootstrap = &b
+			}
+
+			if jackknife {
+				j, err := risk.Jackknife(obs, level, ciLevel)
+				if err != nil {
+					log.Fatalf("Jackknife of scenarios %s at %v: %v", r, level, err)
+				}
+
+				result.Jackknife = &j
+			}
+
+			if parametricES {
+				checks, err := parametric(obs, positions, parts, level, deviation)
+				if err != nil {
+					log.Fatalf("Parametric measures of scenarios %s at %v: %v", r, level, err)
+				}
+
+				result.Parametric = checks
+			}
+
+			if attributed {
+				a, err := attribute(r, level, obs, parts, defs, nbFactors)
+				if err != nil {
+					log.Fatalf("Attribution of scenarios %s at %v: %v", r, level, err)
+				}
+
+				result.Attribution = &a
+			}
+
+			report.Results = append(report.Results, result)
 		}
 	}
 
-	fmt.Println("Second min scenario ID: ", minScenarioID2)
-	fmt.Println("Second min scenario value: ", minScenarioValue2)
+	printReport(os.Stdout, report)
 
-	fmt.Println("Min scenario ID: ", minScenarioID)
-	fmt.Println("Min scenario value: ", minScenarioValue)
+	if outputPath == "" {
+		return
+	}
 
-	// -----------------------------
-	scenarios := make([]ScenarioResult, 0, len(scenarioValues))
-	for scenarioId, scenarioResult := range scenarioValues {
-		if scenarioId < lowerScenarioID || scenarioId > higherScenarioID {
-			continue
-		}
-		scenarioResult.id = scenarioId
-		scenarios = append(scenarios, scenarioResult)
+	if err := writeReport(report, outputPath); err != nil {
+		log.Fatal(err)
 	}
 
-	slices.SortFunc(scenarios, func(a ScenarioResult, b ScenarioResult) int {
-		if a.Value < b.Value {
-			return -1
-		}
+	if err := run.Write(outputPath); err != nil {
+		log.Fatal(err)
+	}
+}
 
-		if a.Value > b.Value {
-			return 1
-		}
+func parseConfidences(value string) ([]float64, error) {
+	levels, err := parseFloats(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid confidence levels: %w", err)
+	}
+
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("no confidence level")
+	}
 
-		return 0
-	})
+	for _, level := range levels {
+		if level <= 0 || level >= 1 {
+			return nil, fmt.Errorf("confidence level %v is not in (0, 1)", level)
+		}
+	}
 
-	fmt.Println("10 highest scenarios: ", scenarios[len(scenarios)-10:])
-	fmt.Println("10 lowest scenarios: ", scenarios[:10])
-	fmt.Println("---------------------")
-	// -----------------------------
-	nbScenarios := 50
-	lowScenarios := scenarios[:nbScenarios]
+	return levels, nil
+}
 
-	var es float64
-	for _, scenario := range lowScenarios {
-		// fmt.Println("Low scenario: ", scenario.id, scenario.Value)
-		es += (scenario.Value) / float64(nbScenarios)
+// parseFloats parses a comma-separated list of numbers, empty for none.
+func parseFloats(value string) ([]float64, error) {
+	values := make([]float64, 0)
+	if strings.TrimSpace(value) == "" {
+		return values, nil
 	}
 
-	v := lowScenarios[len(lowScenarios)-1].Value/results.ResultsMap.Main.NPV.Value - 1
-	es = es/results.ResultsMap.Main.NPV.Value - 1
+	for _, part := range strings.Split(value, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %w", part, err)
+		}
 
-	// v := lowScenarios[len(lowScenarios)-1].Value/results.Main.NPV.Value - 1
-	// es = es/results.Main.NPV.Value - 1
-	vol := 0.0
-	for _, scenario := range scenarios {
-		vol += math.Pow(scenario.Value-results.ResultsMap.Main.NPV.Value, 2)
-		// vol += math.Pow(scenario.Value-results.Main.NPV.Value, 2)
+		values = append(values, v)
 	}
-	vol = math.Sqrt(vol / float64(len(scenarios)))
 
-	fmt.Println("VaR: ", v)
-	fmt.Println("ES: ", es)
-	fmt.Println("Vol: ", vol)
+	return values, nil
 }
@@ -0,0 +1,290 @@
+package scripts
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"toolkit/health"
+	"toolkit/risk"
+)
+
+// Report holds the measures of a pricing result for every scenario range and
+// confidence level.
+type Report struct {
+	Input   string   `json:"input"`
+	Mode    string   `json:"mode"`
+	NPV     float64  `json:"npv"`
+	Results []Result `json:"results"`
+
+	// Health checks the scenarios of every position.
+	Health []health.Report `json:"health"`
+}
+
+// Result holds the measures of a scenario range at a confidence level.
+type Result struct {
+	Range string `json:"range"`
+	risk.Measures
+
+	// Statuses counts the scenarios of the range per status, Excluded those
+	// left out of the measures.
+	Statuses map[string]int `json:"statuses"`
+	Excluded int            `json:"excluded"`
+
+	// Health is the lowest health score of the positions over the range.
+	Health float64 `json:"health"`
+
+	Worst []risk.Observation `json:"worst,omitempty"`
+
+	// Bootstrap and Jackknife are the confidence intervals of the measures.
+	Bootstrap *risk.Intervals `json:"bootstrap,omitempty"`
+	Jackknife *risk.Intervals `json:"jackknife,omitempty"`
+
+	// Parametric compares the ES of the portfolio, then of the positions,
+	// with parametric ones.
+	Parametric []ParametricCheck `json:"parametric,omitempty"`
+
+	Attribution *Attribution `json:"attribution,omitempty"`
+}
+
+// intervals returns the confidence intervals of the result.
+func (r Result) intervals() []*risk.Intervals {
+	intervals := make([]*risk.Intervals, 0, 2)
+	for _, i := range []*risk.Intervals{r.Bootstrap, r.Jackknife} {
+		if i != nil {
+			intervals = append(intervals, i)
+		}
+	}
+
+	return intervals
+}
+
+func writeReport(report Report, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("could not write %s: %w", path, err)
+		}
+
+		return nil
+	}
+
+	header := []string{"range", "confidence", "mode", "npv", "var", "es", "volatility", "scenarios", "tailScenarios", "varScenario", "excluded", "statuses", "health"}
+	if len(report.Results) > 0 {
+		for _, i := range report.Results[0].intervals() {
+			for _, measure := range []string{"var", "es", "volatility"} {
+				header = append(header, i.Method+"_"+measure+"_lower", i.Method+"_"+measure+"_upper")
+			}
+		}
+
+		if len(report.Results[0].Parametric) > 0 {
+			for _, m := range report.Results[0].Parametric[0].Models {
+				header = append(header, m.Model+"_var", m.Model+"_es")
+			}
+			header = append(header, "parametricDeviation", "flagged")
+		}
+	}
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("could not write header of %s: %w", path, err)
+	}
+
+	for _, r := range report.Results {
+		record := []string{
+			r.Range,
+			strconv.FormatFloat(r.Confidence, 'f', -1, 64),
+			report.Mode,
+			strconv.FormatFloat(report.NPV, 'f', -1, 64),
+			strconv.FormatFloat(r.VaR, 'f', -1, 64),
+			strconv.FormatFloat(r.ES, 'f', -1, 64),
+			strconv.FormatFloat(r.Volatility, 'f', -1, 64),
+			strconv.Itoa(r.Scenarios),
+			strconv.Itoa(r.TailScenarios),
+			strconv.FormatUint(uint64(r.VaRScenario), 10),
+			strconv.Itoa(r.Excluded),
+			formatStatuses(r.Statuses),
+			strconv.FormatFloat(r.Health, 'f', -1, 64),
+		}
+
+		for _, i := range r.intervals() {
+			for _, interval := range []risk.Interval{i.VaR, i.ES, i.Volatility} {
+				record = append(record, strconv.FormatFloat(interval.Lower, 'f', -1, 64), strconv.FormatFloat(interval.Upper, 'f', -1, 64))
+			}
+		}
+
+		if len(r.Parametric) > 0 {
+			flagged := make([]string, 0)
+			for _, c := range r.Parametric {
+				if c.Flagged {
+					flagged = append(flagged, c.Name)
+				}
+			}
+
+			for _, m := range r.Parametric[0].Models {
+				record = append(record, strconv.FormatFloat(m.VaR, 'f', -1, 64), strconv.FormatFloat(m.ES, 'f', -1, 64))
+			}
+			record = append(record, strconv.FormatFloat(r.Parametric[0].Deviation, 'f', -1, 64), strings.Join(flagged, " "))
+		}
+
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("could not write %s: %w", path, err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func printReport(w io.Writer, report Report) {
+	fmt.Fprintf(w, "File: %s\nNPV: %v (%s P&L)\n\n", report.Input, report.NPV, report.Mode)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANGE\tCONFIDENCE\tVAR\tES\tVOL\tSCENARIOS\tTAIL\tVAR SCENARIO\tSTATUSES\tHEALTH")
+	for _, r := range report.Results {
+		fmt.Fprintf(tw, "%s\t%v\t%.6g\t%.6g\t%.6g\t%d\t%d\t%d\t%s\t%.2f%%\n",
+			r.Range, r.Confidence, r.VaR, r.ES, r.Volatility, r.Scenarios, r.TailScenarios, r.VaRScenario, formatStatuses(r.Statuses), r.Health)
+	}
+	tw.Flush()
+
+	printIntervals(w, report)
+	printParametric(w, report)
+
+	for _, r := range report.Results {
+		if r.Attribution != nil {
+			printAttribution(w, *r.Attribution)
+		}
+	}
+}
+
+// printIntervals prints the confidence intervals of the measures, if any.
+func printIntervals(w io.Writer, report Report) {
+	if len(report.Results) == 0 || len(report.Results[0].intervals()) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANGE\tCONFIDENCE\tMETHOD\tLEVEL\tVAR\tES\tVOL")
+	for _, r := range report.Results {
+		for _, i := range r.intervals() {
+			fmt.Fprintf(tw, "%s\t%v\t%s\t%v\t%s\t%s\t%s\n",
+				r.Range, r.Confidence, i.Method, i.Level, formatInterval(i.VaR), formatInterval(i.ES), formatInterval(i.Volatility))
+		}
+	}
+	tw.Flush()
+}
+
+// printParametric prints the parametric ES next to the historical one, if
+// any, flagged checks starred.
+func printParametric(w io.Writer, report Report) {
+	if len(report.Results) == 0 || len(report.Results[0].Parametric) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "RANGE\tCONFIDENCE\tNAME\tSKEW\tKURTOSIS\tHISTORICAL ES")
+	for _, m := range report.Results[0].Parametric[0].Models {
+		fmt.Fprintf(tw, "\t%s ES", strings.ToUpper(m.Model))
+	}
+	fmt.Fprintln(tw, "\tDOF\tDEVIATION")
+
+	for _, r := range report.Results {
+		for _, c := range r.Parametric {
+			fmt.Fprintf(tw, "%s\t%v\t%s\t%.3g\t%.3g\t%.6g", r.Range, r.Confidence, c.Name, c.Moments.Skewness, c.Moments.Kurtosis, c.HistoricalES)
+
+			dof := 0.0
+			for _, m := range c.Models {
+				fmt.Fprintf(tw, "\t%.6g", m.ES)
+				dof = math.Max(dof, m.DoF)
+			}
+
+			flag := ""
+			if c.Flagged {
+				flag = "*"
+			}
+			fmt.Fprintf(tw, "\t%.3g\t%.1f%%%s\n", dof, 100*c.Deviation, flag)
+		}
+	}
+	tw.Flush()
+}
+
+func formatInterval(i risk.Interval) string {
+	return fmt.Sprintf("%.6g [%.6g, %.6g]", i.Estimate, i.Lower, i.Upper)
+}
+
+// printAttribution prints the contributions to the ES and the worst tail
+// scenarios.
+func printAttribution(w io.Writer, a Attribution) {
+	fmt.Fprintf(w, "\nES attribution of %s at %v: %.6g\n", a.Range, a.Confidence, a.ES)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POSITION\tES\tSHARE")
+	for _, c := range a.Positions {
+		fmt.Fprintf(tw, "%s\t%.6g\t%.1f%%\n", c.Name, c.ES, 100*c.Share)
+	}
+
+	if a.Factors != nil {
+		fmt.Fprintf(tw, "\nRISK FACTOR (R² %.3f, %d undefined scenarios)\tES\tSHARE\n", a.R2, a.Undefined)
+		for _, c := range a.Factors {
+			fmt.Fprintf(tw, "%s\t%.6g\t%.1f%%\n", c.Name, c.ES, 100*c.Share)
+		}
+	}
+	tw.Flush()
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nTAIL SCENARIO\tP&L\tWEIGHT\tLARGEST FACTORS")
+	for i, s := range a.Tail {
+		if i == nbWorst {
+			fmt.Fprintf(tw, "... %d more\n", len(a.Tail)-nbWorst)
+
+			break
+		}
+
+		factors := make([]string, len(s.Factors))
+		for j, f := range s.Factors {
+			factors[j] = fmt.Sprintf("%s %+.3g (%+.3g)", f.Factor, f.PnL, f.Shock)
+		}
+
+		fmt.Fprintf(tw, "%d\t%.6g\t%.3g\t%s\n", s.ID, s.PnL, s.Weight, strings.Join(factors, ", "))
+	}
+	tw.Flush()
+}
+
+// formatStatuses lists the status counts as "Success:498 Failed:2".
+func formatStatuses(statuses map[string]int) string {
+	keys := make([]string, 0, len(statuses))
+	for k := range statuses {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s:%d", k, statuses[k])
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,79 @@
+package scripts
+
+import (
+	"fmt"
+	"math"
+
+	"toolkit/risk"
+)
+
+// portfolioName names the check of the whole portfolio.
+const portfolioName = "(portfolio)"
+
+// ParametricCheck compares the historical ES of the portfolio or of a position
+// with the ES of parametric models fitted on the same P&L.
+type ParametricCheck struct {
+	Name         string            `json:"name"`
+	HistoricalES float64           `json:"historicalES"`
+	Moments      risk.Moments      `json:"moments"`
+	Models       []risk.Parametric `json:"models"`
+
+	// Deviation is the largest relative gap |historical / parametric - 1|
+	// over the models, Flagged whether it exceeds the threshold.
+	Deviation float64 `json:"deviation"`
+	Flagged   bool    `json:"flagged"`
+}
+
+// parametric checks the portfolio and, when there are several, every
+// position, the P&L of a position in a scenario being its part in parts.
+func parametric(obs []risk.Observation, positions []position, parts map[string]map[uint32]float64, confidence, threshold float64) ([]ParametricCheck, error) {
+	check := func(name string, obs []risk.Observation) (ParametricCheck, error) {
+		measures, err := risk.Compute(obs, confidence)
+		if err != nil {
+			return ParametricCheck{}, err
+		}
+
+		moments, models, err := risk.ParametricMeasures(obs, confidence)
+		if err != nil {
+			return ParametricCheck{}, err
+		}
+
+		c := ParametricCheck{Name: name, HistoricalES: measures.ES, Moments: moments, Models: models}
+		for _, m := range models {
+			if m.ES == 0 {
+				continue
+			}
+
+			c.Deviation = math.Max(c.Deviation, math.Abs(measures.ES/m.ES-1))
+		}
+		c.Flagged = c.Deviation > threshold
+
+		return c, nil
+	}
+
+	portfolio, err := check(portfolioName, obs)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := []ParametricCheck{portfolio}
+	if len(positions) < 2 {
+		return checks, nil
+	}
+
+	for _, p := range positions {
+		part := make([]risk.Observation, len(obs))
+		for i, o := range obs {
+			part[i] = risk.Observation{ID: o.ID, PnL: parts[p.name][o.ID], Weight: o.Weight}
+		}
+
+		c, err := check(p.name, part)
+		if err != nil {
+			return nil, fmt.Errorf("could not check %s: %w", p.name, err)
+		}
+
+		checks = append(checks, c)
+	}
+
+	return checks, nil
+}
@@ -0,0 +1,285 @@
+package scripts
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"toolkit/risk"
+)
+
+const statusSuccess = "Success"
+
+type PricingResult struct {
+	ResultsMap Results `json:"results"`
+}
+
+type Results struct {
+	Main      MainResult                `json:"main"`
+	Scenarios map[uint32]ScenarioResult `json:"scenarios"`
+}
+
+type MainResult struct {
+	NPV         ScenarioResult `json:"NPV"`
+	NotionalNPV ScenarioResult `json:"notionalNPV"`
+}
+
+type ScenarioResult struct {
+	Value  float64 `json:"value"`
+	Status string  `json:"status"`
+}
+
+// scenarioRange is an inclusive range of scenario IDs.
+type scenarioRange struct {
+	from, to uint32
+}
+
+func (r scenarioRange) String() string {
+	return fmt.Sprintf("%d-%d", r.from, r.to)
+}
+
+func (r scenarioRange) contains(id uint32) bool {
+	return id >= r.from && id <= r.to
+}
+
+// parseRanges parses comma-separated ranges such as "2501-3000,6500-6999".
+func parseRanges(value string) ([]scenarioRange, error) {
+	ranges := make([]scenarioRange, 0)
+	for _, part := range strings.Split(value, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("scenario range %q is not of the form from-to", part)
+		}
+
+		from, err := strconv.ParseUint(bounds[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start of scenario range %q: %w", part, err)
+		}
+
+		to, err := strconv.ParseUint(bounds[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end of scenario range %q: %w", part, err)
+		}
+
+		if from > to {
+			return nil, fmt.Errorf("scenario range %q is empty", part)
+		}
+
+		ranges = append(ranges, scenarioRange{uint32(from), uint32(to)})
+	}
+
+	return ranges, nil
+}
+
+// Policies for the scenarios whose status is not Success.
+const (
+	// statusSkip leaves them out of the measures.
+	statusSkip = "skip"
+	// statusFail stops the computation.
+	statusFail = "fail"
+	// statusNPV keeps them with the NPV as value, that is a zero P&L.
+	statusNPV = "npv"
+)
+
+// loadResult reads an Eve pricing result.
+func loadResult(path string) (Results, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Results{}, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	var result PricingResult
+	if err := json.Unmarshal(content, &result); err != nil {
+		return Results{}, fmt.Errorf("could not unmarshal %s: %w", path, err)
+	}
+
+	if s := result.ResultsMap.Main.NPV.Status; s != statusSuccess {
+		return Results{}, fmt.Errorf("NPV of %s has status %q", path, s)
+	}
+
+	return result.ResultsMap, nil
+}
+
+// position is an Eve pricing result held in a quantity.
+type position struct {
+	name     string
+	quantity float64
+	results  Results
+}
+
+// loadPositions reads the Eve results of the positions, named after their
+// file.
+func loadPositions(paths []string, quantities []float64) ([]position, error) {
+	positions := make([]position, 0, len(paths))
+	for i, path := range paths {
+		results, err := loadResult(path)
+		if err != nil {
+			return nil, err
+		}
+
+		quantity := 1.0
+		if i < len(quantities) {
+			quantity = quantities[i]
+		}
+
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		for _, p := range positions {
+			if p.name == name {
+				name = fmt.Sprintf("%s#%d", name, i+1)
+			}
+		}
+		positions = append(positions, position{name: name, quantity: quantity, results: results})
+	}
+
+	return positions, nil
+}
+
+// observations returns the P&L of the portfolio for the scenarios of the range
+// against its NPV, absolute or relative, along with the P&L of each position
+// on the same scale, the count of scenarios per status and the number of
+// scenarios left out. A scenario missing from a position has status Missing.
+func observations(positions []position, r scenarioRange, relative bool, weights map[uint32]float64, policy string) ([]risk.Observation, map[string]map[uint32]float64, map[string]int, int, error) {
+	npv := 0.0
+	ids := make(map[uint32]bool)
+	for _, p := range positions {
+		npv += p.quantity * p.results.Main.NPV.Value
+
+		for id := range p.results.Scenarios {
+			if r.contains(id) {
+				ids[id] = true
+			}
+		}
+	}
+
+	scale := 1.0
+	if relative {
+		if npv == 0 {
+			return nil, nil, nil, 0, fmt.Errorf("relative P&L against a zero NPV")
+		}
+
+		scale = 1 / npv
+	}
+
+	obs := make([]risk.Observation, 0, len(ids))
+	parts := make(map[string]map[uint32]float64, len(positions))
+	for _, p := range positions {
+		parts[p.name] = make(map[uint32]float64, len(ids))
+	}
+
+	statuses := make(map[string]int)
+	excluded := 0
+
+	for id := range ids {
+		pnl := make([]float64, len(positions))
+		skip := false
+		for i, p := range positions {
+			scenario, ok := p.results.Scenarios[id]
+
+			status := scenario.Status
+			if !ok || status == "" {
+				status = "Missing"
+			}
+			statuses[status]++
+
+			value := scenario.Value
+			if status != statusSuccess {
+				switch policy {
+				case statusSkip:
+					skip = true
+				case statusFail:
+					return nil, nil, nil, 0, fmt.Errorf("scenario %d of %s has status %q", id, p.name, status)
+				case statusNPV:
+					value = p.results.Main.NPV.Value
+				default:
+					return nil, nil, nil, 0, fmt.Errorf("unknown status policy %q", policy)
+				}
+			}
+
+			pnl[i] = p.quantity * (value - p.results.Main.NPV.Value) * scale
+		}
+
+		if skip {
+			excluded++
+
+			continue
+		}
+
+		weight := 1.0
+		if weights != nil {
+			w, ok := weights[id]
+			if !ok {
+				return nil, nil, nil, 0, fmt.Errorf("scenario %d has no weight", id)
+			}
+			weight = w
+		}
+
+		total := 0.0
+		for i, p := range positions {
+			parts[p.name][id] = pnl[i]
+			total += pnl[i]
+		}
+
+		obs = append(obs, risk.Observation{ID: id, PnL: total, Weight: weight})
+	}
+
+	// Scenario order makes the bootstrap reproducible.
+	sort.Slice(obs, func(i, j int) bool {
+		return obs[i].ID < obs[j].ID
+	})
+
+	return obs, parts, statuses, excluded, nil
+}
+
+// readWeights reads scenario weights from a JSON object keyed by scenario ID
+// or from a CSV file of id,weight rows with an optional header.
+func readWeights(path string) (map[uint32]float64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	weights := make(map[uint32]float64)
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(content, &weights); err != nil {
+			return nil, fmt.Errorf("could not unmarshal %s: %w", path, err)
+		}
+
+		return weights, nil
+	}
+
+	records, err := csv.NewReader(strings.NewReader(string(content))).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d of %s has no weight", i+1, path)
+		}
+
+		id, err := strconv.ParseUint(strings.TrimSpace(record[0]), 10, 32)
+		if err != nil {
+			if i == 0 {
+				// Header.
+				continue
+			}
+
+			return nil, fmt.Errorf("line %d of %s: invalid scenario ID: %w", i+1, path, err)
+		}
+
+		weight, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d of %s: invalid weight: %w", i+1, path, err)
+		}
+
+		weights[uint32(id)] = weight
+	}
+
+	return weights, nil
+}
@@ -0,0 +1,9 @@
+// Command fire runs the fire script on its own, outside of the
+// validation command.
+package main
+
+import "fire"
+
+func main() {
+	fire.Main()
+}
@@ -1,4 +1,4 @@
-package main
+package fire
 
 import (
 	"context"
@@ -8,18 +8,25 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-const maestroHost = "https://api.dev.edge-lab.ch/maestro"
+var maestroHost = "https://api.dev.edge-lab.ch/maestro"
 
-func retriggerPricings(assets []string) error {
+// retriggerPricings retriggers the pricing of every asset and returns the
+// number of pricings retriggered.
+func retriggerPricings(assets []string) (int, error) {
+	retriggered := 0
 	for _, asset := range assets {
 		log.Infof("Retriggering pricing for asset %s", asset)
 		err := retriggerPricing(asset)
 		if err != nil {
 			log.Errorf("Error while retriggering pricing for asset %s: %v", asset, err)
+
+			continue
 		}
+
+		retriggered++
 	}
 
-	return nil
+	return retriggered, nil
 }
 
 func retriggerPricing(asset string) error {
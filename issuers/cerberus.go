@@ -1,4 +1,4 @@
-package main
+package issuers
 
 import (
 	"context"
@@ -8,12 +8,12 @@ import (
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
-)
 
-const (
-	cerberusHost = "https://api.edgelab.ch/cerberus"
+	"toolkit/checkpoint"
 )
 
+var cerberusHost = "https://api.edgelab.ch/cerberus"
+
 // IssuerList  lists all issuers IDs by querying cerberus.
 func IssuerList() ([]string, error) {
 	size := 1000
@@ -116,19 +116,23 @@ type SIC struct {
 	Description string `json:"sicDescription"`
 }
 
-func IssuerDescriptions(issuers []string) ([]IssuerDescription, error) {
-	descriptions := make([]IssuerDescription, 0, len(issuers))
-
-	log.Infof("Fetching %d issuers", len(issuers))
+// IssuerDescriptions fetches the description of every issuer not yet in the
+// journal, hands it to output and records it as completed.
+func IssuerDescriptions(issuers []string, journal *checkpoint.Journal, output func(IssuerDescription) error) error {
+	log.Infof("Fetching %d issuers, %d already done", len(issuers), journal.Len())
 
 	count := 0
 
 	for _, issuerID := range issuers {
+		if journal.Done(issuerID) {
+			continue
+		}
+
 		url := fmt.Sprintf("%s/issuers/%s?view=full", cerberusHost, issuerID)
 
 		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
 		if err != nil {
-			return nil, fmt.Errorf("could not create the request: %w", err)
+			return fmt.Errorf("could not create the request: %w", err)
 		}
 		req.Header.Set("x-internal-service", "validation")
 		req.Header.Set("Content-Type", "application/json")
@@ -138,14 +142,14 @@ func IssuerDescriptions(issuers []string) ([]IssuerDescription, error) {
 		client := &http.Client{}
 		res, err := client.Do(req)
 		if err != nil {
-			return nil, fmt.Errorf("could not send the request: %w", err)
+			return fmt.Errorf("could not send the request: %w", err)
 		}
 
 		defer res.Body.Close()
 
 		raw, err := io.ReadAll(res.Body)
 		if err != nil {
-			return nil, fmt.Errorf("could not read response body: %w", err)
+			return fmt.Errorf("could not read response body: %w", err)
 		}
 
 		if res.StatusCode != http.StatusOK {
@@ -176,7 +180,13 @@ func IssuerDescriptions(issuers []string) ([]IssuerDescription, error) {
 			}
 		}
 
-		descriptions = append(descriptions, description)
+		if err := output(description); err != nil {
+			return err
+		}
+
+		if err := journal.Record(issuerID, description); err != nil {
+			return err
+		}
 
 		count++
 		if count%1000 == 0 {
@@ -184,5 +194,5 @@ func IssuerDescriptions(issuers []string) ([]IssuerDescription, error) {
 		}
 	}
 
-	return descriptions, nil
+	return nil
 }
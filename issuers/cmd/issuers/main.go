@@ -0,0 +1,9 @@
+// Command issuers runs the issuers script on its own, outside of the
+// validation command.
+package main
+
+import "issuers"
+
+func main() {
+	issuers.Main()
+}
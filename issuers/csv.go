@@ -1,4 +1,4 @@
-package main
+package issuers
 
 import (
 	"encoding/csv"
@@ -8,58 +8,86 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func outputToCsv(outputFolder string, descriptions []IssuerDescription) error {
+// descriptionWriter writes one CSV row per issuer description and flushes it
+// right away, so that an interrupted run keeps the rows already fetched.
+type descriptionWriter struct {
+	file      *os.File
+	csvwriter *csv.Writer
+}
+
+func newDescriptionWriter(outputFolder string) (*descriptionWriter, error) {
 	log.Infof("Building output csvs")
 
 	csvFile, err := os.Create(outputFolder + "issuers_descriptions.csv")
 	if err != nil {
-		return fmt.Errorf("error while creating report file: %s", err)
+		return nil, fmt.Errorf("error while creating report file: %s", err)
 	}
 
 	csvwriter := csv.NewWriter(csvFile)
-	defer csvwriter.Flush()
 
 	if err := csvwriter.Write([]string{"id", "name", "shortName", "country", "stateProvince", "sector name", "countryOfRisk ID", "industry name", "countryOfIncorporation ID", "market value", "market cap", "primary exchange countries ids"}); err != nil {
-		return fmt.Errorf("error while writing id: %s", err)
+		csvFile.Close()
+
+		return nil, fmt.Errorf("error while writing id: %s", err)
+	}
+
+	return &descriptionWriter{
+		file:      csvFile,
+		csvwriter: csvwriter,
+	}, nil
+}
+
+func (w *descriptionWriter) Write(description IssuerDescription) error {
+	strings := []string{
+		description.ID,
+		description.Name,
+		description.ShortName,
+		description.Country.Name,
+		description.StateProvince,
+		description.Sector.Name,
+		description.CountryOfRisk.ID,
+		description.Industry.Name,
+		description.CountryOfIncorporation.ID,
+	}
+
+	if description.MarketValue != nil {
+		strings = append(strings, fmt.Sprintf("%f", *description.MarketValue))
+	} else {
+		strings = append(strings, "")
+	}
+
+	if description.MarketCap != nil {
+		strings = append(strings, *description.MarketCap)
+	} else {
+		strings = append(strings, "")
 	}
 
-	for _, description := range descriptions {
-		strings := []string{
-			description.ID,
-			description.Name,
-			description.ShortName,
-			description.Country.Name,
-			description.StateProvince,
-			description.Sector.Name,
-			description.CountryOfRisk.ID,
-			description.Industry.Name,
-			description.CountryOfIncorporation.ID,
-		}
-
-		if description.MarketValue != nil {
-			strings = append(strings, fmt.Sprintf("%f", *description.MarketValue))
-		} else {
-			strings = append(strings, "")
-		}
-
-		if description.MarketCap != nil {
-			strings = append(strings, *description.MarketCap)
-		} else {
-			strings = append(strings, "")
-		}
-
-		primaryExchangeCountries := make([]string, 0, len(description.PrimaryExchangeCountries))
-		for _, country := range description.PrimaryExchangeCountries {
-			primaryExchangeCountries = append(primaryExchangeCountries, country.ID)
-		}
-
-		strings = append(strings, fmt.Sprintf("%v", primaryExchangeCountries))
-
-		err := csvwriter.Write(strings)
-		if err != nil {
-			return fmt.Errorf("error while writing results: %s", err)
-		}
+	primaryExchangeCountries := make([]string, 0, len(description.PrimaryExchangeCountries))
+	for _, country := range description.PrimaryExchangeCountries {
+		primaryExchangeCountries = append(primaryExchangeCountries, country.ID)
+	}
+
+	strings = append(strings, fmt.Sprintf("%v", primaryExchangeCountries))
+
+	if err := w.csvwriter.Write(strings); err != nil {
+		return fmt.Errorf("error while writing results: %s", err)
+	}
+
+	w.csvwriter.Flush()
+	if err := w.csvwriter.Error(); err != nil {
+		return fmt.Errorf("error while flushing results: %s", err)
 	}
 
 	return nil
 }
+
+func (w *descriptionWriter) Close() error {
+	w.csvwriter.Flush()
+	if err := w.csvwriter.Error(); err != nil {
+		w.file.Close()
+
+		return fmt.Errorf("error while flushing results: %s", err)
+	}
+
+	return w.file.Close()
+}
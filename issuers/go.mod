@@ -5,3 +5,7 @@ go 1.21.0
 require github.com/sirupsen/logrus v1.9.3
 
 require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+
+require toolkit v0.0.0
+
+replace toolkit => ../toolkit
@@ -1,4 +1,4 @@
-package main
+package issuersratings
 
 import (
 	"context"
@@ -11,9 +11,7 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-const (
-	cerberusHost = "https://api.edgelab.ch/cerberus"
-)
+var cerberusHost = "https://api.edgelab.ch/cerberus"
 
 // IssuerList  lists all issuers IDs by querying cerberus.
 func IssuerList() ([]string, error) {
@@ -0,0 +1,9 @@
+// Command issuersratings runs the issuersratings script on its own, outside of the
+// validation command.
+package main
+
+import "issuersratings"
+
+func main() {
+	issuersratings.Main()
+}
@@ -1,4 +1,4 @@
-package main
+package issuersratings
 
 import (
 	"encoding/csv"
@@ -15,6 +15,7 @@ func outputToCsv(outputFolder string, issuersRatings chan *IssuerCreditRating) e
 	if err != nil {
 		return fmt.Errorf("error while creating report file: %s", err)
 	}
+	defer csvFile.Close()
 
 	csvwriter := csv.NewWriter(csvFile)
 	defer csvwriter.Flush()
@@ -32,6 +33,12 @@ func outputToCsv(outputFolder string, issuersRatings chan *IssuerCreditRating) e
 		if err != nil {
 			return fmt.Errorf("error while writing results: %s", err)
 		}
+
+		// Flush every row, so that an interrupted run keeps what was fetched.
+		csvwriter.Flush()
+		if err := csvwriter.Error(); err != nil {
+			return fmt.Errorf("error while flushing results: %s", err)
+		}
 	}
 
 	return nil
@@ -53,3 +53,7 @@ require (
 	google.golang.org/protobuf v1.36.4 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+require toolkit v0.0.0
+
+replace toolkit => ../toolkit
@@ -1,13 +1,14 @@
-package main
+package liquidityvalidation
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
+
+	"toolkit/checkpoint"
 )
 
 type RequestInput struct {
@@ -26,12 +27,14 @@ type Request struct {
 	Payload json.RawMessage
 }
 
-const (
+var (
 	requestURL     = "http://adam-http.service.consul/debug/dump/request"
 	requestURLPROD = "https://api.edgelab.ch/adam/debug/dump/request"
 )
 
-func requestAdam(ids []string) ([]Request, error) {
+// requestAdam dumps the Eve request of every asset. Dumps already in the
+// journal are reused instead of being requested again.
+func requestAdam(ids []string, journal *checkpoint.Journal) ([]Request, error) {
 	ctx := context.Background()
 	output := make([]Request, 0, len(ids))
 
@@ -44,6 +47,23 @@ func requestAdam(ids []string) ([]Request, error) {
 	}
 
 	for _, id := range ids {
+		var payload json.RawMessage
+		done, err := journal.Result(id, &payload)
+		if err != nil {
+			return nil, err
+		}
+
+		if done {
+			if payload != nil {
+				output = append(output, Request{
+					ID:      id,
+					Payload: payload,
+				})
+			}
+
+			continue
+		}
+
 		input := RequestInput{
 			Run: RunDate{
 				Date: snapshot,
@@ -75,21 +95,17 @@ func requestAdam(ids []string) ([]Request, error) {
 			req.Header.Set("Authorization", "Bearer "+tokenPROD)
 		}
 
-		client := &http.Client{}
-		res, err := client.Do(req)
+		status, raw, err := sendRequest(req, "adam", snapshot, body, 0)
 		if err != nil {
-			return nil, fmt.Errorf("could not send the request: %w", err)
+			return nil, err
 		}
 
-		defer res.Body.Close()
-
-		raw, err := io.ReadAll(res.Body)
-		if err != nil {
-			return nil, fmt.Errorf("could not read response body: %w", err)
+		if status != http.StatusOK {
+			continue
 		}
 
-		if s := res.StatusCode; s != http.StatusOK {
-			continue
+		if err := journal.Record(id, json.RawMessage(raw)); err != nil {
+			return nil, err
 		}
 
 		output = append(output, Request{
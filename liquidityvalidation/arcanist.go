@@ -1,14 +1,13 @@
-package main
+package liquidityvalidation
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
+	"sort"
 )
 
 type ArcanistRequestInput struct {
@@ -53,11 +52,16 @@ type ArcanistOutput struct {
 	Results map[int]ArcanistResult `json:"results"`
 }
 
+// ArcanistResult is the result of a position. A null Result comes with the
+// status, error and messages explaining it, when Arcanist gives them.
 type ArcanistResult struct {
-	Result *float64 `json:"result"`
+	Result   *float64 `json:"result"`
+	Status   string   `json:"status,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Messages []string `json:"messages,omitempty"`
 }
 
-const (
+var (
 	arcanistRequestURL     = "http://arcanist-http.service.consul/v6/positions/quantile-risk-measure"
 	arcanistRequestURLPROD = "https://api.edgelab.ch/arcanist/v6/positions/quantile-risk-measure"
 
@@ -66,6 +70,12 @@ const (
 	metricCurrency  = "local"
 	confidenceLevel = 0.9
 	quantityUnit    = "ABSOLUTE"
+
+	positionCurrency = "USD"
+	firstScenarioID  = 6500
+	nbScenarios      = 500
+	scenarioHorizon  = 30.0
+	batchSize        = 50
 )
 
 func requestArcanist(ids []liquidityOutput) (map[string]float64, map[string]float64, error) {
@@ -76,11 +86,11 @@ func requestArcanist(ids []liquidityOutput) (map[string]float64, map[string]floa
 	maxNumber := len(ids)
 
 	counter := 0
-	partialIDs := make([]liquidityOutput, 0, 50)
+	partialIDs := make([]liquidityOutput, 0, batchSize)
 	for i, id := range ids {
 		partialIDs = append(partialIDs, id)
 		counter++
-		if len(partialIDs) < 50 && i != maxNumber-1 {
+		if len(partialIDs) < batchSize && i != maxNumber-1 {
 			continue
 		}
 
@@ -102,7 +112,7 @@ func requestArcanist(ids []liquidityOutput) (map[string]float64, map[string]floa
 			outputMDLiquidity[k] = v
 		}
 
-		partialIDs = make([]liquidityOutput, 0, 50)
+		partialIDs = make([]liquidityOutput, 0, batchSize)
 
 		log.Printf("Processed Arcanist %d/%d assets (%f%%)", counter, maxNumber, float64(counter)/float64(maxNumber)*100)
 	}
@@ -111,26 +121,68 @@ func requestArcanist(ids []liquidityOutput) (map[string]float64, map[string]floa
 }
 
 func requestArcanistPartial(ctx context.Context, ids []liquidityOutput, withQELiquidity bool) (map[string]float64, error) {
-	scenarios := make(map[string]ArcanistScenario, 500)
-	for i := 1; i <= 500; i++ {
-		id := i + 6499
-		scenarios[fmt.Sprintf("%d", id)] = ArcanistScenario{
-			ID:        id,
-			Weight:    1.0,
-			Amplitude: 1.0,
-		}
-	}
-
 	positions := make(map[int]ArcanistPosition, len(ids))
 	for i, id := range ids {
 		positions[i] = ArcanistPosition{
 			Asset:     id.id,
 			Quantity:  1.0,
-			Currency:  "USD",
+			Currency:  positionCurrency,
 			Liquidity: float64(id.horizon),
 		}
 	}
 
+	results, err := requestArcanistPositions(ctx, positions, withQELiquidity)
+	if err != nil {
+		return nil, err
+	}
+
+	outputMap := make(map[string]float64, len(results))
+	for i, v := range results {
+		outputMap[positions[i].Asset] = v
+	}
+
+	return outputMap, nil
+}
+
+// riskParameters are the parameters of a risk measure request.
+type riskParameters struct {
+	Metric          string
+	ConfidenceLevel float64
+	FirstScenarioID int
+	NbScenarios     int
+	ScenarioHorizon float64
+}
+
+// defaultRiskParameters returns the parameters set by the flags.
+func defaultRiskParameters() riskParameters {
+	return riskParameters{
+		Metric:          metric,
+		ConfidenceLevel: confidenceLevel,
+		FirstScenarioID: firstScenarioID,
+		NbScenarios:     nbScenarios,
+		ScenarioHorizon: scenarioHorizon,
+	}
+}
+
+// requestArcanistPositions returns the risk measure of the positions by index,
+// leaving out those without result.
+func requestArcanistPositions(ctx context.Context, positions map[int]ArcanistPosition, withQELiquidity bool) (map[int]float64, error) {
+	return requestArcanistMeasure(ctx, defaultRiskParameters(), positions, withQELiquidity)
+}
+
+// requestArcanistMeasure returns the risk measure of the positions with the
+// parameters, by index, leaving out those without result.
+func requestArcanistMeasure(ctx context.Context, params riskParameters, positions map[int]ArcanistPosition, withQELiquidity bool) (map[int]float64, error) {
+	scenarios := make(map[string]ArcanistScenario, params.NbScenarios)
+	for i := 0; i < params.NbScenarios; i++ {
+		id := params.FirstScenarioID + i
+		scenarios[fmt.Sprintf("%d", id)] = ArcanistScenario{
+			ID:        id,
+			Weight:    1.0,
+			Amplitude: 1.0,
+		}
+	}
+
 	snapshot := snapshotDEV
 	if environment == "PROD" {
 		snapshot = snapshotPROD
@@ -144,14 +196,14 @@ func requestArcanistPartial(ctx context.Context, ids []liquidityOutput, withQELi
 	input := ArcanistRequestInput{
 		Context: ArcanistContext{
 			Snapshot:        snapshot,
-			Metric:          metric,
+			Metric:          params.Metric,
 			MetricUnit:      metricUnit,
 			MetricCurrency:  metricCurrency,
-			ConfidenceLevel: confidenceLevel,
+			ConfidenceLevel: params.ConfidenceLevel,
 			Scenarios:       scenarios,
 			TimeHorizon: TimeHorizon{
 				ScenarioHorizon: Value{
-					Value: 30,
+					Value: params.ScenarioHorizon,
 				},
 			},
 			RiskType: riskType,
@@ -160,13 +212,15 @@ func requestArcanistPartial(ctx context.Context, ids []liquidityOutput, withQELi
 		Positions:    positions,
 	}
 
+	if err := validateArcanistRequest(input); err != nil {
+		return nil, err
+	}
+
 	body, err := json.Marshal(input)
 	if err != nil {
 		return nil, fmt.Errorf("could not marshal the liquidity request: %w", err)
 	}
 
-	_ = os.WriteFile("aaa/aaa.json", body, 0644)
-
 	url := arcanistRequestURL
 	if environment == "PROD" {
 		url = arcanistRequestURLPROD
@@ -184,35 +238,59 @@ func requestArcanistPartial(ctx context.Context, ids []liquidityOutput, withQELi
 		req.Header.Set("Authorization", "Bearer "+tokenPROD)
 	}
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	status, raw, err := sendRequest(req, "arcanist", snapshot, body, 0)
 	if err != nil {
-		return nil, fmt.Errorf("could not send the request: %w", err)
+		return nil, err
 	}
 
-	defer res.Body.Close()
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d: %s", status, string(raw))
+	}
 
-	raw, err := io.ReadAll(res.Body)
+	outputMap, failures, err := decodeArcanistResults(raw, positions)
 	if err != nil {
-		return nil, fmt.Errorf("could not read response body: %w", err)
+		return nil, err
 	}
+	positionFailures.add(failures...)
 
-	if s := res.StatusCode; s != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", s, string(raw))
-	}
+	return outputMap, nil
+}
 
+// decodeArcanistResults returns the results of the positions by index, and a
+// failure for every position without result.
+func decodeArcanistResults(raw []byte, positions map[int]ArcanistPosition) (map[int]float64, []positionFailure, error) {
 	var output ArcanistOutput
 	if err := json.Unmarshal(raw, &output); err != nil {
-		return nil, fmt.Errorf("could not unmarshal the response: %w", err)
+		return nil, nil, fmt.Errorf("could not unmarshal the response: %w", err)
 	}
 
-	outputMap := make(map[string]float64, len(output.Results))
-	for i, o := range output.Results {
-		if o.Result == nil {
-			continue
+	outputMap := make(map[int]float64, len(output.Results))
+	failures := make([]positionFailure, 0)
+	indexes := make([]int, 0, len(positions))
+	for i := range positions {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	for _, i := range indexes {
+		p := positions[i]
+		o, ok := output.Results[i]
+		switch {
+		case !ok:
+			failures = append(failures, positionFailure{Service: "arcanist", Asset: p.Asset, Code: arcanistMissing, Key: fmt.Sprintf("%d", i)})
+		case o.Result == nil:
+			f := positionFailure{Service: "arcanist", Asset: p.Asset, Code: arcanistNoResult, Key: fmt.Sprintf("%d", i), Messages: o.Messages}
+			if o.Status != "" {
+				f.Code = o.Status
+			}
+			if o.Error != "" {
+				f.Messages = append([]string{o.Error}, f.Messages...)
+			}
+			failures = append(failures, f)
+		default:
+			outputMap[i] = *o.Result
 		}
-		outputMap[positions[i].Asset] = *o.Result
 	}
 
-	return outputMap, nil
+	return outputMap, failures, nil
 }
@@ -0,0 +1,59 @@
+package liquidityvalidation
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"toolkit/cache"
+)
+
+// responseCache stores the answers of the services, so that a repeated or
+// resumed run does not fetch them again. It is nil when caching is disabled.
+var responseCache *cache.Cache
+
+// sendRequest sends the request and returns the status code and body of the
+// response. Successful responses are cached under the service, URL, snapshot
+// and request body; a zero TTL means the entry never expires.
+func sendRequest(req *http.Request, service, snapshot string, body []byte, ttl time.Duration) (int, []byte, error) {
+	key := cache.Key{
+		Service:  service,
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Snapshot: snapshot,
+		Body:     body,
+	}
+
+	if responseCache != nil {
+		cached, ok, err := responseCache.Get(key)
+		if err != nil {
+			log.Warnf("could not read %s response from cache: %v", service, err)
+		} else if ok {
+			return http.StatusOK, cached, nil
+		}
+	}
+
+	client := &http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not send the request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not read response body: %w", err)
+	}
+
+	if res.StatusCode == http.StatusOK && responseCache != nil {
+		if err := responseCache.Put(key, raw, ttl); err != nil {
+			log.Warnf("could not store %s response in cache: %v", service, err)
+		}
+	}
+
+	return res.StatusCode, raw, nil
+}
@@ -0,0 +1,9 @@
+// Command liquidityvalidation runs the liquidityvalidation script on its own, outside of the
+// validation command.
+package main
+
+import "liquidityvalidation"
+
+func main() {
+	liquidityvalidation.Main()
+}
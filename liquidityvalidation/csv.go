@@ -1,4 +1,4 @@
-package main
+package liquidityvalidation
 
 import (
 	"encoding/csv"
@@ -8,10 +8,10 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func outputToCsv(outputMD []liquidityOutput, outputEve map[string]eveOutput, outputArcanist, outputArcanistMD, outputRecco map[string]float64) error {
+func outputToCsv(outputMD []liquidityOutput, outputEve map[string]eveOutput, outputVolumes map[string]volumeEstimate, rates []float64, outputArcanist, outputArcanistMD, outputRecco map[string]float64) error {
 	log.Infof("Building output csv")
 
-	csvFile, err := os.Create("output.csv")
+	csvFile, err := os.Create(outputPath)
 	if err != nil {
 		return fmt.Errorf("error while creating report file: %s", err)
 	}
@@ -20,24 +20,31 @@ func outputToCsv(outputMD []liquidityOutput, outputEve map[string]eveOutput, out
 	csvwriter := csv.NewWriter(csvFile)
 	defer csvwriter.Flush()
 
-	if err := csvwriter.Write([]string{"id", "marketCap", "horizonPoC", "horizonMD", "horizonNoVolumes", "horizonVolumes", "esHistInno30D-ArcanistLiquidity", "esHistInno30D-Arcanist", "esHistInno30D-Recco"}); err != nil {
+	header := []string{"id", "marketCap", "horizonPoC", "horizonMD", "horizonNoVolumes", "horizonVolumes", "horizonVolumeEstimate", "horizonVolumeGap", "adv", "sizeToADV"}
+	for _, rate := range rates {
+		header = append(header, fmt.Sprintf("liquidationDays-%v", rate))
+	}
+	header = append(header, "esHistInno30D-ArcanistLiquidity", "esHistInno30D-Arcanist", "esHistInno30D-Recco", "error")
+
+	if err := csvwriter.Write(header); err != nil {
 		return fmt.Errorf("error while writing id: %s", err)
 	}
 
 	for _, result := range outputMD {
 		eveResult, ok := outputEve[result.id]
 		if !ok {
-			err := csvwriter.Write([]string{
+			row := []string{
 				result.id,
 				"",
 				"",
 				fmt.Sprintf("%d", result.horizon),
 				"",
 				"",
-				"",
-				"",
-				"",
-			})
+			}
+			row = append(row, volumeColumns(outputVolumes, result.id, nil, rates)...)
+			row = append(row, "", "", "", errorColumn(result))
+
+			err := csvwriter.Write(row)
 			if err != nil {
 				return fmt.Errorf("error while writing results: %s", err)
 			}
@@ -72,6 +79,8 @@ func outputToCsv(outputMD []liquidityOutput, outputEve map[string]eveOutput, out
 			strings = append(strings, "")
 		}
 
+		strings = append(strings, volumeColumns(outputVolumes, result.id, eveResult.HorizonTradingVolumes, rates)...)
+
 		arcanistValue, ok := outputArcanist[result.id]
 		if !ok {
 			strings = append(strings, "")
@@ -93,6 +102,8 @@ func outputToCsv(outputMD []liquidityOutput, outputEve map[string]eveOutput, out
 			strings = append(strings, fmt.Sprintf("%f", reccoValue))
 		}
 
+		strings = append(strings, errorColumn(result))
+
 		err := csvwriter.Write(strings)
 		if err != nil {
 			return fmt.Errorf("error while writing results: %s", err)
@@ -101,3 +112,42 @@ func outputToCsv(outputMD []liquidityOutput, outputEve map[string]eveOutput, out
 
 	return nil
 }
+
+// volumeColumns returns the volume-based estimate of an asset and its gap to
+// the Eve horizon with trading volumes, blank when missing.
+func volumeColumns(outputVolumes map[string]volumeEstimate, id string, eveHorizon *int, rates []float64) []string {
+	columns := make([]string, 4+len(rates))
+
+	estimate, ok := outputVolumes[id]
+	if !ok {
+		return columns
+	}
+
+	if estimate.Horizon != nil {
+		columns[0] = fmt.Sprintf("%d", *estimate.Horizon)
+
+		if eveHorizon != nil {
+			columns[1] = fmt.Sprintf("%d", *eveHorizon-*estimate.Horizon)
+		}
+	}
+
+	columns[2] = fmt.Sprintf("%f", estimate.ADV)
+	if estimate.ADV > 0 {
+		columns[3] = fmt.Sprintf("%f", estimate.SizeToADV)
+	}
+
+	for i, days := range estimate.LiquidationDays {
+		columns[4+i] = fmt.Sprintf("%f", days)
+	}
+
+	return columns
+}
+
+// errorColumn returns why the asset could not be described, blank if it was.
+func errorColumn(result liquidityOutput) string {
+	if result.err == nil {
+		return ""
+	}
+
+	return result.err.Error()
+}
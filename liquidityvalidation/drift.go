@@ -0,0 +1,404 @@
+package liquidityvalidation
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	log "github.com/sirupsen/logrus"
+
+	"toolkit/checkpoint"
+	"toolkit/manifest"
+)
+
+// Drift mode: the snapshot-bound stages run over a list of snapshots and every
+// result is stored in a SQLite file, to follow the horizons and ES of the
+// assets across snapshots and runs.
+var (
+	driftSnapshots        = ""
+	driftDBPath           = "drift.sqlite"
+	driftOutputPath       = "drift-alerts.csv"
+	driftHorizonThreshold = 5
+	driftESThreshold      = 0.2
+)
+
+const driftSchema = `
+CREATE TABLE IF NOT EXISTS cerberus (
+	run_at      TEXT NOT NULL,
+	environment TEXT NOT NULL,
+	asset       TEXT NOT NULL,
+	horizon_md  INTEGER,
+	horizon_poc INTEGER,
+	market_cap  REAL,
+	PRIMARY KEY (run_at, environment, asset)
+);
+
+CREATE TABLE IF NOT EXISTS snapshots (
+	environment           TEXT NOT NULL,
+	snapshot              TEXT NOT NULL,
+	asset                 TEXT NOT NULL,
+	run_at                TEXT NOT NULL,
+	horizon_no_volumes    INTEGER,
+	horizon_volumes       INTEGER,
+	es_arcanist_liquidity REAL,
+	es_arcanist           REAL,
+	PRIMARY KEY (environment, snapshot, asset)
+);
+`
+
+// driftStore is the SQLite file of the drift results.
+type driftStore struct {
+	db *sql.DB
+}
+
+func openDriftStore(path string) (*driftStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", path, err)
+	}
+
+	if _, err := db.Exec(driftSchema); err != nil {
+		db.Close()
+
+		return nil, fmt.Errorf("could not create the drift tables in %s: %w", path, err)
+	}
+
+	return &driftStore{db: db}, nil
+}
+
+func (s *driftStore) Close() error {
+	return s.db.Close()
+}
+
+// saveCerberus stores the live Cerberus answers of a run. They do not depend
+// on the snapshot, so they drift from run to run.
+func (s *driftStore) saveCerberus(runAt time.Time, env string, outputMD []liquidityOutput) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not start the transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, md := range outputMD {
+		if md.err != nil {
+			continue
+		}
+
+		if _, err := tx.Exec(
+			`INSERT OR REPLACE INTO cerberus (run_at, environment, asset, horizon_md, horizon_poc, market_cap) VALUES (?, ?, ?, ?, ?, ?)`,
+			runAt.UTC().Format(time.RFC3339), env, md.id, md.horizon, md.pocHorizon, md.marketCap,
+		); err != nil {
+			return fmt.Errorf("could not store the Cerberus answer of %s: %w", md.id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit the Cerberus answers: %w", err)
+	}
+
+	return nil
+}
+
+// saveSnapshot stores the Eve horizons and Arcanist ES of a snapshot,
+// replacing those of a previous run on the same snapshot.
+func (s *driftStore) saveSnapshot(runAt time.Time, env, snapshot string, outputMD []liquidityOutput, outputEve map[string]eveOutput, outputArcanist, outputArcanistMD map[string]float64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not start the transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	optional := func(m map[string]float64, id string) *float64 {
+		v, ok := m[id]
+		if !ok {
+			return nil
+		}
+
+		return &v
+	}
+
+	for _, md := range outputMD {
+		var noVolumes, volumes *int
+		if eve, ok := outputEve[md.id]; ok {
+			noVolumes, volumes = &eve.HorizonNoTradingVolumes, eve.HorizonTradingVolumes
+		}
+
+		esLiquidity, es := optional(outputArcanist, md.id), optional(outputArcanistMD, md.id)
+		if noVolumes == nil && esLiquidity == nil && es == nil {
+			continue
+		}
+
+		if _, err := tx.Exec(
+			`INSERT OR REPLACE INTO snapshots (environment, snapshot, asset, run_at, horizon_no_volumes, horizon_volumes, es_arcanist_liquidity, es_arcanist) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			env, snapshot, md.id, runAt.UTC().Format(time.RFC3339), noVolumes, volumes, esLiquidity, es,
+		); err != nil {
+			return fmt.Errorf("could not store %s on %s: %w", md.id, snapshot, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit snapshot %s: %w", snapshot, err)
+	}
+
+	return nil
+}
+
+// driftPoint is a value of a measure of an asset at a snapshot or run.
+type driftPoint struct {
+	Asset string
+	Label string
+	Value *float64
+}
+
+// driftAlert is a jump of a measure of an asset between consecutive
+// snapshots or runs.
+type driftAlert struct {
+	Asset   string
+	Measure string
+	From    string
+	To      string
+	Before  float64
+	After   float64
+	Change  float64
+}
+
+// Measures followed across snapshots and runs, and whether their jumps are
+// relative.
+var driftMeasures = []struct {
+	name     string
+	table    string
+	column   string
+	order    string
+	relative bool
+}{
+	{"horizonMD", "cerberus", "horizon_md", "run_at", false},
+	{"horizonPoC", "cerberus", "horizon_poc", "run_at", false},
+	{"horizonNoVolumes", "snapshots", "horizon_no_volumes", "snapshot", false},
+	{"horizonVolumes", "snapshots", "horizon_volumes", "snapshot", false},
+	{"esArcanistLiquidity", "snapshots", "es_arcanist_liquidity", "snapshot", true},
+	{"esArcanist", "snapshots", "es_arcanist", "snapshot", true},
+}
+
+// alerts returns the jumps of every measure of the environment over the whole
+// history of the store.
+func (s *driftStore) alerts(env string, horizonThreshold int, esThreshold float64) ([]driftAlert, error) {
+	alerts := make([]driftAlert, 0)
+	for _, m := range driftMeasures {
+		// Names come from driftMeasures, not from the user.
+		rows, err := s.db.Query(
+			fmt.Sprintf(`SELECT asset, %s, %s FROM %s WHERE environment = ? ORDER BY asset, %s`, m.order, m.column, m.table, m.order),
+			env,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not query %s: %w", m.name, err)
+		}
+
+		points := make([]driftPoint, 0)
+		for rows.Next() {
+			var p driftPoint
+			var value sql.NullFloat64
+			if err := rows.Scan(&p.Asset, &p.Label, &value); err != nil {
+				rows.Close()
+
+				return nil, fmt.Errorf("could not read %s: %w", m.name, err)
+			}
+			if value.Valid {
+				p.Value = &value.Float64
+			}
+			points = append(points, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", m.name, err)
+		}
+
+		threshold := float64(horizonThreshold)
+		if m.relative {
+			threshold = esThreshold
+		}
+		alerts = append(alerts, detectJumps(m.name, points, threshold, m.relative)...)
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool {
+		if alerts[i].Asset != alerts[j].Asset {
+			return alerts[i].Asset < alerts[j].Asset
+		}
+
+		return alerts[i].To < alerts[j].To
+	})
+
+	return alerts, nil
+}
+
+// detectJumps flags the changes of the points, sorted by asset then label,
+// larger than the threshold between consecutive values of an asset. Relative
+// changes are taken on the absolute values. Missing values are skipped.
+func detectJumps(measure string, points []driftPoint, threshold float64, relative bool) []driftAlert {
+	alerts := make([]driftAlert, 0)
+
+	var previous *driftPoint
+	for i := range points {
+		p := &points[i]
+		if p.Value == nil {
+			continue
+		}
+
+		if previous != nil && previous.Asset == p.Asset {
+			before, after := *previous.Value, *p.Value
+
+			change := after - before
+			if relative {
+				change = math.Inf(1)
+				if before != 0 {
+					change = math.Abs(after)/math.Abs(before) - 1
+				}
+			}
+
+			if math.Abs(change) > threshold {
+				alerts = append(alerts, driftAlert{
+					Asset: p.Asset, Measure: measure, From: previous.Label, To: p.Label,
+					Before: before, After: after, Change: change,
+				})
+			}
+		}
+
+		previous = p
+	}
+
+	return alerts
+}
+
+// runDrift prices the assets on every snapshot of driftSnapshots, stores the
+// results and writes the jumps of the whole history to driftOutputPath.
+func runDrift(run *manifest.Manifest, outputMD []liquidityOutput, checkpointDir string, resume bool) error {
+	snapshots := splitList(driftSnapshots)
+	if len(snapshots) == 0 {
+		return errors.New("no snapshot to track")
+	}
+
+	store, err := openDriftStore(driftDBPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	runAt := time.Now()
+	if err := store.saveCerberus(runAt, environment, outputMD); err != nil {
+		return err
+	}
+
+	assetIDs := make([]string, 0, len(outputMD))
+	assets := make([]liquidityOutput, 0, len(outputMD))
+	for _, md := range outputMD {
+		if md.err == nil {
+			assetIDs = append(assetIDs, md.id)
+			assets = append(assets, md)
+		}
+	}
+
+	for _, snapshot := range snapshots {
+		log.Infof("Price the assets on snapshot %s", snapshot)
+		if environment == "PROD" {
+			snapshotPROD = snapshot
+		} else {
+			snapshotDEV = snapshot
+		}
+
+		outputEve, outputArcanist, outputArcanistMD, err := priceSnapshot(run, snapshot, assetIDs, assets, checkpointDir, resume)
+		if err != nil {
+			return fmt.Errorf("could not price snapshot %s: %w", snapshot, err)
+		}
+
+		if err := store.saveSnapshot(runAt, environment, snapshot, assets, outputEve, outputArcanist, outputArcanistMD); err != nil {
+			return err
+		}
+	}
+
+	alerts, err := store.alerts(environment, driftHorizonThreshold, driftESThreshold)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range alerts {
+		log.Warnf("%s: %s jumped from %g to %g between %s and %s", a.Asset, a.Measure, a.Before, a.After, a.From, a.To)
+	}
+
+	if err := driftToCsv(driftOutputPath, alerts); err != nil {
+		return err
+	}
+
+	return run.Write(driftDBPath, driftOutputPath)
+}
+
+// priceSnapshot runs Adam, Eve and Arcanist on the current snapshot, with
+// journals of their own.
+func priceSnapshot(run *manifest.Manifest, snapshot string, assetIDs []string, assets []liquidityOutput, checkpointDir string, resume bool) (map[string]eveOutput, map[string]float64, map[string]float64, error) {
+	dir := filepath.Join(checkpointDir, strings.NewReplacer(":", "", "/", "").Replace(snapshot))
+
+	adamJournal, err := checkpoint.Open(filepath.Join(dir, "adam.jsonl"), resume)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer adamJournal.Close()
+
+	outputAdam, err := requestAdam(assetIDs, adamJournal)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	run.Count("adam "+snapshot, len(assetIDs), len(outputAdam))
+
+	eveJournal, err := checkpoint.Open(filepath.Join(dir, "eve.jsonl"), resume)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer eveJournal.Close()
+
+	outputEve, err := requestEve(outputAdam, eveJournal)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	run.Count("eve "+snapshot, len(outputAdam), len(outputEve))
+
+	outputArcanist, outputArcanistMD, err := requestArcanist(assets)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	run.Count("arcanist "+snapshot, len(assets), len(outputArcanist))
+
+	return outputEve, outputArcanist, outputArcanistMD, nil
+}
+
+func driftToCsv(path string, alerts []driftAlert) error {
+	csvFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer csvFile.Close()
+
+	csvwriter := csv.NewWriter(csvFile)
+	_ = csvwriter.Write([]string{"id", "measure", "from", "to", "before", "after", "change"})
+	for _, a := range alerts {
+		_ = csvwriter.Write([]string{
+			a.Asset, a.Measure, a.From, a.To,
+			strconv.FormatFloat(a.Before, 'f', -1, 64),
+			strconv.FormatFloat(a.After, 'f', -1, 64),
+			strconv.FormatFloat(a.Change, 'f', -1, 64),
+		})
+	}
+
+	csvwriter.Flush()
+	if err := csvwriter.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package liquidityvalidation
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_detectJumps(t *testing.T) {
+	t.Parallel()
+
+	value := func(v float64) *float64 { return &v }
+	points := []driftPoint{
+		{Asset: "A", Label: "s1", Value: value(10)},
+		{Asset: "A", Label: "s2", Value: value(12)},
+		{Asset: "A", Label: "s3"},
+		{Asset: "A", Label: "s4", Value: value(20)},
+		{Asset: "B", Label: "s1", Value: value(0)},
+	}
+
+	assert.Equal(t, []driftAlert{
+		{Asset: "A", Measure: "horizonMD", From: "s2", To: "s4", Before: 12, After: 20, Change: 8},
+	}, detectJumps("horizonMD", points, 5, false))
+
+	alerts := detectJumps("esArcanist", points, 0.5, true)
+	require.Len(t, alerts, 1)
+	assert.InDelta(t, 2.0/3, alerts[0].Change, 1e-9)
+}
+
+func Test_driftStore(t *testing.T) {
+	t.Parallel()
+
+	store, err := openDriftStore(filepath.Join(t.TempDir(), "drift.sqlite"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	marketCap := 5e9
+	outputMD := []liquidityOutput{{id: "A", horizon: 3, marketCap: &marketCap}, {id: "B", horizon: 1}}
+	runAt := time.Date(2024, 10, 14, 8, 0, 0, 0, time.UTC)
+
+	require.NoError(t, store.saveCerberus(runAt, "PROD", outputMD))
+	outputMD[0].horizon = 10
+	require.NoError(t, store.saveCerberus(runAt.Add(24*time.Hour), "PROD", outputMD))
+
+	volumes := 4
+	require.NoError(t, store.saveSnapshot(runAt, "PROD", "2024-10-12T19:30:05Z", outputMD,
+		map[string]eveOutput{"A": {ID: "A", HorizonNoTradingVolumes: 3, HorizonTradingVolumes: &volumes}},
+		map[string]float64{"A": -0.1, "B": -0.2}, map[string]float64{"A": -0.1}))
+	require.NoError(t, store.saveSnapshot(runAt, "PROD", "2024-10-13T19:30:05Z", outputMD,
+		map[string]eveOutput{"A": {ID: "A", HorizonNoTradingVolumes: 3, HorizonTradingVolumes: &volumes}},
+		map[string]float64{"A": -0.2, "B": -0.21}, map[string]float64{"A": -0.1}))
+
+	alerts, err := store.alerts("PROD", 5, 0.5)
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	assert.Equal(t, "esArcanistLiquidity", alerts[0].Measure)
+	assert.Equal(t, "2024-10-13T19:30:05Z", alerts[0].To)
+	assert.Equal(t, "horizonMD", alerts[1].Measure)
+	assert.Equal(t, "2024-10-15T08:00:00Z", alerts[1].To)
+
+	alerts, err = store.alerts("DEV", 5, 0.5)
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+}
@@ -1,18 +1,19 @@
-package main
+package liquidityvalidation
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 
 	log "github.com/sirupsen/logrus"
+
+	"toolkit/checkpoint"
 )
 
-const (
+var (
 	eveURL     = "http://eve-live.service.consul/debug/value"
 	eveURLPROD = "https://api.edgelab.ch/eve/debug/value"
 )
@@ -23,12 +24,26 @@ type eveOutput struct {
 	HorizonTradingVolumes   *int
 }
 
-func requestEve(requests []Request) (map[string]eveOutput, error) {
+// requestEve prices every dump with and without trading volumes. Horizons
+// already in the journal are reused instead of being priced again.
+func requestEve(requests []Request, journal *checkpoint.Journal) (map[string]eveOutput, error) {
 	ctx := context.Background()
 	output := make(map[string]eveOutput, len(requests))
 	counter := 0
 	maxNumber := len(requests)
 	for _, request := range requests {
+		var previous eveOutput
+		done, err := journal.Result(request.ID, &previous)
+		if err != nil {
+			return nil, err
+		}
+
+		if done {
+			output[request.ID] = previous
+
+			continue
+		}
+
 		baseResponse, err := makeRequestEve(ctx, request.Payload)
 		/* 	file, _ := json.MarshalIndent(request.Payload, "", " ")
 		_ = os.WriteFile(fmt.Sprintf("%s.json", request.ID), file, 0644)
@@ -66,6 +81,10 @@ func requestEve(requests []Request) (map[string]eveOutput, error) {
 				HorizonNoTradingVolumes: result.Liquidity.Horizon.Value,
 			}
 
+			if err := journal.Record(request.ID, output[request.ID]); err != nil {
+				return nil, err
+			}
+
 			counter++
 			if counter%100 == 0 {
 				log.Printf("Processed %d/%d bonds (%f%%)", counter, maxNumber, float64(counter)/float64(maxNumber)*100)
@@ -107,6 +126,10 @@ func requestEve(requests []Request) (map[string]eveOutput, error) {
 			HorizonNoTradingVolumes: result2.Liquidity.Horizon.Value,
 		}
 
+		if err := journal.Record(request.ID, output[request.ID]); err != nil {
+			return nil, err
+		}
+
 		counter++
 		if counter%100 == 0 {
 			log.Printf("Processed %d/%d bonds (%f%%)", counter, maxNumber, float64(counter)/float64(maxNumber)*100)
@@ -134,21 +157,14 @@ func makeRequestEve(ctx context.Context, body json.RawMessage) (json.RawMessage,
 		req.Header.Set("Authorization", "Bearer "+tokenPROD)
 	}
 
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("could not send the request: %w", err)
-	}
-
-	defer res.Body.Close()
-
-	raw, err := io.ReadAll(res.Body)
+	// The payload carries the whole market data, so it is enough to key the cache.
+	status, raw, err := sendRequest(req, "eve", "", body, 0)
 	if err != nil {
-		return nil, fmt.Errorf("could not read response body: %w", err)
+		return nil, err
 	}
 
-	if s := res.StatusCode; s != http.StatusOK {
-		return nil, fmt.Errorf("received non-200 status code: %d", s)
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("received non-200 status code: %d", status)
 	}
 
 	return json.RawMessage(raw), nil
@@ -3,6 +3,7 @@ module liquidityvalidation
 go 1.21.0
 
 require (
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/sirupsen/logrus v1.9.3
 	github.com/stretchr/testify v1.9.0
 )
@@ -13,3 +14,7 @@ require (
 	golang.org/x/sys v0.18.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+require toolkit v0.0.0
+
+replace toolkit => ../toolkit
@@ -0,0 +1,392 @@
+package liquidityvalidation
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"toolkit/manifest"
+)
+
+// Grid mode: Arcanist and Recco over every combination of the parameters
+// instead of the single point set by the flags.
+var (
+	gridMode        = false
+	gridMetrics     = "VaR,ES"
+	gridConfidences = "0.9,0.95,0.975,0.99"
+	gridWindows     = "6500-6999"
+	gridHorizons    = "10,30,60"
+	gridOutputPath  = "grid.csv"
+)
+
+// scenarioWindow is an inclusive range of Arcanist scenario IDs.
+type scenarioWindow struct {
+	First, Last int
+}
+
+func (w scenarioWindow) String() string {
+	return fmt.Sprintf("%d-%d", w.First, w.Last)
+}
+
+// gridPoint is a combination of the grid parameters.
+type gridPoint struct {
+	Metric          string
+	ConfidenceLevel float64
+	Window          scenarioWindow
+	Horizon         int
+}
+
+func (p gridPoint) parameters() riskParameters {
+	return riskParameters{
+		Metric:          p.Metric,
+		ConfidenceLevel: p.ConfidenceLevel,
+		FirstScenarioID: p.Window.First,
+		NbScenarios:     p.Window.Last - p.Window.First + 1,
+		ScenarioHorizon: float64(p.Horizon),
+	}
+}
+
+// gridRow is a line of the long-format grid table. Uplift is the relative
+// uplift of the liquidity over the market measure of the same point, on the
+// liquidity rows.
+type gridRow struct {
+	ID              string
+	Service         string
+	RiskType        string
+	Metric          string
+	ConfidenceLevel float64
+	Window          string
+	Horizon         int
+	Value           float64
+	Uplift          *float64
+}
+
+// gridPoints returns every combination of the parameters.
+func gridPoints(metrics []string, confidences []float64, windows []scenarioWindow, horizons []int) []gridPoint {
+	points := make([]gridPoint, 0, len(metrics)*len(confidences)*len(windows)*len(horizons))
+	for _, m := range metrics {
+		for _, c := range confidences {
+			for _, w := range windows {
+				for _, h := range horizons {
+					points = append(points, gridPoint{Metric: m, ConfidenceLevel: c, Window: w, Horizon: h})
+				}
+			}
+		}
+	}
+
+	return points
+}
+
+// runGrid computes the grid for the described assets and writes it to
+// gridOutputPath.
+func runGrid(run *manifest.Manifest, outputMD []liquidityOutput) error {
+	metrics := splitList(gridMetrics)
+	if len(metrics) == 0 {
+		return errors.New("no grid metric")
+	}
+
+	confidences, err := parseFloats(gridConfidences)
+	if err != nil {
+		return err
+	}
+	for _, c := range confidences {
+		if c <= 0 || c >= 1 {
+			return fmt.Errorf("confidence level %v is not in (0, 1)", c)
+		}
+	}
+
+	windows, err := parseWindows(gridWindows)
+	if err != nil {
+		return err
+	}
+
+	horizons, err := parseInts(gridHorizons)
+	if err != nil {
+		return err
+	}
+
+	assets := make([]liquidityOutput, 0, len(outputMD))
+	for _, md := range outputMD {
+		if md.err == nil {
+			assets = append(assets, md)
+		}
+	}
+
+	ctx := context.Background()
+	rows := make([]gridRow, 0)
+
+	points := gridPoints(metrics, confidences, windows, horizons)
+	computed := 0
+	for i, point := range points {
+		log.Infof("Grid point %d/%d: %s %v, scenarios %s, %d days", i+1, len(points), point.Metric, point.ConfidenceLevel, point.Window, point.Horizon)
+
+		market, liquidity, err := requestArcanistGrid(ctx, point, assets)
+		if err != nil {
+			log.Warnf("Arcanist failed on %s %v, scenarios %s, %d days: %v", point.Metric, point.ConfidenceLevel, point.Window, point.Horizon, err)
+
+			continue
+		}
+
+		rows = append(rows, arcanistGridRows(point, assets, market, liquidity)...)
+		computed++
+	}
+	run.Count("grid-arcanist", len(points), computed)
+
+	// Recco only computes the ES, on its own scenarios: it has a point per
+	// confidence level and horizon.
+	if !containsFold(metrics, "ES") {
+		log.Info("No ES in the grid metrics, Recco is left out")
+
+		return writeGrid(gridOutputPath, rows)
+	}
+
+	computed = 0
+	for _, c := range confidences {
+		for _, h := range horizons {
+			values, err := requestReccoGrid(ctx, c, h, assets)
+			if err != nil {
+				log.Warnf("Recco failed on %v, %d days: %v", c, h, err)
+
+				continue
+			}
+
+			for _, md := range assets {
+				if v, ok := values[md.id]; ok {
+					rows = append(rows, gridRow{
+						ID: md.id, Service: "recco", RiskType: "MARKET_LIQUIDITY",
+						Metric: "ES", ConfidenceLevel: c, Horizon: h, Value: v,
+					})
+				}
+			}
+			computed++
+		}
+	}
+	run.Count("grid-recco", len(confidences)*len(horizons), computed)
+
+	return writeGrid(gridOutputPath, rows)
+}
+
+// requestArcanistGrid returns the market and liquidity measures of the assets
+// at the point, batch by batch.
+func requestArcanistGrid(ctx context.Context, point gridPoint, assets []liquidityOutput) (map[string]float64, map[string]float64, error) {
+	market := make(map[string]float64, len(assets))
+	liquidity := make(map[string]float64, len(assets))
+
+	for start := 0; start < len(assets); start += batchSize {
+		batch := assets[start:min(start+batchSize, len(assets))]
+
+		positions := make(map[int]ArcanistPosition, len(batch))
+		for i, md := range batch {
+			positions[i] = ArcanistPosition{
+				Asset:     md.id,
+				Quantity:  1.0,
+				Currency:  positionCurrency,
+				Liquidity: float64(md.horizon),
+			}
+		}
+
+		for _, withQELiquidity := range []bool{false, true} {
+			results, err := requestArcanistMeasure(ctx, point.parameters(), positions, withQELiquidity)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			target := market
+			if withQELiquidity {
+				target = liquidity
+			}
+			for i, v := range results {
+				target[positions[i].Asset] = v
+			}
+		}
+	}
+
+	return market, liquidity, nil
+}
+
+// requestReccoGrid returns the ES of the assets at the confidence level and
+// horizon, batch by batch.
+func requestReccoGrid(ctx context.Context, confidence float64, horizon int, assets []liquidityOutput) (map[string]float64, error) {
+	values := make(map[string]float64, len(assets))
+
+	for start := 0; start < len(assets); start += batchSize {
+		batch := assets[start:min(start+batchSize, len(assets))]
+
+		positions := make([]ReccoPosition, 0, len(batch))
+		for _, md := range batch {
+			positions = append(positions, ReccoPosition{Asset: md.id, Amount: 1, IdentifierType: "id", Key: md.id})
+		}
+
+		results, err := requestReccoMeasure(ctx, confidence, horizon, amountScheme, positions)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range results {
+			values[k] = v
+		}
+	}
+
+	return values, nil
+}
+
+// arcanistGridRows returns the market and liquidity rows of the assets at the
+// point, in asset order.
+func arcanistGridRows(point gridPoint, assets []liquidityOutput, market, liquidity map[string]float64) []gridRow {
+	rows := make([]gridRow, 0, 2*len(assets))
+	for _, md := range assets {
+		row := gridRow{
+			ID:              md.id,
+			Service:         "arcanist",
+			Metric:          point.Metric,
+			ConfidenceLevel: point.ConfidenceLevel,
+			Window:          point.Window.String(),
+			Horizon:         point.Horizon,
+		}
+
+		m, hasMarket := market[md.id]
+		if hasMarket {
+			row.RiskType, row.Value = "MARKET", m
+			rows = append(rows, row)
+		}
+
+		if l, ok := liquidity[md.id]; ok {
+			row.RiskType, row.Value = "MARKET_LIQUIDITY", l
+			if hasMarket && m != 0 {
+				uplift := math.Abs(l)/math.Abs(m) - 1
+				row.Uplift = &uplift
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+func writeGrid(path string, rows []gridRow) error {
+	csvFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer csvFile.Close()
+
+	csvwriter := csv.NewWriter(csvFile)
+	_ = csvwriter.Write([]string{"id", "service", "riskType", "metric", "confidenceLevel", "scenarios", "horizon", "value", "liquidityUplift"})
+	for _, r := range rows {
+		_ = csvwriter.Write([]string{
+			r.ID, r.Service, r.RiskType, r.Metric,
+			strconv.FormatFloat(r.ConfidenceLevel, 'f', -1, 64),
+			r.Window,
+			strconv.Itoa(r.Horizon),
+			strconv.FormatFloat(r.Value, 'f', -1, 64),
+			formatFloat(r.Uplift),
+		})
+	}
+
+	csvwriter.Flush()
+	if err := csvwriter.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// parseWindows parses comma-separated inclusive ranges of scenario IDs such as
+// "6500-6999,2501-3000".
+func parseWindows(value string) ([]scenarioWindow, error) {
+	windows := make([]scenarioWindow, 0)
+	for _, part := range splitList(value) {
+		bounds := strings.SplitN(part, "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("scenario window %q is not of the form first-last", part)
+		}
+
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid scenario window %q: %w", part, err)
+		}
+
+		last, err := strconv.Atoi(bounds[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid scenario window %q: %w", part, err)
+		}
+
+		if first > last {
+			return nil, fmt.Errorf("scenario window %q is empty", part)
+		}
+
+		windows = append(windows, scenarioWindow{First: first, Last: last})
+	}
+
+	if len(windows) == 0 {
+		return nil, errors.New("no scenario window")
+	}
+
+	return windows, nil
+}
+
+func parseFloats(value string) ([]float64, error) {
+	values := make([]float64, 0)
+	for _, part := range splitList(value) {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %w", part, err)
+		}
+		values = append(values, v)
+	}
+
+	if len(values) == 0 {
+		return nil, errors.New("empty list of numbers")
+	}
+
+	return values, nil
+}
+
+func parseInts(value string) ([]int, error) {
+	values := make([]int, 0)
+	for _, part := range splitList(value) {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q: %w", part, err)
+		}
+		if v <= 0 {
+			return nil, fmt.Errorf("%d is not positive", v)
+		}
+		values = append(values, v)
+	}
+
+	if len(values) == 0 {
+		return nil, errors.New("empty list of integers")
+	}
+
+	return values, nil
+}
+
+// splitList splits a comma-separated list, dropping blank items.
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, part)
+		}
+	}
+
+	return items
+}
+
+func containsFold(items []string, item string) bool {
+	for _, i := range items {
+		if strings.EqualFold(i, item) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,52 @@
+package liquidityvalidation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseWindows(t *testing.T) {
+	t.Parallel()
+
+	windows, err := parseWindows("6500-6999, 2501-3000")
+	require.NoError(t, err)
+	assert.Equal(t, []scenarioWindow{{6500, 6999}, {2501, 3000}}, windows)
+	assert.Equal(t, 500, gridPoint{Window: windows[0]}.parameters().NbScenarios)
+
+	for _, value := range []string{"", "6500", "6999-6500", "a-b"} {
+		_, err := parseWindows(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func Test_gridPoints(t *testing.T) {
+	t.Parallel()
+
+	points := gridPoints([]string{"VaR", "ES"}, []float64{0.9, 0.99}, []scenarioWindow{{6500, 6999}}, []int{10, 30, 60})
+	require.Len(t, points, 12)
+	assert.Equal(t, gridPoint{Metric: "VaR", ConfidenceLevel: 0.9, Window: scenarioWindow{6500, 6999}, Horizon: 10}, points[0])
+	assert.Equal(t, gridPoint{Metric: "ES", ConfidenceLevel: 0.99, Window: scenarioWindow{6500, 6999}, Horizon: 60}, points[11])
+}
+
+func Test_arcanistGridRows(t *testing.T) {
+	t.Parallel()
+
+	point := gridPoint{Metric: "ES", ConfidenceLevel: 0.975, Window: scenarioWindow{6500, 6999}, Horizon: 30}
+	assets := []liquidityOutput{{id: "A"}, {id: "B"}, {id: "C"}}
+
+	rows := arcanistGridRows(point, assets, map[string]float64{"A": -0.1, "B": -0.2}, map[string]float64{"A": -0.12, "C": -0.3})
+	require.Len(t, rows, 4)
+
+	assert.Equal(t, "MARKET", rows[0].RiskType)
+	assert.Nil(t, rows[0].Uplift)
+	assert.Equal(t, "MARKET_LIQUIDITY", rows[1].RiskType)
+	require.NotNil(t, rows[1].Uplift)
+	assert.InDelta(t, 0.2, *rows[1].Uplift, 1e-9)
+	assert.Equal(t, "6500-6999", rows[1].Window)
+
+	assert.Equal(t, "B", rows[2].ID)
+	assert.Equal(t, "C", rows[3].ID)
+	assert.Nil(t, rows[3].Uplift)
+}
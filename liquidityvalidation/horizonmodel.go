@@ -0,0 +1,489 @@
+package liquidityvalidation
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Horizon model types.
+const (
+	piecewiseModel = "piecewise"
+	logisticModel  = "logistic"
+)
+
+// horizonModel maps the market cap of an issuer to a liquidity horizon in
+// days, through log10 of the market cap:
+//
+//   - piecewise interpolates linearly between Points, flat outside them;
+//   - logistic is MaxHorizon / (1 + exp(Slope * (log10(cap) - Midpoint))).
+//
+// Horizons are truncated to whole days within [0, MaxHorizon]. Issuers
+// without market cap get MissingHorizon, those with a non-positive one
+// MaxHorizon.
+type horizonModel struct {
+	Type           string         `json:"type"`
+	MaxHorizon     float64        `json:"maxHorizon"`
+	MissingHorizon float64        `json:"missingHorizon"`
+	Points         []horizonPoint `json:"points,omitempty"`
+	Midpoint       float64        `json:"midpoint,omitempty"`
+	Slope          float64        `json:"slope,omitempty"`
+}
+
+type horizonPoint struct {
+	LogMarketCap float64 `json:"logMarketCap"`
+	Horizon      float64 `json:"horizon"`
+}
+
+// defaultHorizonModel is the proof of concept: 30 days under 100 M$, none over
+// 10 B$ and a log10 ramp in between.
+var defaultHorizonModel = horizonModel{
+	Type:           piecewiseModel,
+	MaxHorizon:     30,
+	MissingHorizon: 15,
+	Points:         []horizonPoint{{LogMarketCap: 8, Horizon: 30}, {LogMarketCap: 10, Horizon: 0}},
+}
+
+// Starting point of a logistic model: a midpoint of 1 B$ and a slope of 1 per
+// decade.
+const (
+	logisticMidpoint = 9.0
+	logisticSlope    = 1.0
+)
+
+// Horizon model of the run and calibration settings.
+var (
+	currentHorizonModel = defaultHorizonModel
+
+	horizonModelPath      = ""
+	calibrate             = false
+	calibrationOutputPath = "horizon-model.json"
+	calibrationReportPath = "horizon-fit.csv"
+)
+
+// marketCapBuckets are the usual market-cap buckets, by lower bound.
+var marketCapBuckets = []struct {
+	name  string
+	lower float64
+}{
+	{"mega", 2e11},
+	{"large", 1e10},
+	{"mid", 2e9},
+	{"small", 2.5e8},
+	{"micro", 5e7},
+	{"nano", math.Inf(-1)},
+}
+
+const missingBucket = "missing"
+
+// marketCapBucket returns the bucket of a market cap.
+func marketCapBucket(marketCap *float64) string {
+	if marketCap == nil {
+		return missingBucket
+	}
+
+	for _, b := range marketCapBuckets {
+		if *marketCap > b.lower {
+			return b.name
+		}
+	}
+
+	return marketCapBuckets[len(marketCapBuckets)-1].name
+}
+
+func (m horizonModel) horizon(marketCap *float64) int {
+	if marketCap == nil {
+		return int(m.MissingHorizon)
+	}
+
+	if *marketCap <= 0 {
+		return int(m.MaxHorizon)
+	}
+
+	return int(min(max(m.curve(math.Log10(*marketCap)), 0.0), m.MaxHorizon))
+}
+
+// curve is the untruncated horizon at log10 of the market cap.
+func (m horizonModel) curve(x float64) float64 {
+	if m.Type == logisticModel {
+		return m.MaxHorizon / (1 + math.Exp(m.Slope*(x-m.Midpoint)))
+	}
+
+	points := m.Points
+	if x <= points[0].LogMarketCap {
+		return points[0].Horizon
+	}
+
+	for i := 1; i < len(points); i++ {
+		if x <= points[i].LogMarketCap {
+			t := (x - points[i-1].LogMarketCap) / (points[i].LogMarketCap - points[i-1].LogMarketCap)
+
+			return points[i-1].Horizon*(1-t) + points[i].Horizon*t
+		}
+	}
+
+	return points[len(points)-1].Horizon
+}
+
+func (m horizonModel) validate() error {
+	if m.MaxHorizon <= 0 {
+		return errors.New("maxHorizon must be positive")
+	}
+
+	switch m.Type {
+	case piecewiseModel:
+		if len(m.Points) < 2 {
+			return errors.New("a piecewise model needs at least 2 points")
+		}
+
+		for i := 1; i < len(m.Points); i++ {
+			if m.Points[i].LogMarketCap <= m.Points[i-1].LogMarketCap {
+				return errors.New("points must be in increasing logMarketCap")
+			}
+		}
+	case logisticModel:
+		if m.Slope == 0 {
+			return errors.New("a logistic model needs a non-zero slope")
+		}
+
+		if m.Midpoint <= 0 {
+			return errors.New("a logistic model needs a positive midpoint")
+		}
+	default:
+		return fmt.Errorf("unknown horizon model type %q", m.Type)
+	}
+
+	return nil
+}
+
+// loadHorizonModel reads a horizon model from a JSON file, fields left out
+// keeping the value of the default model, or of the logistic starting point
+// for the midpoint and slope.
+func loadHorizonModel(path string) (horizonModel, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return horizonModel{}, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	model := defaultHorizonModel
+	model.Points = nil
+	model.Midpoint, model.Slope = logisticMidpoint, logisticSlope
+	if err := json.Unmarshal(raw, &model); err != nil {
+		return horizonModel{}, fmt.Errorf("could not unmarshal %s: %w", path, err)
+	}
+
+	if model.Type == piecewiseModel {
+		model.Midpoint, model.Slope = 0, 0
+		if model.Points == nil {
+			model.Points = defaultHorizonModel.Points
+		}
+	}
+
+	if err := model.validate(); err != nil {
+		return horizonModel{}, fmt.Errorf("invalid horizon model %s: %w", path, err)
+	}
+
+	return model, nil
+}
+
+func marketCapToHorizon(marketCap *float64) int {
+	return currentHorizonModel.horizon(marketCap)
+}
+
+// horizonSample is the production horizon of an asset and the market cap of
+// its issuer.
+type horizonSample struct {
+	marketCap float64
+	horizon   float64
+}
+
+// horizonSamples returns the described assets with a market cap.
+func horizonSamples(outputMD []liquidityOutput) []horizonSample {
+	samples := make([]horizonSample, 0, len(outputMD))
+	for _, md := range outputMD {
+		if md.err != nil || md.marketCap == nil || *md.marketCap <= 0 {
+			continue
+		}
+
+		samples = append(samples, horizonSample{marketCap: *md.marketCap, horizon: float64(md.horizon)})
+	}
+
+	return samples
+}
+
+// calibrateHorizonModel fits a model of the type of base to the samples by
+// least squares, keeping its maximum and missing horizons. A piecewise model
+// gets a point at every bucket bound, a logistic one is fitted by a grid
+// search refined around the best point.
+func calibrateHorizonModel(base horizonModel, samples []horizonSample) (horizonModel, error) {
+	if len(samples) == 0 {
+		return horizonModel{}, errors.New("no asset with a market cap to calibrate on")
+	}
+
+	model := base
+	switch base.Type {
+	case piecewiseModel:
+		knots := make([]float64, 0, len(marketCapBuckets)-1)
+		for i := len(marketCapBuckets) - 2; i >= 0; i-- {
+			knots = append(knots, math.Log10(marketCapBuckets[i].lower))
+		}
+
+		model.Points = fitPiecewise(samples, knots, base.MaxHorizon)
+	case logisticModel:
+		model.Midpoint, model.Slope = fitLogistic(samples, base.MaxHorizon)
+	default:
+		return horizonModel{}, fmt.Errorf("unknown horizon model type %q", base.Type)
+	}
+
+	return model, nil
+}
+
+// fitPiecewise returns the horizons at the knots minimising the squared error
+// of the interpolated curve, clamped to [0, maxHorizon]. Knots without nearby
+// samples are held by a small ridge to the mean horizon.
+func fitPiecewise(samples []horizonSample, knots []float64, maxHorizon float64) []horizonPoint {
+	k := len(knots)
+	ata := make([][]float64, k)
+	for i := range ata {
+		ata[i] = make([]float64, k+1)
+	}
+
+	mean := 0.0
+	for _, s := range samples {
+		mean += s.horizon
+	}
+	mean /= float64(len(samples))
+
+	basis := make([]float64, k)
+	for _, s := range samples {
+		hatBasis(knots, math.Log10(s.marketCap), basis)
+		for i := 0; i < k; i++ {
+			for j := 0; j < k; j++ {
+				ata[i][j] += basis[i] * basis[j]
+			}
+			ata[i][k] += basis[i] * s.horizon
+		}
+	}
+
+	const ridge = 1e-6
+	for i := 0; i < k; i++ {
+		ata[i][i] += ridge
+		ata[i][k] += ridge * mean
+	}
+
+	horizons := solveLinear(ata)
+
+	points := make([]horizonPoint, k)
+	for i, x := range knots {
+		points[i] = horizonPoint{LogMarketCap: x, Horizon: min(max(horizons[i], 0), maxHorizon)}
+	}
+
+	return points
+}
+
+// hatBasis sets the weights of the knots in the linear interpolation at x.
+func hatBasis(knots []float64, x float64, basis []float64) {
+	for i := range basis {
+		basis[i] = 0
+	}
+
+	switch {
+	case x <= knots[0]:
+		basis[0] = 1
+	case x >= knots[len(knots)-1]:
+		basis[len(knots)-1] = 1
+	default:
+		i := sort.SearchFloat64s(knots, x)
+		t := (x - knots[i-1]) / (knots[i] - knots[i-1])
+		basis[i-1] = 1 - t
+		basis[i] = t
+	}
+}
+
+// solveLinear solves the augmented system by Gaussian elimination with
+// partial pivoting.
+func solveLinear(a [][]float64) []float64 {
+	n := len(a)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+
+		for row := col + 1; row < n; row++ {
+			f := a[row][col] / a[col][col]
+			for j := col; j <= n; j++ {
+				a[row][j] -= f * a[col][j]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		sum := a[i][n]
+		for j := i + 1; j < n; j++ {
+			sum -= a[i][j] * x[j]
+		}
+		x[i] = sum / a[i][i]
+	}
+
+	return x
+}
+
+// fitLogistic returns the midpoint and slope of the logistic curve minimising
+// the squared error.
+func fitLogistic(samples []horizonSample, maxHorizon float64) (float64, float64) {
+	sse := func(midpoint, slope float64) float64 {
+		model := horizonModel{Type: logisticModel, MaxHorizon: maxHorizon, Midpoint: midpoint, Slope: slope}
+
+		sum := 0.0
+		for _, s := range samples {
+			d := model.curve(math.Log10(s.marketCap)) - s.horizon
+			sum += d * d
+		}
+
+		return sum
+	}
+
+	midpoint, slope := logisticMidpoint, logisticSlope
+	best := sse(midpoint, slope)
+
+	// Midpoints from 10 M$ to 10 T$, slopes from 0.1 to 20 per decade, then
+	// twice finer grids around the best point.
+	midStep, slopeStep := 0.1, 0.1
+	fromMid, toMid := 7.0, 13.0
+	fromSlope, toSlope := math.Log(0.1), math.Log(20)
+	for round := 0; round < 8; round++ {
+		for m := fromMid; m <= toMid+1e-12; m += midStep {
+			for ls := fromSlope; ls <= toSlope+1e-12; ls += slopeStep {
+				if e := sse(m, math.Exp(ls)); e < best {
+					best, midpoint, slope = e, m, math.Exp(ls)
+				}
+			}
+		}
+
+		fromMid, toMid = midpoint-2*midStep, midpoint+2*midStep
+		fromSlope, toSlope = math.Log(slope)-2*slopeStep, math.Log(slope)+2*slopeStep
+		midStep, slopeStep = midStep/2, slopeStep/2
+	}
+
+	return midpoint, slope
+}
+
+// bucketFit is the fit quality of a model over the assets of a market-cap
+// bucket, the errors being in days of predicted minus production horizon.
+type bucketFit struct {
+	Model   string
+	Bucket  string
+	Samples int
+	MAE     float64
+	RMSE    float64
+	Bias    float64
+
+	// Exact is the share of assets whose horizon is matched to the day.
+	Exact float64
+}
+
+// horizonFit returns the fit quality of the model per bucket, and over all
+// assets last.
+func horizonFit(name string, model horizonModel, samples []horizonSample) []bucketFit {
+	order := make([]string, 0, len(marketCapBuckets)+1)
+	for _, b := range marketCapBuckets {
+		order = append(order, b.name)
+	}
+	order = append(order, "all")
+
+	fits := make(map[string]*bucketFit, len(order))
+	for _, b := range order {
+		fits[b] = &bucketFit{Model: name, Bucket: b}
+	}
+
+	for _, s := range samples {
+		marketCap := s.marketCap
+		d := float64(model.horizon(&marketCap)) - s.horizon
+
+		for _, b := range []string{marketCapBucket(&marketCap), "all"} {
+			f := fits[b]
+			f.Samples++
+			f.MAE += math.Abs(d)
+			f.RMSE += d * d
+			f.Bias += d
+			if d == 0 {
+				f.Exact++
+			}
+		}
+	}
+
+	output := make([]bucketFit, 0, len(order))
+	for _, b := range order {
+		f := fits[b]
+		if f.Samples > 0 {
+			n := float64(f.Samples)
+			f.MAE /= n
+			f.RMSE = math.Sqrt(f.RMSE / n)
+			f.Bias /= n
+			f.Exact /= n
+		}
+
+		output = append(output, *f)
+	}
+
+	return output
+}
+
+// runCalibration fits the horizon model to the production horizons and
+// writes it along with the fit of the current and calibrated models.
+func runCalibration(outputMD []liquidityOutput) error {
+	samples := horizonSamples(outputMD)
+
+	calibrated, err := calibrateHorizonModel(currentHorizonModel, samples)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.MarshalIndent(calibrated, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal the horizon model: %w", err)
+	}
+
+	if err := os.WriteFile(calibrationOutputPath, raw, 0o644); err != nil {
+		return fmt.Errorf("could not write %s: %w", calibrationOutputPath, err)
+	}
+
+	fits := append(horizonFit("current", currentHorizonModel, samples), horizonFit("calibrated", calibrated, samples)...)
+
+	csvFile, err := os.Create(calibrationReportPath)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", calibrationReportPath, err)
+	}
+	defer csvFile.Close()
+
+	csvwriter := csv.NewWriter(csvFile)
+	_ = csvwriter.Write([]string{"model", "bucket", "samples", "mae", "rmse", "bias", "exact"})
+	for _, f := range fits {
+		_ = csvwriter.Write([]string{
+			f.Model, f.Bucket, strconv.Itoa(f.Samples),
+			formatFloat(&f.MAE), formatFloat(&f.RMSE), formatFloat(&f.Bias), formatFloat(&f.Exact),
+		})
+
+		log.Infof("%-10s %-6s %5d assets  MAE %6.2f  RMSE %6.2f  bias %+6.2f  exact %5.1f%%",
+			f.Model, f.Bucket, f.Samples, f.MAE, f.RMSE, f.Bias, 100*f.Exact)
+	}
+
+	csvwriter.Flush()
+	if err := csvwriter.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", calibrationReportPath, err)
+	}
+
+	return nil
+}
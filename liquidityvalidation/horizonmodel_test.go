@@ -0,0 +1,108 @@
+package liquidityvalidation
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_defaultHorizonModel(t *testing.T) {
+	t.Parallel()
+
+	// The proof of concept ramp.
+	poc := func(marketCap float64) int {
+		mlog := (math.Log10(marketCap) - 8.0) / (10.0 - 8.0)
+
+		return int(30 * (1.0 - min(max(mlog, 0.0), 1.0)))
+	}
+
+	for marketCap := 1.0; marketCap < 1e16; marketCap *= 1.37 {
+		assert.Equal(t, poc(marketCap), defaultHorizonModel.horizon(&marketCap), "market cap %v", marketCap)
+	}
+
+	zero := 0.0
+	assert.Equal(t, 30, defaultHorizonModel.horizon(&zero))
+	assert.Equal(t, 15, defaultHorizonModel.horizon(nil))
+}
+
+func Test_marketCapBucket(t *testing.T) {
+	t.Parallel()
+
+	for marketCap, bucket := range map[float64]string{3e11: "mega", 2e11: "large", 5e9: "mid", 1e9: "small", 1e8: "micro", 1e7: "nano"} {
+		assert.Equal(t, bucket, marketCapBucket(&marketCap))
+	}
+	assert.Equal(t, missingBucket, marketCapBucket(nil))
+}
+
+func Test_calibrateHorizonModel(t *testing.T) {
+	t.Parallel()
+
+	target := horizonModel{Type: logisticModel, MaxHorizon: 60, Midpoint: 9.2, Slope: 3}
+
+	samples := make([]horizonSample, 0)
+	for x := 6.5; x < 12.5; x += 0.01 {
+		samples = append(samples, horizonSample{marketCap: math.Pow(10, x), horizon: target.curve(x)})
+	}
+
+	logistic, err := calibrateHorizonModel(horizonModel{Type: logisticModel, MaxHorizon: 60}, samples)
+	require.NoError(t, err)
+	assert.InDelta(t, 9.2, logistic.Midpoint, 1e-2)
+	assert.InDelta(t, 3.0, logistic.Slope, 5e-2)
+
+	piecewise, err := calibrateHorizonModel(horizonModel{Type: piecewiseModel, MaxHorizon: 60}, samples)
+	require.NoError(t, err)
+	require.Len(t, piecewise.Points, 5)
+	assert.InDelta(t, math.Log10(5e7), piecewise.Points[0].LogMarketCap, 1e-12)
+
+	// The knots follow the curve, and both fits beat the default ramp.
+	for _, p := range piecewise.Points {
+		assert.InDelta(t, target.curve(p.LogMarketCap), p.Horizon, 3)
+	}
+
+	all := func(model horizonModel) bucketFit {
+		fits := horizonFit("", model, samples)
+
+		return fits[len(fits)-1]
+	}
+	assert.Equal(t, len(samples), all(logistic).Samples)
+	assert.Less(t, all(logistic).MAE, 1.0)
+	assert.Less(t, all(piecewise).MAE, all(defaultHorizonModel).MAE)
+
+	_, err = calibrateHorizonModel(defaultHorizonModel, nil)
+	assert.Error(t, err)
+}
+
+func Test_loadHorizonModel(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.json")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "logistic", "midpoint": 9, "slope": 2}`), 0o644))
+	model, err := loadHorizonModel(path)
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, model.MaxHorizon)
+	assert.Nil(t, model.Points)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "piecewise", "points": [{"logMarketCap": 9, "horizon": 1}, {"logMarketCap": 8, "horizon": 2}]}`), 0o644))
+	_, err = loadHorizonModel(path)
+	assert.ErrorContains(t, err, "increasing")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "logistic"}`), 0o644))
+	model, err = loadHorizonModel(path)
+	require.NoError(t, err)
+	assert.Equal(t, 9.0, model.Midpoint)
+	assert.Equal(t, 1.0, model.Slope)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "logistic", "slope": 0}`), 0o644))
+	_, err = loadHorizonModel(path)
+	assert.ErrorContains(t, err, "non-zero slope")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "logistic", "midpoint": 0}`), 0o644))
+	_, err = loadHorizonModel(path)
+	assert.ErrorContains(t, err, "positive midpoint")
+}
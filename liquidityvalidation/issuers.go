@@ -0,0 +1,349 @@
+package liquidityvalidation
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Issuer-level consistency checks of the Cerberus answers.
+var (
+	issuerOutputPath = ""
+	issuerSpread     = 10
+	staleDays        = 30
+)
+
+// Issuer flags.
+const (
+	issuerSpreadFlag   = "spread"
+	issuerInvertedFlag = "inverted"
+	issuerMissingFlag  = "missingMarketValue"
+	issuerStaleFlag    = "staleMarketValue"
+)
+
+// Asset classes compared by the inversion check.
+const (
+	classEquity = "equity"
+	classBond   = "bond"
+	classOther  = "other"
+)
+
+// assetClass maps a Cerberus asset type to the classes the checks compare.
+func assetClass(assetType string) string {
+	t := strings.ToUpper(assetType)
+	switch {
+	case strings.Contains(t, "EQUITY"), strings.Contains(t, "STOCK"), strings.Contains(t, "SHARE"):
+		return classEquity
+	case strings.Contains(t, "BOND"):
+		return classBond
+	}
+
+	return classOther
+}
+
+// TypeHorizons describes the horizons of the assets of an issuer sharing a
+// type.
+type TypeHorizons struct {
+	Type    string  `json:"type"`
+	Assets  int     `json:"assets"`
+	Min     int     `json:"min"`
+	MinID   string  `json:"minId"`
+	Max     int     `json:"max"`
+	MaxID   string  `json:"maxId"`
+	Median  float64 `json:"median"`
+	Spread  int     `json:"spread"`
+	Flagged bool    `json:"flagged"`
+}
+
+// IssuerReport is the consistency of the assets of an issuer.
+type IssuerReport struct {
+	Issuer    string         `json:"issuer"`
+	Country   string         `json:"country,omitempty"`
+	MarketCap *float64       `json:"marketCap,omitempty"`
+	Assets    int            `json:"assets"`
+	Types     []TypeHorizons `json:"types"`
+
+	// MarketCapSince is the first run of the drift store with the current
+	// market cap, when the store has it.
+	MarketCapSince string `json:"marketCapSince,omitempty"`
+
+	Flags  []string `json:"flags,omitempty"`
+	Detail []string `json:"detail,omitempty"`
+}
+
+// checkIssuers groups the described assets by issuer and flags the
+// inconsistencies: horizons of a type spreading over more than spread days,
+// equities slower to liquidate than bonds of the same issuer, and market caps
+// missing or unchanged since before staleBefore. since returns the first run
+// with the current market cap of an asset, ok false when unknown.
+func checkIssuers(outputMD []liquidityOutput, spread int, since func(asset string, marketCap float64) (time.Time, bool), staleBefore time.Time) []IssuerReport {
+	byIssuer := make(map[string][]liquidityOutput)
+	order := make([]string, 0)
+	for _, md := range outputMD {
+		if md.err != nil || md.issuer == "" {
+			continue
+		}
+
+		if _, ok := byIssuer[md.issuer]; !ok {
+			order = append(order, md.issuer)
+		}
+		byIssuer[md.issuer] = append(byIssuer[md.issuer], md)
+	}
+	sort.Strings(order)
+
+	reports := make([]IssuerReport, 0, len(order))
+	for _, issuer := range order {
+		assets := byIssuer[issuer]
+		report := IssuerReport{
+			Issuer:    issuer,
+			Country:   assets[0].country,
+			MarketCap: assets[0].marketCap,
+			Assets:    len(assets),
+		}
+
+		byType := make(map[string][]liquidityOutput)
+		for _, md := range assets {
+			t := md.assetType
+			if t == "" {
+				t = "unknown"
+			}
+			byType[t] = append(byType[t], md)
+		}
+
+		types := make([]string, 0, len(byType))
+		for t := range byType {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+
+		horizonsByClass := make(map[string][]float64)
+		for _, t := range types {
+			h := typeHorizons(t, byType[t])
+			if h.Assets > 1 && h.Spread > spread {
+				h.Flagged = true
+				report.Detail = append(report.Detail, fmt.Sprintf("%s horizons from %d (%s) to %d (%s)", t, h.Min, h.MinID, h.Max, h.MaxID))
+			}
+			report.Types = append(report.Types, h)
+
+			for _, md := range byType[t] {
+				class := assetClass(t)
+				horizonsByClass[class] = append(horizonsByClass[class], float64(md.horizon))
+			}
+		}
+
+		if containsFlagged(report.Types) {
+			report.Flags = append(report.Flags, issuerSpreadFlag)
+		}
+
+		// Equities of an issuer are expected to liquidate at least as fast as
+		// its bonds.
+		if equities, bonds := horizonsByClass[classEquity], horizonsByClass[classBond]; len(equities) > 0 && len(bonds) > 0 {
+			equity, bond := median(equities), median(bonds)
+			if equity > bond {
+				report.Flags = append(report.Flags, issuerInvertedFlag)
+				report.Detail = append(report.Detail, fmt.Sprintf("median equity horizon %g over median bond horizon %g", equity, bond))
+			}
+		}
+
+		if report.MarketCap == nil {
+			report.Flags = append(report.Flags, issuerMissingFlag)
+		} else if since != nil {
+			oldest := time.Time{}
+			for _, md := range assets {
+				if t, ok := since(md.id, *report.MarketCap); ok && (oldest.IsZero() || t.Before(oldest)) {
+					oldest = t
+				}
+			}
+
+			if !oldest.IsZero() {
+				report.MarketCapSince = oldest.UTC().Format(time.RFC3339)
+				if oldest.Before(staleBefore) {
+					report.Flags = append(report.Flags, issuerStaleFlag)
+					report.Detail = append(report.Detail, fmt.Sprintf("market cap unchanged since %s", report.MarketCapSince))
+				}
+			}
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports
+}
+
+func typeHorizons(assetType string, assets []liquidityOutput) TypeHorizons {
+	h := TypeHorizons{Type: assetType, Assets: len(assets)}
+
+	values := make([]float64, 0, len(assets))
+	for i, md := range assets {
+		if i == 0 || md.horizon < h.Min {
+			h.Min, h.MinID = md.horizon, md.id
+		}
+		if i == 0 || md.horizon > h.Max {
+			h.Max, h.MaxID = md.horizon, md.id
+		}
+		values = append(values, float64(md.horizon))
+	}
+
+	h.Median = median(values)
+	h.Spread = h.Max - h.Min
+
+	return h
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	return percentile(sorted, 0.5)
+}
+
+func containsFlagged(types []TypeHorizons) bool {
+	for _, t := range types {
+		if t.Flagged {
+			return true
+		}
+	}
+
+	return false
+}
+
+// marketCapHistory saves the current Cerberus answers to the drift store at
+// path and returns a lookup of the first run since which an asset has had its
+// market cap. It warns when the store has no earlier run, since stale market
+// caps can only be told from a history of runs, and when path is empty.
+func marketCapHistory(path string, runAt time.Time, outputMD []liquidityOutput) (func(asset string, marketCap float64) (time.Time, bool), func() error, error) {
+	if path == "" {
+		log.Warn("No -drift-db given, stale market caps are not detected")
+
+		return nil, func() error { return nil }, nil
+	}
+
+	store, err := openDriftStore(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := store.saveCerberus(runAt, environment, outputMD); err != nil {
+		_ = store.Close()
+
+		return nil, nil, err
+	}
+
+	runs, err := store.cerberusRuns(environment)
+	if err != nil {
+		_ = store.Close()
+
+		return nil, nil, err
+	}
+	if runs < 2 {
+		log.Warnf("No earlier Cerberus answers in %s, stale market caps are detected from the next runs", path)
+	}
+
+	since := func(asset string, marketCap float64) (time.Time, bool) {
+		return store.marketCapSince(environment, asset, marketCap)
+	}
+
+	return since, store.Close, nil
+}
+
+// cerberusRuns returns the number of runs with Cerberus answers in the
+// environment.
+func (s *driftStore) cerberusRuns(env string) (int, error) {
+	var runs int
+	if err := s.db.QueryRow(`SELECT COUNT(DISTINCT run_at) FROM cerberus WHERE environment = ?`, env).Scan(&runs); err != nil {
+		return 0, fmt.Errorf("could not count the Cerberus runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// marketCapSince returns the run after the last one where the market cap of
+// the asset differed from marketCap, or the first run if it never did.
+func (s *driftStore) marketCapSince(env, asset string, marketCap float64) (time.Time, bool) {
+	rows, err := s.db.Query(`SELECT run_at, market_cap FROM cerberus WHERE environment = ? AND asset = ? ORDER BY run_at`, env, asset)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer rows.Close()
+
+	var since string
+	for rows.Next() {
+		var runAt string
+		var value sql.NullFloat64
+		if err := rows.Scan(&runAt, &value); err != nil {
+			return time.Time{}, false
+		}
+
+		switch {
+		case !value.Valid || value.Float64 != marketCap:
+			since = ""
+		case since == "":
+			since = runAt
+		}
+	}
+
+	if since == "" || rows.Err() != nil {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+// writeIssuers writes the issuer report as JSON, or as CSV with a row per
+// issuer and type, depending on the extension.
+func writeIssuers(path string, reports []IssuerReport) error {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		raw, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not marshal the issuer report: %w", err)
+		}
+
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			return fmt.Errorf("could not write %s: %w", path, err)
+		}
+
+		return nil
+	}
+
+	csvFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer csvFile.Close()
+
+	csvwriter := csv.NewWriter(csvFile)
+	_ = csvwriter.Write([]string{
+		"issuer", "country", "marketCap", "marketCapSince", "assets", "flags",
+		"type", "typeAssets", "horizonMin", "horizonMinId", "horizonMax", "horizonMaxId", "horizonMedian", "detail",
+	})
+	for _, r := range reports {
+		for _, t := range r.Types {
+			_ = csvwriter.Write([]string{
+				r.Issuer, r.Country, formatFloat(r.MarketCap), r.MarketCapSince, strconv.Itoa(r.Assets), strings.Join(r.Flags, " "),
+				t.Type, strconv.Itoa(t.Assets), strconv.Itoa(t.Min), t.MinID, strconv.Itoa(t.Max), t.MaxID,
+				strconv.FormatFloat(t.Median, 'f', -1, 64), strings.Join(r.Detail, "; "),
+			})
+		}
+	}
+
+	csvwriter.Flush()
+	if err := csvwriter.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
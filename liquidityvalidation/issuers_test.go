@@ -0,0 +1,71 @@
+package liquidityvalidation
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkIssuers(t *testing.T) {
+	t.Parallel()
+
+	marketCap := 5e9
+	outputMD := []liquidityOutput{
+		{id: "A1", issuer: "A", assetType: "EQUITY", horizon: 2, marketCap: &marketCap},
+		{id: "A2", issuer: "A", assetType: "BOND", horizon: 5, marketCap: &marketCap},
+		{id: "A3", issuer: "A", assetType: "BOND", horizon: 30, marketCap: &marketCap},
+		{id: "B1", issuer: "B", assetType: "COMMON_STOCK", horizon: 20},
+		{id: "B2", issuer: "B", assetType: "BOND", horizon: 10},
+		{id: "C1", issuer: "C", assetType: "EQUITY", horizon: 1, marketCap: &marketCap},
+		{id: "D1", issuer: "D", err: assert.AnError},
+	}
+
+	now := time.Date(2024, 10, 14, 0, 0, 0, 0, time.UTC)
+	since := func(asset string, _ float64) (time.Time, bool) {
+		if asset == "C1" {
+			return now.AddDate(0, -3, 0), true
+		}
+
+		return now, true
+	}
+
+	reports := checkIssuers(outputMD, 10, since, now.AddDate(0, 0, -30))
+	require.Len(t, reports, 3)
+
+	assert.Equal(t, "A", reports[0].Issuer)
+	assert.Equal(t, []string{issuerSpreadFlag}, reports[0].Flags)
+	require.Len(t, reports[0].Types, 2)
+	assert.Equal(t, TypeHorizons{Type: "BOND", Assets: 2, Min: 5, MinID: "A2", Max: 30, MaxID: "A3", Median: 17.5, Spread: 25, Flagged: true}, reports[0].Types[0])
+
+	assert.Equal(t, []string{issuerInvertedFlag, issuerMissingFlag}, reports[1].Flags)
+	assert.Equal(t, []string{issuerStaleFlag}, reports[2].Flags)
+	assert.Equal(t, "2024-07-14T00:00:00Z", reports[2].MarketCapSince)
+}
+
+func Test_marketCapSince(t *testing.T) {
+	t.Parallel()
+
+	store, err := openDriftStore(filepath.Join(t.TempDir(), "drift.sqlite"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	runAt := time.Date(2024, 10, 1, 8, 0, 0, 0, time.UTC)
+	for day, value := range []float64{1e9, 2e9, 2e9, 2e9} {
+		marketCap := value
+		require.NoError(t, store.saveCerberus(runAt.AddDate(0, 0, day), "PROD", []liquidityOutput{{id: "A", marketCap: &marketCap}}))
+	}
+
+	runs, err := store.cerberusRuns("PROD")
+	require.NoError(t, err)
+	assert.Equal(t, 4, runs)
+
+	since, ok := store.marketCapSince("PROD", "A", 2e9)
+	require.True(t, ok)
+	assert.Equal(t, runAt.AddDate(0, 0, 1), since)
+
+	_, ok = store.marketCapSince("PROD", "A", 3e9)
+	assert.False(t, ok)
+}
@@ -0,0 +1,339 @@
+package liquidityvalidation
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"toolkit/checkpoint"
+	"toolkit/manifest"
+)
+
+// Size ladder: every asset is priced in Arcanist and Recco for a ladder of
+// quantities instead of a single unit, to exercise the size-dependent part of
+// the liquidity model.
+var (
+	sizeLadder       = ""
+	ladderOutputPath = "ladder.csv"
+)
+
+// ladderRow is an asset at a rung of the ladder. The notional is the quantity
+// at the Eve NPV of a unit, in the currency of the NPV, which may differ from
+// the one of the market cap. Sizes are nil when their reference is unknown.
+type ladderRow struct {
+	ID        string
+	HorizonMD int
+	Quantity  float64
+
+	Notional        *float64
+	SizeToMarketCap *float64
+	SizeToADV       *float64
+	LiquidationDays []float64
+	VolumeHorizon   *int
+
+	ArcanistMarketES    *float64
+	ArcanistLiquidityES *float64
+	ReccoES             *float64
+
+	// Uplift is |liquidity ES| / |market ES| - 1 at the rung, ESRatio the
+	// liquidity ES relative to the one of the first rung priced.
+	Uplift  *float64
+	ESRatio *float64
+}
+
+// ladderAsset is what the rows of an asset are built from.
+type ladderAsset struct {
+	md  liquidityOutput
+	adv float64
+	npv *float64
+
+	// Results of the services by rung.
+	market, liquidity, recco map[int]float64
+}
+
+// runLadder prices the described assets over the ladder and writes the rows
+// to ladderOutputPath.
+func runLadder(run *manifest.Manifest, outputMD []liquidityOutput, rates []float64, checkpointDir string, resume bool) error {
+	ladder, err := parseFloats(sizeLadder)
+	if err != nil {
+		return fmt.Errorf("invalid size ladder: %w", err)
+	}
+	for _, q := range ladder {
+		if q <= 0 {
+			return fmt.Errorf("size ladder quantity %v is not positive", q)
+		}
+	}
+
+	assets := make([]*ladderAsset, 0, len(outputMD))
+	assetIDs := make([]string, 0, len(outputMD))
+	for _, md := range outputMD {
+		if md.err == nil {
+			assets = append(assets, &ladderAsset{md: md})
+			assetIDs = append(assetIDs, md.id)
+		}
+	}
+
+	journal, err := checkpoint.Open(filepath.Join(checkpointDir, "adam.jsonl"), resume)
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+
+	log.Info("Fetch requests in Adam")
+	requests, err := requestAdam(assetIDs, journal)
+	if err != nil {
+		return err
+	}
+	run.Count("adam", len(assetIDs), len(requests))
+
+	advs := averageDailyVolumes(requests)
+	log.Info("Price a unit of every asset in Eve")
+	results := loadScenarioResults(outputMD, requests)
+	for _, a := range assets {
+		a.adv = advs[a.md.id]
+		if r, ok := results[a.md.id]; ok && r.Main.NPV.Status == "Success" {
+			npv := r.Main.NPV.Value
+			a.npv = &npv
+		}
+	}
+
+	ctx := context.Background()
+	chunk := max(1, batchSize/len(ladder))
+	for start := 0; start < len(assets); start += chunk {
+		batch := assets[start:min(start+chunk, len(assets))]
+		if err := requestLadder(ctx, batch, ladder); err != nil {
+			return fmt.Errorf("could not price the size ladder: %w", err)
+		}
+
+		log.Printf("Processed ladder %d/%d assets", start+len(batch), len(assets))
+	}
+
+	rows := make([]ladderRow, 0, len(assets)*len(ladder))
+	priced := 0
+	for _, a := range assets {
+		assetRows := ladderRows(*a, ladder, rates)
+		for _, r := range assetRows {
+			if r.ArcanistLiquidityES != nil {
+				priced++
+			}
+		}
+		rows = append(rows, assetRows...)
+	}
+	run.Count("ladder", len(rows), priced)
+
+	if err := ladderToCsv(ladderOutputPath, rows, rates); err != nil {
+		return err
+	}
+
+	return run.Write(append([]string{ladderOutputPath}, reportFailures()...)...)
+}
+
+// requestLadder prices the assets at every rung in a single Arcanist request
+// per risk type and a single Recco request, a position per asset and rung.
+// The rungs are quantities, so Recco gets them in the quantity scheme.
+func requestLadder(ctx context.Context, batch []*ladderAsset, ladder []float64) error {
+	positions := make(map[int]ArcanistPosition, len(batch)*len(ladder))
+	reccoPositions := make([]ReccoPosition, 0, len(batch)*len(ladder))
+	for i, a := range batch {
+		for k, q := range ladder {
+			positions[i*len(ladder)+k] = ArcanistPosition{
+				Asset:     a.md.id,
+				Quantity:  q,
+				Currency:  positionCurrency,
+				Liquidity: float64(a.md.horizon),
+			}
+			reccoPositions = append(reccoPositions, ReccoPosition{
+				Asset:          a.md.id,
+				Amount:         q,
+				IdentifierType: "id",
+				Key:            strconv.Itoa(i*len(ladder) + k),
+			})
+		}
+	}
+
+	market, err := requestArcanistPositions(ctx, positions, false)
+	if err != nil {
+		return err
+	}
+
+	liquidity, err := requestArcanistPositions(ctx, positions, true)
+	if err != nil {
+		return err
+	}
+
+	recco, err := requestReccoQuantities(ctx, reccoPositions)
+	if err != nil {
+		return err
+	}
+
+	for i, a := range batch {
+		a.market = make(map[int]float64, len(ladder))
+		a.liquidity = make(map[int]float64, len(ladder))
+		a.recco = make(map[int]float64, len(ladder))
+
+		for k := range ladder {
+			index := i*len(ladder) + k
+			if v, ok := market[index]; ok {
+				a.market[k] = v
+			}
+			if v, ok := liquidity[index]; ok {
+				a.liquidity[k] = v
+			}
+			if v, ok := recco[strconv.Itoa(index)]; ok {
+				a.recco[k] = v
+			}
+		}
+	}
+
+	return nil
+}
+
+// averageDailyVolumes returns the ADV of every dump carrying trading volumes.
+func averageDailyVolumes(requests []Request) map[string]float64 {
+	advs := make(map[string]float64, len(requests))
+	for _, request := range requests {
+		var payload struct {
+			TradingVolumes json.RawMessage `json:"tradingVolumes"`
+		}
+		if err := json.Unmarshal(request.Payload, &payload); err != nil || len(payload.TradingVolumes) == 0 || string(payload.TradingVolumes) == "null" {
+			continue
+		}
+
+		volumes, err := parseTradingVolumes(payload.TradingVolumes)
+		if err != nil {
+			continue
+		}
+
+		estimate, err := estimateVolumeHorizon(volumes, 1, nil)
+		if err != nil {
+			continue
+		}
+
+		advs[request.ID] = estimate.ADV
+	}
+
+	return advs
+}
+
+// ladderRows returns the rows of an asset, a row per rung.
+func ladderRows(a ladderAsset, ladder []float64, rates []float64) []ladderRow {
+	rows := make([]ladderRow, 0, len(ladder))
+
+	var first *float64
+	for k, q := range ladder {
+		row := ladderRow{ID: a.md.id, HorizonMD: a.md.horizon, Quantity: q}
+
+		if a.npv != nil {
+			notional := q * math.Abs(*a.npv)
+			row.Notional = &notional
+
+			if a.md.marketCap != nil && *a.md.marketCap > 0 {
+				size := notional / *a.md.marketCap
+				row.SizeToMarketCap = &size
+			}
+		}
+
+		if a.adv > 0 {
+			size := q / a.adv
+			row.SizeToADV = &size
+
+			for _, rate := range rates {
+				row.LiquidationDays = append(row.LiquidationDays, size/rate)
+			}
+			if len(rates) > 0 {
+				horizon := int(math.Ceil(row.LiquidationDays[0]))
+				row.VolumeHorizon = &horizon
+			}
+		}
+
+		if v, ok := a.market[k]; ok {
+			row.ArcanistMarketES = &v
+		}
+		if v, ok := a.liquidity[k]; ok {
+			row.ArcanistLiquidityES = &v
+
+			if row.ArcanistMarketES != nil && *row.ArcanistMarketES != 0 {
+				uplift := math.Abs(v)/math.Abs(*row.ArcanistMarketES) - 1
+				row.Uplift = &uplift
+			}
+
+			if first == nil {
+				first = &v
+			}
+			if *first != 0 {
+				ratio := math.Abs(v) / math.Abs(*first)
+				row.ESRatio = &ratio
+			}
+		}
+		if v, ok := a.recco[k]; ok {
+			row.ReccoES = &v
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func ladderToCsv(path string, rows []ladderRow, rates []float64) error {
+	csvFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer csvFile.Close()
+
+	csvwriter := csv.NewWriter(csvFile)
+
+	header := []string{"id", "horizonMD", "quantity", "notional", "sizeToMarketCap", "sizeToADV", "horizonVolumeEstimate"}
+	for _, rate := range rates {
+		header = append(header, fmt.Sprintf("liquidationDays-%v", rate))
+	}
+	header = append(header, "esArcanist", "esArcanistLiquidity", "esRecco", "liquidityUplift", "esRatio")
+	_ = csvwriter.Write(header)
+
+	for _, r := range rows {
+		row := []string{
+			r.ID,
+			strconv.Itoa(r.HorizonMD),
+			strconv.FormatFloat(r.Quantity, 'f', -1, 64),
+			formatFloat(r.Notional),
+			formatFloat(r.SizeToMarketCap),
+			formatFloat(r.SizeToADV),
+			"",
+		}
+		if r.VolumeHorizon != nil {
+			row[6] = strconv.Itoa(*r.VolumeHorizon)
+		}
+
+		for i := range rates {
+			if i < len(r.LiquidationDays) {
+				row = append(row, strconv.FormatFloat(r.LiquidationDays[i], 'f', -1, 64))
+			} else {
+				row = append(row, "")
+			}
+		}
+
+		row = append(row,
+			formatFloat(r.ArcanistMarketES),
+			formatFloat(r.ArcanistLiquidityES),
+			formatFloat(r.ReccoES),
+			formatFloat(r.Uplift),
+			formatFloat(r.ESRatio),
+		)
+		_ = csvwriter.Write(row)
+	}
+
+	csvwriter.Flush()
+	if err := csvwriter.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
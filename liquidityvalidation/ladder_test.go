@@ -0,0 +1,44 @@
+package liquidityvalidation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ladderRows(t *testing.T) {
+	t.Parallel()
+
+	marketCap, npv := 1e6, 50.0
+	a := ladderAsset{
+		md:        liquidityOutput{id: "A", horizon: 10, marketCap: &marketCap},
+		adv:       1000,
+		npv:       &npv,
+		market:    map[int]float64{0: -0.1, 1: -0.1, 2: -0.1},
+		liquidity: map[int]float64{0: -0.12, 2: -0.18},
+		recco:     map[int]float64{1: 0.11},
+	}
+
+	rows := ladderRows(a, []float64{10, 1000, 10000}, []float64{0.1, 0.25})
+	require.Len(t, rows, 3)
+
+	assert.InDelta(t, 500, *rows[0].Notional, 1e-9)
+	assert.InDelta(t, 5e-4, *rows[0].SizeToMarketCap, 1e-12)
+	assert.InDelta(t, 0.01, *rows[0].SizeToADV, 1e-12)
+	assert.Equal(t, 1, *rows[0].VolumeHorizon)
+	assert.InDelta(t, 0.2, *rows[0].Uplift, 1e-9)
+	assert.InDelta(t, 1, *rows[0].ESRatio, 1e-9)
+
+	assert.Nil(t, rows[1].ArcanistLiquidityES)
+	assert.Nil(t, rows[1].ESRatio)
+	assert.InDelta(t, 0.11, *rows[1].ReccoES, 1e-9)
+
+	assert.Equal(t, []float64{100, 40}, rows[2].LiquidationDays)
+	assert.Equal(t, 100, *rows[2].VolumeHorizon)
+	assert.InDelta(t, 1.5, *rows[2].ESRatio, 1e-9)
+
+	rows = ladderRows(ladderAsset{md: liquidityOutput{id: "B"}}, []float64{1}, []float64{0.1})
+	assert.Nil(t, rows[0].Notional)
+	assert.Nil(t, rows[0].SizeToADV)
+}
@@ -1,14 +1,25 @@
-package main
+package liquidityvalidation
 
 import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"math"
 	"net/http"
+	"sync"
+	"sync/atomic"
 
 	log "github.com/sirupsen/logrus"
+
+	"toolkit/input"
+)
+
+var (
+	cerberusURL            = "http://marketdata.service.consul"
+	cerberusURLPROD        = "https://api.edgelab.ch/cerberus"
+	cerberusIssuersURLPROD = "https://api.edgelab.ch/cerberus"
+
+	// marketdataWorkers is the number of concurrent Cerberus requests.
+	marketdataWorkers = 8
 )
 
 type liquidityOutput struct {
@@ -16,66 +27,160 @@ type liquidityOutput struct {
 	horizon    int
 	marketCap  *float64
 	pocHorizon *int
+	issuer     string
+	country    string
+	assetType  string
+
+	// err is why the asset or its issuer could not be described.
+	err error
+}
+
+// assetDescription is the part of a Cerberus asset the validation uses.
+type assetDescription struct {
+	horizon   int
+	issuer    string
+	assetType string
 }
 
-func requestMarketdata(assetIDs []string) ([]liquidityOutput, error) {
-	maxNumber := len(assetIDs)
-	liquidityHorizons := make([]liquidityOutput, 0, maxNumber)
-	issuersMarketCap := make(map[string]*float64)
-	counter := 0
-	for _, id := range assetIDs {
-		horizon, issuer, err := liquidityHorizon(id)
+// issuerDescription is the part of a Cerberus issuer the validation uses.
+type issuerDescription struct {
+	marketCap *float64
+	country   string
+}
+
+// isinResolver resolves the ISINs of the input through Cerberus.
+func isinResolver() input.Resolver {
+	if environment == "PROD" {
+		return input.Cerberus(cerberusIssuersURLPROD, tokenPROD)
+	}
+
+	return input.Cerberus(cerberusURL, "")
+}
+
+// requestMarketdata fetches the liquidity horizon of the assets and the market
+// cap of their issuers. Assets are described concurrently, then every issuer is
+// fetched once. Failures are kept on the asset instead of stopping the run.
+func requestMarketdata(assetIDs []string) []liquidityOutput {
+	return fetchMarketdata(assetIDs, marketdataWorkers, liquidityHorizon, requestIssuer)
+}
+
+func fetchMarketdata(
+	assetIDs []string,
+	workers int,
+	describeAsset func(id string) (assetDescription, error),
+	describeIssuer func(id string) (issuerDescription, error),
+) []liquidityOutput {
+	outputs := make([]liquidityOutput, len(assetIDs))
+
+	var processed atomic.Int64
+	forEach(len(assetIDs), workers, func(i int) {
+		id := assetIDs[i]
+		output := liquidityOutput{id: id}
+
+		description, err := describeAsset(id)
 		if err != nil {
-			return nil, fmt.Errorf("could not check liquidity horizon: %w", err)
+			output.err = fmt.Errorf("could not check liquidity horizon: %w", err)
 		}
+		output.horizon = description.horizon
+		output.issuer = description.issuer
+		output.assetType = description.assetType
+		outputs[i] = output
 
-		output := liquidityOutput{
-			id:      id,
-			horizon: horizon,
+		if n := processed.Add(1); n%100 == 0 {
+			log.Printf("Processed %d/%d assets (%f%%)", n, len(assetIDs), float64(n)/float64(len(assetIDs))*100)
+		}
+	})
+
+	issuerIDs := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, output := range outputs {
+		if output.err == nil && output.issuer != "" && !seen[output.issuer] {
+			seen[output.issuer] = true
+			issuerIDs = append(issuerIDs, output.issuer)
 		}
+	}
 
-		if marketCap, ok := issuersMarketCap[issuer]; ok {
-			if marketCap != nil {
-				output.marketCap = marketCap
+	log.Infof("Fetching %d issuers of %d assets", len(issuerIDs), len(assetIDs))
+	issuers := make([]issuerDescription, len(issuerIDs))
+	issuerErrs := make([]error, len(issuerIDs))
+	forEach(len(issuerIDs), workers, func(i int) {
+		issuers[i], issuerErrs[i] = describeIssuer(issuerIDs[i])
+	})
 
-				pocHorizon := marketCapToHorizon(marketCap)
-				output.pocHorizon = &pocHorizon
-			}
-		} else {
-			marketCap, err := issuerMarketCap(issuer)
-			if err != nil {
-				return nil, fmt.Errorf("could not check issuer market cap: %w", err)
+	byIssuer := make(map[string]int, len(issuerIDs))
+	for i, id := range issuerIDs {
+		byIssuer[id] = i
+	}
+
+	failed := 0
+	for i := range outputs {
+		output := &outputs[i]
+
+		if j, ok := byIssuer[output.issuer]; ok && output.err == nil {
+			if issuerErrs[j] != nil {
+				output.err = fmt.Errorf("could not check issuer market cap: %w", issuerErrs[j])
 			}
 
-			issuersMarketCap[issuer] = marketCap
-			if marketCap != nil {
-				output.marketCap = marketCap
+			output.country = issuers[j].country
+			if issuers[j].marketCap != nil {
+				output.marketCap = issuers[j].marketCap
 
-				pocHorizon := marketCapToHorizon(marketCap)
+				pocHorizon := marketCapToHorizon(issuers[j].marketCap)
 				output.pocHorizon = &pocHorizon
 			}
 		}
 
-		liquidityHorizons = append(liquidityHorizons, output)
-
-		counter++
-		if counter%100 == 0 {
-			log.Printf("Processed %d/%d assets (%f%%)", counter, maxNumber, float64(counter)/float64(maxNumber)*100)
+		if output.err != nil {
+			log.Warnf("asset %s: %v", output.id, output.err)
+			failed++
 		}
 	}
 
-	return liquidityHorizons, nil
+	if failed > 0 {
+		log.Warnf("Could not describe %d/%d assets", failed, len(assetIDs))
+	}
+
+	return outputs
 }
 
-func liquidityHorizon(id string) (int, string, error) {
-	url := fmt.Sprintf("http://marketdata.service.consul/assets/id/%s?view=full", id)
+// forEach calls fn for 0 to n-1 on a pool of workers.
+func forEach(n, workers int, fn func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+
+		for i := 0; i < n; i++ {
+			indexes <- i
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func liquidityHorizon(id string) (assetDescription, error) {
+	url := fmt.Sprintf("%s/assets/id/%s?view=full", cerberusURL, id)
 	if environment == "PROD" {
-		url = fmt.Sprintf("https://api.edgelab.ch/cerberux/assets/id/%s?view=full", id)
+		url = fmt.Sprintf("%s/assets/id/%s?view=full", cerberusURLPROD, id)
 	}
 
 	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
 	if err != nil {
-		return 0, "", fmt.Errorf("could not create the request: %w", err)
+		return assetDescription{}, fmt.Errorf("could not create the request: %w", err)
 	}
 	req.Header.Set("x-internal-service", "validation")
 	req.Header.Set("Content-Type", "application/json")
@@ -84,46 +189,38 @@ func liquidityHorizon(id string) (int, string, error) {
 		req.Header.Set("Authorization", "Bearer "+tokenPROD)
 	}
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	status, raw, err := sendRequest(req, "cerberus", "", nil, liveTTL)
 	if err != nil {
-		return 0, "", fmt.Errorf("could not send the request: %w", err)
+		return assetDescription{}, err
 	}
 
-	defer res.Body.Close()
-
-	raw, err := io.ReadAll(res.Body)
-	if err != nil {
-		return 0, "", fmt.Errorf("could not read response body: %w", err)
-	}
-
-	if res.StatusCode != http.StatusOK {
-		log.Infof("failed with asset %s, status code %d, response %s", id, res.StatusCode, string(raw))
-		return 0, "", nil
+	if status != http.StatusOK {
+		return assetDescription{}, fmt.Errorf("asset %s failed with status code %d, response %s", id, status, string(raw))
 	}
 
 	var response struct {
-		Horizon int `json:"liquidityHorizon"`
+		Horizon int    `json:"liquidityHorizon"`
+		Type    string `json:"type"`
 		Issuer  struct {
 			ID string `json:"id"`
 		} `json:"issuer"`
 	}
 	if err := json.Unmarshal(raw, &response); err != nil {
-		return 0, "", fmt.Errorf("received non-JSON response: %s, error was: %w", string(raw), err)
+		return assetDescription{}, fmt.Errorf("received non-JSON response: %s, error was: %w", string(raw), err)
 	}
 
-	return response.Horizon, response.Issuer.ID, nil
+	return assetDescription{horizon: response.Horizon, issuer: response.Issuer.ID, assetType: response.Type}, nil
 }
 
-func issuerMarketCap(id string) (*float64, error) {
-	url := fmt.Sprintf("http://marketdata.service.consul/issuers/%s?view=full", id)
+func requestIssuer(id string) (issuerDescription, error) {
+	url := fmt.Sprintf("%s/issuers/%s?view=full", cerberusURL, id)
 	if environment == "PROD" {
-		url = fmt.Sprintf("https://api.edgelab.ch/cerberus/issuers/%s?view=full", id)
+		url = fmt.Sprintf("%s/issuers/%s?view=full", cerberusIssuersURLPROD, id)
 	}
 
 	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("could not create the request: %w", err)
+		return issuerDescription{}, fmt.Errorf("could not create the request: %w", err)
 	}
 	req.Header.Set("x-internal-service", "validation")
 	req.Header.Set("Content-Type", "application/json")
@@ -131,66 +228,32 @@ func issuerMarketCap(id string) (*float64, error) {
 	if environment == "PROD" {
 		req.Header.Set("Authorization", "Bearer "+tokenPROD)
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("could not send the request: %w", err)
-	}
-
-	defer res.Body.Close()
-
-	raw, err := io.ReadAll(res.Body)
+	status, raw, err := sendRequest(req, "cerberus", "", nil, liveTTL)
 	if err != nil {
-		return nil, fmt.Errorf("could not read response body: %w", err)
+		return issuerDescription{}, err
 	}
 
-	if res.StatusCode != http.StatusOK {
-		log.Infof("failed with issuer %s, status code %d, response %s", id, res.StatusCode, string(raw))
-		return nil, nil
+	if status != http.StatusOK {
+		return issuerDescription{}, fmt.Errorf("issuer %s failed with status code %d, response %s", id, status, string(raw))
 	}
 
 	var response struct {
 		MarketValue *float64 `json:"marketValue"`
+		Country     struct {
+			ID string `json:"id"`
+		} `json:"country"`
 	}
 	if err := json.Unmarshal(raw, &response); err != nil {
-		return nil, fmt.Errorf("received non-JSON response: %s, error was: %w", string(raw), err)
+		return issuerDescription{}, fmt.Errorf("received non-JSON response: %s, error was: %w", string(raw), err)
 	}
 
+	description := issuerDescription{country: response.Country.ID}
 	if response.MarketValue == nil {
-		return nil, nil
+		return description, nil
 	}
 
 	value := *response.MarketValue * 1000000
+	description.marketCap = &value
 
-	return &value, nil
-}
-
-func marketCapToHorizon(marketCap *float64) int {
-	const (
-		maxHorizon = 30
-
-		//  Normalize between 0 and 1, kind of
-		liquid   = 10.0
-		illiquid = 8.0
-	)
-
-	if marketCap == nil {
-		return maxHorizon / 2.0
-	}
-
-	if *marketCap <= 0 {
-		return maxHorizon
-	}
-
-	// Usual definition:
-	// Mega : cap > 200 B$ (2e11)
-	// Large: cap > 10 B$ (1e10)
-	// Med  : cap > 2 B$ (2e9)
-	// Small: cap > 250 M$ (2.5e8)
-	// Micro: cap > 50M$ (5e7)
-	// Nano : cap otherwise
-
-	mlog := (math.Log10(*marketCap) - illiquid) / (liquid - illiquid)
-
-	return int(maxHorizon * (1.0 - min(max(mlog, 0.0), 1.0)))
+	return description, nil
 }
@@ -1,17 +1,86 @@
-package main
+package liquidityvalidation
 
 import (
-	"fmt"
+	"errors"
+	"math"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_marketCapToHorizon(t *testing.T) {
-	for _, marketCap := range []float64{0, 1, 100, 1000, 10000, 100000, 1000000, 10000000, 100000000, 1000000000, 10000000000, 100000000000, 1000000000000, 10000000000000, 100000000000000, 1000000000000000, 10000000000000000, 100000000000000000, 1000000000000000000, 10000000000000000000} {
-		horizon := marketCapToHorizon(&marketCap)
-		fmt.Println("marketCap:", marketCap, "horizon:", horizon)
+	t.Parallel()
+
+	assert.Equal(t, 15, marketCapToHorizon(nil))
+
+	for marketCap, horizon := range map[float64]int{-1: 30, 0: 30, 1e8: 30, 1e9: 15, 1e10: 0, 1e13: 0} {
+		assert.Equal(t, horizon, marketCapToHorizon(&marketCap), "market cap %v", marketCap)
+	}
+
+	// The default model keeps the formula of the proof of concept.
+	old := func(marketCap float64) int {
+		mlog := (math.Log10(marketCap) - 8.0) / (10.0 - 8.0)
+
+		return int(30 * (1.0 - min(max(mlog, 0.0), 1.0)))
+	}
+	for _, marketCap := range []float64{1, 100, 1e6, 5e7, 1e8, 2.5e8, 1e9, 2e9, 5e9, 1e10, 2e11, 1e15, 1e19} {
+		assert.Equal(t, old(marketCap), marketCapToHorizon(&marketCap), "market cap %v", marketCap)
+	}
+}
+
+func Test_fetchMarketdata(t *testing.T) {
+	t.Parallel()
+
+	assets := map[string]string{"A": "I1", "B": "I1", "C": "I2", "D": "I3"}
+	marketCap := 5e9
+
+	var mu sync.Mutex
+	issuerCalls := make(map[string]int)
+
+	outputs := fetchMarketdata(
+		[]string{"A", "B", "C", "D", "E"},
+		3,
+		func(id string) (assetDescription, error) {
+			issuer, ok := assets[id]
+			if !ok {
+				return assetDescription{}, errors.New("not found")
+			}
+
+			return assetDescription{horizon: 10, issuer: issuer, assetType: "EQUITY"}, nil
+		},
+		func(id string) (issuerDescription, error) {
+			mu.Lock()
+			issuerCalls[id]++
+			mu.Unlock()
+
+			if id == "I3" {
+				return issuerDescription{}, errors.New("unavailable")
+			}
+
+			return issuerDescription{marketCap: &marketCap, country: "CH"}, nil
+		},
+	)
+
+	require.Len(t, outputs, 5)
+	assert.Equal(t, map[string]int{"I1": 1, "I2": 1, "I3": 1}, issuerCalls)
+
+	for i, id := range []string{"A", "B", "C", "D", "E"} {
+		assert.Equal(t, id, outputs[i].id)
+	}
+
+	for _, output := range outputs[:3] {
+		require.NoError(t, output.err)
+		assert.Equal(t, 10, output.horizon)
+		assert.Equal(t, "CH", output.country)
+		assert.Equal(t, "EQUITY", output.assetType)
+		require.NotNil(t, output.pocHorizon)
+		assert.Equal(t, marketCapToHorizon(&marketCap), *output.pocHorizon)
 	}
 
-	assert.True(t, false)
+	assert.ErrorContains(t, outputs[3].err, "unavailable")
+	assert.Equal(t, 10, outputs[3].horizon)
+	assert.Nil(t, outputs[3].marketCap)
+	assert.ErrorContains(t, outputs[4].err, "not found")
 }
@@ -0,0 +1,506 @@
+package liquidityvalidation
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"toolkit/checkpoint"
+	"toolkit/input"
+	"toolkit/manifest"
+	"toolkit/risk"
+)
+
+// Portfolio mode: the ES of the positions of a file is computed locally from
+// the scenario values and through Arcanist and Recco.
+var (
+	positionsPath       = ""
+	portfolioOutputPath = "portfolio.json"
+)
+
+// PortfolioReport is the ES of a portfolio and its breakdown per position. ES
+// are positive losses in the currency of the positions, relative measures of
+// Arcanist and Recco being scaled by the value of the positions.
+type PortfolioReport struct {
+	Confidence float64  `json:"confidence"`
+	Currencies []string `json:"currencies"`
+	Value      float64  `json:"value"`
+	Scenarios  int      `json:"scenarios"`
+	Excluded   int      `json:"excluded"`
+
+	// MarketES is the ES of the portfolio, StandaloneES the sum of the ES of
+	// the positions on their own and Diversification the difference, with
+	// DiversificationRatio its share of StandaloneES. The liquidity ones scale
+	// the P&L of every position to its liquidity horizon.
+	MarketES             float64 `json:"marketES"`
+	StandaloneES         float64 `json:"standaloneES"`
+	Diversification      float64 `json:"diversification"`
+	DiversificationRatio float64 `json:"diversificationRatio"`
+
+	LiquidityES              float64 `json:"liquidityES"`
+	LiquidityStandaloneES    float64 `json:"liquidityStandaloneES"`
+	LiquidityDiversification float64 `json:"liquidityDiversification"`
+
+	// Sums of the per-position answers of Arcanist and Recco, to reconcile
+	// with the local standalone ES, not with the portfolio ES.
+	ArcanistMarketES    *float64 `json:"arcanistMarketES,omitempty"`
+	ArcanistLiquidityES *float64 `json:"arcanistLiquidityES,omitempty"`
+	ReccoES             *float64 `json:"reccoES,omitempty"`
+
+	// ReccoPortfolioES is the ES of the portfolio from the portfolio
+	// granularity of Recco, liquidity adjusted, and ReccoPortfolioGap its gap
+	// to LiquidityES. Arcanist only prices positions, so MarketES has no
+	// remote counterpart: Reconciliation says so, along with any portfolio
+	// measure that could not be fetched.
+	ReccoPortfolioES  *float64 `json:"reccoPortfolioES,omitempty"`
+	ReccoPortfolioGap *float64 `json:"reccoPortfolioGap,omitempty"`
+	Reconciliation    []string `json:"reconciliation"`
+
+	Positions []PortfolioPosition `json:"positions"`
+}
+
+// PortfolioPosition is the ES breakdown of a position.
+//
+// ComponentES is the mean loss of the position over the tail of the portfolio,
+// the components adding up to the ES of the portfolio; MarginalES is the
+// change of the portfolio ES per unit of quantity, ComponentES / Quantity by
+// homogeneity of the ES.
+type PortfolioPosition struct {
+	Index    int     `json:"index"`
+	ID       string  `json:"id"`
+	Currency string  `json:"currency"`
+	Quantity float64 `json:"quantity"`
+	Value    float64 `json:"value"`
+	Horizon  int     `json:"horizon"`
+
+	StandaloneES float64 `json:"standaloneES"`
+	ComponentES  float64 `json:"componentES"`
+	MarginalES   float64 `json:"marginalES"`
+	Share        float64 `json:"share"`
+
+	LiquidityStandaloneES float64 `json:"liquidityStandaloneES"`
+	LiquidityComponentES  float64 `json:"liquidityComponentES"`
+
+	ArcanistMarketES    *float64 `json:"arcanistMarketES,omitempty"`
+	ArcanistLiquidityES *float64 `json:"arcanistLiquidityES,omitempty"`
+	ReccoES             *float64 `json:"reccoES,omitempty"`
+
+	// Gaps of Arcanist and Recco relative to the local standalone ES, the
+	// liquidity one for Recco.
+	ArcanistMarketGap    *float64 `json:"arcanistMarketGap,omitempty"`
+	ArcanistLiquidityGap *float64 `json:"arcanistLiquidityGap,omitempty"`
+	ReccoGap             *float64 `json:"reccoGap,omitempty"`
+}
+
+// runPortfolio computes the ES of the positions file locally and remotely and
+// writes the report.
+func runPortfolio(run *manifest.Manifest, checkpointDir string, resume bool) error {
+	positions, err := input.ReadPositions(positionsPath, input.Options{Resolver: isinResolver()})
+	if err != nil {
+		return fmt.Errorf("could not read positions: %w", err)
+	}
+
+	if err := run.AddInput(positionsPath); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(positions))
+	assetIDs := make([]string, 0, len(positions))
+	for _, p := range positions {
+		if !seen[p.ID] {
+			seen[p.ID] = true
+			assetIDs = append(assetIDs, p.ID)
+		}
+	}
+
+	log.Info("Fetch liquidity horizons in MD")
+	outputMD := requestMarketdata(assetIDs)
+
+	horizons := make(map[string]int, len(outputMD))
+	for _, md := range outputMD {
+		horizons[md.id] = md.horizon
+	}
+
+	var requests []Request
+	if scenarioFixtures == "" {
+		journal, err := checkpoint.Open(filepath.Join(checkpointDir, "adam.jsonl"), resume)
+		if err != nil {
+			return err
+		}
+		defer journal.Close()
+
+		log.Info("Fetch requests in Adam")
+		requests, err = requestAdam(assetIDs, journal)
+		if err != nil {
+			return err
+		}
+		run.Count("adam", len(assetIDs), len(requests))
+	}
+
+	results := loadScenarioResults(outputMD, requests)
+	run.Count("scenarios", len(assetIDs), len(results))
+
+	report, err := portfolioES(positions, horizons, results)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	arcanistPositions := make(map[int]ArcanistPosition, len(positions))
+	reccoPositions := make([]ReccoPosition, len(positions))
+	for i, p := range positions {
+		currency := p.Currency
+		if currency == "" {
+			currency = positionCurrency
+		}
+
+		arcanistPositions[i] = ArcanistPosition{
+			Asset:     p.ID,
+			Quantity:  p.Quantity,
+			Currency:  currency,
+			Liquidity: float64(horizons[p.ID]),
+		}
+		reccoPositions[i] = ReccoPosition{
+			Asset:          p.ID,
+			Amount:         p.Quantity,
+			IdentifierType: "id",
+			Key:            strconv.Itoa(i),
+		}
+	}
+
+	log.Info("Fetch the portfolio in Arcanist")
+	arcanistMarket, err := requestArcanistPositions(ctx, arcanistPositions, false)
+	if err != nil {
+		return fmt.Errorf("could not fetch the market ES in Arcanist: %w", err)
+	}
+
+	arcanistLiquidity, err := requestArcanistPositions(ctx, arcanistPositions, true)
+	if err != nil {
+		return fmt.Errorf("could not fetch the liquidity ES in Arcanist: %w", err)
+	}
+	run.Count("arcanist", len(positions), len(arcanistLiquidity))
+
+	log.Info("Fetch the portfolio in Recco")
+	reccoByKey, err := requestReccoQuantities(ctx, reccoPositions)
+	if err != nil {
+		return fmt.Errorf("could not fetch the ES in Recco: %w", err)
+	}
+	run.Count("recco", len(positions), len(reccoByKey))
+
+	log.Info("Fetch the portfolio ES in Recco")
+	reccoPortfolio, reccoErr := requestReccoPortfolio(ctx, reccoPositions)
+	if reccoErr != nil {
+		log.Warnf("Could not fetch the portfolio ES in Recco: %v", reccoErr)
+	}
+
+	recco := make(map[int]float64, len(reccoByKey))
+	for key, v := range reccoByKey {
+		i, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("unexpected Recco key %q", key)
+		}
+		recco[i] = v
+	}
+
+	reconcilePortfolio(&report, arcanistMarket, arcanistLiquidity, recco)
+	reconcilePortfolioES(&report, reccoPortfolio, reccoErr)
+
+	log.Infof("Portfolio ES %f (liquidity %f), standalone %f, diversification %f (%.1f%%)",
+		report.MarketES, report.LiquidityES, report.StandaloneES, report.Diversification, 100*report.DiversificationRatio)
+	for _, note := range report.Reconciliation {
+		log.Warn(note)
+	}
+
+	if err := writePortfolio(portfolioOutputPath, report); err != nil {
+		return err
+	}
+
+	return run.Write(append([]string{portfolioOutputPath}, reportFailures()...)...)
+}
+
+// portfolioES computes the local ES of the portfolio and breaks it down per
+// position. Scenarios not in Success for any position are left out.
+func portfolioES(positions []input.Position, horizons map[string]int, results map[string]scenarioResult) (PortfolioReport, error) {
+	report := PortfolioReport{Confidence: confidenceLevel}
+
+	currencies := make(map[string]bool)
+	for i, p := range positions {
+		result, ok := results[p.ID]
+		if !ok {
+			return PortfolioReport{}, fmt.Errorf("no scenario values for position %d (%s)", i+1, p.ID)
+		}
+
+		value := p.Quantity * result.Main.NPV.Value
+		report.Value += value
+		currencies[p.Currency] = true
+
+		report.Positions = append(report.Positions, PortfolioPosition{
+			Index:    i,
+			ID:       p.ID,
+			Currency: p.Currency,
+			Quantity: p.Quantity,
+			Value:    value,
+			Horizon:  horizons[p.ID],
+		})
+	}
+
+	for c := range currencies {
+		report.Currencies = append(report.Currencies, c)
+	}
+	sort.Strings(report.Currencies)
+
+	if len(report.Currencies) > 1 {
+		log.Warnf("Positions in %s are added without FX conversion", strings.Join(report.Currencies, ", "))
+	}
+
+	market := make([]risk.Observation, 0, nbScenarios)
+	liquidity := make([]risk.Observation, 0, nbScenarios)
+	marketParts := make(map[string]map[uint32]float64, len(positions))
+	liquidityParts := make(map[string]map[uint32]float64, len(positions))
+	for i := range positions {
+		marketParts[strconv.Itoa(i)] = make(map[uint32]float64, nbScenarios)
+		liquidityParts[strconv.Itoa(i)] = make(map[uint32]float64, nbScenarios)
+	}
+
+	for s := 0; s < nbScenarios; s++ {
+		id := uint32(firstScenarioID + s)
+
+		pnl := make([]float64, len(positions))
+		skip := false
+		for i, p := range positions {
+			result := results[p.ID]
+
+			scenario, ok := result.Scenarios[id]
+			if !ok || scenario.Status != "Success" {
+				skip = true
+
+				break
+			}
+
+			pnl[i] = p.Quantity * (scenario.Value - result.Main.NPV.Value)
+		}
+
+		if skip {
+			report.Excluded++
+
+			continue
+		}
+
+		var totalMarket, totalLiquidity float64
+		for i, p := range positions {
+			scaled := pnl[i] * horizonScaling(horizons[p.ID])
+
+			marketParts[strconv.Itoa(i)][id] = pnl[i]
+			liquidityParts[strconv.Itoa(i)][id] = scaled
+			totalMarket += pnl[i]
+			totalLiquidity += scaled
+		}
+
+		market = append(market, risk.Observation{ID: id, PnL: totalMarket, Weight: 1})
+		liquidity = append(liquidity, risk.Observation{ID: id, PnL: totalLiquidity, Weight: 1})
+	}
+	report.Scenarios = len(market)
+
+	marketContributions, marketES, err := risk.Contributions(market, marketParts, confidenceLevel)
+	if err != nil {
+		return PortfolioReport{}, fmt.Errorf("could not compute the portfolio ES: %w", err)
+	}
+
+	liquidityContributions, liquidityES, err := risk.Contributions(liquidity, liquidityParts, confidenceLevel)
+	if err != nil {
+		return PortfolioReport{}, fmt.Errorf("could not compute the portfolio liquidity ES: %w", err)
+	}
+
+	report.MarketES = -marketES
+	report.LiquidityES = -liquidityES
+
+	for _, c := range marketContributions {
+		i, _ := strconv.Atoi(c.Name)
+		report.Positions[i].ComponentES = -c.ES
+		report.Positions[i].Share = c.Share
+		if positions[i].Quantity != 0 {
+			report.Positions[i].MarginalES = -c.ES / positions[i].Quantity
+		}
+	}
+
+	for _, c := range liquidityContributions {
+		i, _ := strconv.Atoi(c.Name)
+		report.Positions[i].LiquidityComponentES = -c.ES
+	}
+
+	for i := range report.Positions {
+		key := strconv.Itoa(i)
+
+		standalone, err := risk.ES(partObservations(market, marketParts[key]), confidenceLevel)
+		if err != nil {
+			return PortfolioReport{}, fmt.Errorf("could not compute the ES of position %d: %w", i+1, err)
+		}
+
+		liquidityStandalone, err := risk.ES(partObservations(liquidity, liquidityParts[key]), confidenceLevel)
+		if err != nil {
+			return PortfolioReport{}, fmt.Errorf("could not compute the liquidity ES of position %d: %w", i+1, err)
+		}
+
+		report.Positions[i].StandaloneES = -standalone
+		report.Positions[i].LiquidityStandaloneES = -liquidityStandalone
+		report.StandaloneES += -standalone
+		report.LiquidityStandaloneES += -liquidityStandalone
+	}
+
+	report.Diversification = report.StandaloneES - report.MarketES
+	if report.StandaloneES != 0 {
+		report.DiversificationRatio = report.Diversification / report.StandaloneES
+	}
+	report.LiquidityDiversification = report.LiquidityStandaloneES - report.LiquidityES
+
+	return report, nil
+}
+
+// partObservations returns the observations of the total with the P&L of a
+// part.
+func partObservations(total []risk.Observation, part map[uint32]float64) []risk.Observation {
+	obs := make([]risk.Observation, len(total))
+	for i, o := range total {
+		obs[i] = risk.Observation{ID: o.ID, PnL: part[o.ID], Weight: o.Weight}
+	}
+
+	return obs
+}
+
+// reconcilePortfolio adds the relative Arcanist and Recco ES of the positions,
+// by index, and their gaps to the local standalone ES.
+func reconcilePortfolio(report *PortfolioReport, arcanistMarket, arcanistLiquidity, recco map[int]float64) {
+	add := func(sum **float64, v float64) {
+		if *sum == nil {
+			*sum = new(float64)
+		}
+		**sum += v
+	}
+
+	gap := func(remote, local float64) *float64 {
+		if local == 0 {
+			return nil
+		}
+
+		g := remote/local - 1
+
+		return &g
+	}
+
+	for i := range report.Positions {
+		p := &report.Positions[i]
+		value := math.Abs(p.Value)
+
+		if v, ok := arcanistMarket[i]; ok {
+			es := math.Abs(v) * value
+			p.ArcanistMarketES = &es
+			p.ArcanistMarketGap = gap(es, p.StandaloneES)
+			add(&report.ArcanistMarketES, es)
+		}
+
+		if v, ok := arcanistLiquidity[i]; ok {
+			es := math.Abs(v) * value
+			p.ArcanistLiquidityES = &es
+			p.ArcanistLiquidityGap = gap(es, p.LiquidityStandaloneES)
+			add(&report.ArcanistLiquidityES, es)
+		}
+
+		if v, ok := recco[i]; ok {
+			es := math.Abs(v) * value
+			p.ReccoES = &es
+			p.ReccoGap = gap(es, p.LiquidityStandaloneES)
+			add(&report.ReccoES, es)
+		}
+	}
+}
+
+// reconcilePortfolioES reconciles the portfolio ES of Recco, relative to the
+// value of the portfolio, with the local liquidity ES, and notes the measures
+// left unreconciled. reccoErr is the error of the Recco request, if any.
+func reconcilePortfolioES(report *PortfolioReport, recco float64, reccoErr error) {
+	report.Reconciliation = append(report.Reconciliation,
+		"arcanist: no portfolio-level measure, positions are priced on their own and their sum is reconciled with standaloneES")
+
+	if reccoErr != nil {
+		report.Reconciliation = append(report.Reconciliation,
+			fmt.Sprintf("recco: no portfolio-level measure, liquidityES is unreconciled: %v", reccoErr))
+
+		return
+	}
+
+	es := math.Abs(recco) * math.Abs(report.Value)
+	report.ReccoPortfolioES = &es
+	if report.LiquidityES != 0 {
+		g := es/report.LiquidityES - 1
+		report.ReccoPortfolioGap = &g
+	}
+}
+
+// writePortfolio writes the report as JSON, or as a CSV of the positions
+// followed by a portfolio row.
+func writePortfolio(path string, report PortfolioReport) error {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		raw, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not marshal the portfolio report: %w", err)
+		}
+
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			return fmt.Errorf("could not write %s: %w", path, err)
+		}
+
+		return nil
+	}
+
+	csvFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer csvFile.Close()
+
+	csvwriter := csv.NewWriter(csvFile)
+	_ = csvwriter.Write([]string{
+		"index", "id", "currency", "quantity", "value", "horizon",
+		"esStandalone", "esComponent", "esMarginal", "share", "esLiquidityStandalone", "esLiquidityComponent",
+		"esMarketArcanist", "esLiquidityArcanist", "esRecco", "gapMarketArcanist", "gapLiquidityArcanist", "gapRecco",
+		"esReccoPortfolio", "gapReccoPortfolio", "reconciliation",
+	})
+
+	for _, p := range report.Positions {
+		_ = csvwriter.Write([]string{
+			strconv.Itoa(p.Index), p.ID, p.Currency, formatFloat(&p.Quantity), formatFloat(&p.Value), strconv.Itoa(p.Horizon),
+			formatFloat(&p.StandaloneES), formatFloat(&p.ComponentES), formatFloat(&p.MarginalES), formatFloat(&p.Share),
+			formatFloat(&p.LiquidityStandaloneES), formatFloat(&p.LiquidityComponentES),
+			formatFloat(p.ArcanistMarketES), formatFloat(p.ArcanistLiquidityES), formatFloat(p.ReccoES),
+			formatFloat(p.ArcanistMarketGap), formatFloat(p.ArcanistLiquidityGap), formatFloat(p.ReccoGap),
+			"", "", "",
+		})
+	}
+
+	one := 1.0
+	_ = csvwriter.Write([]string{
+		"", "(portfolio)", strings.Join(report.Currencies, " "), "", formatFloat(&report.Value), "",
+		formatFloat(&report.StandaloneES), formatFloat(&report.MarketES), "", formatFloat(&one),
+		formatFloat(&report.LiquidityStandaloneES), formatFloat(&report.LiquidityES),
+		formatFloat(report.ArcanistMarketES), formatFloat(report.ArcanistLiquidityES), formatFloat(report.ReccoES),
+		"", "", "",
+		formatFloat(report.ReccoPortfolioES), formatFloat(report.ReccoPortfolioGap), strings.Join(report.Reconciliation, "; "),
+	})
+
+	csvwriter.Flush()
+	if err := csvwriter.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package liquidityvalidation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"toolkit/input"
+)
+
+func Test_portfolioES(t *testing.T) {
+	t.Parallel()
+
+	// Two assets of NPV 100 moving in opposite directions half of the time.
+	results := map[string]scenarioResult{"a": {}, "b": {}}
+	for id := range results {
+		r := scenarioResult{Scenarios: make(map[uint32]scenarioValue, nbScenarios)}
+		r.Main.NPV.Value = 100
+		results[id] = r
+	}
+	for i := 0; i < nbScenarios; i++ {
+		move := float64(i%20) - 10
+		other := move
+		if i%2 == 0 {
+			other = -move
+		}
+		results["a"].Scenarios[uint32(firstScenarioID+i)] = scenarioValue{Value: 100 + move, Status: "Success"}
+		results["b"].Scenarios[uint32(firstScenarioID+i)] = scenarioValue{Value: 100 + other, Status: "Success"}
+	}
+	results["b"].Scenarios[uint32(firstScenarioID)] = scenarioValue{Status: "Failed"}
+
+	positions := []input.Position{{ID: "a", Currency: "USD", Quantity: 2}, {ID: "b", Currency: "USD", Quantity: 1}}
+	report, err := portfolioES(positions, map[string]int{"a": 120, "b": 30}, results)
+	require.NoError(t, err)
+	assert.Equal(t, nbScenarios-1, report.Scenarios)
+	assert.Equal(t, 1, report.Excluded)
+	assert.Equal(t, 300.0, report.Value)
+
+	sum, liquiditySum := 0.0, 0.0
+	for _, p := range report.Positions {
+		sum += p.ComponentES
+		liquiditySum += p.LiquidityComponentES
+		assert.InDelta(t, p.ComponentES/p.Quantity, p.MarginalES, 1e-12)
+	}
+	assert.InDelta(t, report.MarketES, sum, 1e-9)
+	assert.InDelta(t, report.LiquidityES, liquiditySum, 1e-9)
+	assert.Greater(t, report.Diversification, 0.0)
+	assert.InDelta(t, report.StandaloneES-report.MarketES, report.Diversification, 1e-12)
+
+	// Position a doubles with its 120-day horizon, b stays.
+	assert.InDelta(t, 2*report.Positions[0].StandaloneES, report.Positions[0].LiquidityStandaloneES, 1e-9)
+	assert.InDelta(t, report.Positions[1].StandaloneES, report.Positions[1].LiquidityStandaloneES, 1e-9)
+
+	reconcilePortfolio(&report, map[int]float64{0: -0.1}, nil, map[int]float64{1: 0.1})
+	assert.InDelta(t, 20.0, *report.Positions[0].ArcanistMarketES, 1e-12)
+	assert.InDelta(t, 20/report.Positions[0].StandaloneES-1, *report.Positions[0].ArcanistMarketGap, 1e-12)
+	assert.InDelta(t, 20.0, *report.ArcanistMarketES, 1e-12)
+	assert.Nil(t, report.ArcanistLiquidityES)
+	assert.InDelta(t, 10.0, *report.ReccoES, 1e-12)
+
+	reconcilePortfolioES(&report, -0.05, nil)
+	assert.InDelta(t, 15.0, *report.ReccoPortfolioES, 1e-12)
+	assert.InDelta(t, 15/report.LiquidityES-1, *report.ReccoPortfolioGap, 1e-12)
+	assert.Len(t, report.Reconciliation, 1)
+
+	unreconciled := PortfolioReport{}
+	reconcilePortfolioES(&unreconciled, 0, errors.New("unexpected status code: 404"))
+	assert.Nil(t, unreconciled.ReccoPortfolioES)
+	assert.Len(t, unreconciled.Reconciliation, 2)
+	assert.Contains(t, unreconciled.Reconciliation[1], "liquidityES is unreconciled")
+
+	_, err = portfolioES([]input.Position{{ID: "c", Quantity: 1}}, nil, results)
+	assert.ErrorContains(t, err, "no scenario values")
+}
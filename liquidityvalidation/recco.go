@@ -1,13 +1,13 @@
-package main
+package liquidityvalidation
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
+	"strings"
 )
 
 type ReccoRequestInput struct {
@@ -56,13 +56,22 @@ type ReccoStatus struct {
 	Messages []string `json:"messages"`
 }
 
-const (
+// quantityScheme is the amount scheme of positions given in quantities.
+const quantityScheme = "quantity"
+
+var (
 	reccoUrl     = "https://api.dev.edge-lab.ch/recco/v2/risk-measures/es/granularities/positions"
 	reccoUrlProd = "https://api.edgelab.ch/recco/v2/risk-measures/es/granularities/positions"
 
+	reccoPortfolioUrl     = "https://api.dev.edge-lab.ch/recco/v2/risk-measures/es/granularities/portfolio"
+	reccoPortfolioUrlProd = "https://api.edgelab.ch/recco/v2/risk-measures/es/granularities/portfolio"
+
 	measureType  = "relative"
 	currency     = "local"
-	amountScheme = "quantity"
+	amountScheme = quantityScheme
+
+	reccoTimeHorizon  = 30
+	reccoScenarioType = "historicalInnovations"
 )
 
 func requestRecco(ids []string) (map[string]float64, error) {
@@ -72,11 +81,11 @@ func requestRecco(ids []string) (map[string]float64, error) {
 	maxNumber := len(ids)
 
 	counter := 0
-	partialIDs := make([]string, 0, 50)
+	partialIDs := make([]string, 0, batchSize)
 	for i, id := range ids {
 		partialIDs = append(partialIDs, id)
 		counter++
-		if len(partialIDs) < 50 && i != maxNumber-1 {
+		if len(partialIDs) < batchSize && i != maxNumber-1 {
 			continue
 		}
 
@@ -89,7 +98,7 @@ func requestRecco(ids []string) (map[string]float64, error) {
 			output[k] = v
 		}
 
-		partialIDs = make([]string, 0, 50)
+		partialIDs = make([]string, 0, batchSize)
 
 		log.Printf("Processed Recco %d/%d assets (%f%%)", counter, maxNumber, float64(counter)/float64(maxNumber)*100)
 	}
@@ -108,35 +117,89 @@ func requestReccoPartial(ctx context.Context, ids []string) (map[string]float64,
 		})
 	}
 
+	return requestReccoPositions(ctx, positions)
+}
+
+// requestReccoPositions returns the ES of the positions by key, leaving out
+// those not in success.
+func requestReccoPositions(ctx context.Context, positions []ReccoPosition) (map[string]float64, error) {
+	return requestReccoMeasure(ctx, confidenceLevel, reccoTimeHorizon, amountScheme, positions)
+}
+
+// requestReccoQuantities is requestReccoPositions for positions whose amounts
+// are quantities whatever the amount scheme of the flags.
+func requestReccoQuantities(ctx context.Context, positions []ReccoPosition) (map[string]float64, error) {
+	return requestReccoMeasure(ctx, confidenceLevel, reccoTimeHorizon, quantityScheme, positions)
+}
+
+// requestReccoMeasure returns the ES of the positions at the confidence level
+// and time horizon, their amounts in the scheme, by key, leaving out those not
+// in success.
+func requestReccoMeasure(ctx context.Context, confidence float64, timeHorizon int, scheme string, positions []ReccoPosition) (map[string]float64, error) {
+	url := reccoUrl
+	if environment == "PROD" {
+		url = reccoUrlProd
+	}
+
+	raw, err := sendRecco(ctx, url, confidence, timeHorizon, scheme, positions)
+	if err != nil {
+		return nil, err
+	}
+
+	outputMap, failures, err := decodeReccoResults(raw, positions)
+	if err != nil {
+		return nil, err
+	}
+	positionFailures.add(failures...)
+
+	return outputMap, nil
+}
+
+// requestReccoPortfolio returns the ES of the positions as a whole, from the
+// portfolio granularity of Recco, their amounts being quantities.
+func requestReccoPortfolio(ctx context.Context, positions []ReccoPosition) (float64, error) {
+	url := reccoPortfolioUrl
+	if environment == "PROD" {
+		url = reccoPortfolioUrlProd
+	}
+
+	raw, err := sendRecco(ctx, url, confidenceLevel, reccoTimeHorizon, quantityScheme, positions)
+	if err != nil {
+		return 0, err
+	}
+
+	return decodeReccoPortfolio(raw)
+}
+
+// sendRecco sends the positions, their amounts in the scheme, to a granularity
+// of Recco and returns the raw answer.
+func sendRecco(ctx context.Context, url string, confidence float64, timeHorizon int, scheme string, positions []ReccoPosition) ([]byte, error) {
 	input := ReccoRequestInput{
 		Context: ReccoContext{
 			MeasureType:       measureType,
-			ConfidenceLevel:   confidenceLevel,
+			ConfidenceLevel:   confidence,
 			LiquidityAdjusted: true,
 		},
 		Scenarios: ReccoScenarios{
-			TimeHorizon: 30,
-			Type:        "historicalInnovations",
+			TimeHorizon: timeHorizon,
+			Type:        reccoScenarioType,
 		},
 		Portfolio: ReccoPortfolio{
 			Currency:     currency,
-			AmountScheme: "quantity",
+			AmountScheme: scheme,
 			Positions:    positions,
 		},
 	}
 
+	if err := validateReccoRequest(input); err != nil {
+		return nil, err
+	}
+
 	body, err := json.Marshal(input)
 	if err != nil {
 		return nil, fmt.Errorf("could not marshal the liquidity request: %w", err)
 	}
 
-	// fmt.Println(string(body))
-
-	url := reccoUrl
-	if environment == "PROD" {
-		url = reccoUrlProd
-	}
-
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("could not create the request: %w", err)
@@ -151,36 +214,79 @@ func requestReccoPartial(ctx context.Context, ids []string) (map[string]float64,
 		req.Header.Set("Authorization", "Bearer "+tokenDEV)
 	}
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	// Recco prices on its latest snapshot, so its answers only live for a while.
+	status, raw, err := sendRequest(req, "recco", "", body, liveTTL)
 	if err != nil {
-		return nil, fmt.Errorf("could not send the request: %w", err)
+		return nil, err
 	}
 
-	defer res.Body.Close()
-
-	raw, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, fmt.Errorf("could not read response body: %w", err)
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", status)
 	}
 
-	if s := res.StatusCode; s != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", s)
-	}
+	return raw, nil
+}
 
+// decodeReccoResults returns the ES of the positions in success by key, and a
+// failure with the status of every other position. Positions missing from the
+// response fail with code 0.
+func decodeReccoResults(raw []byte, positions []ReccoPosition) (map[string]float64, []positionFailure, error) {
 	var output ReccoOutput
 	if err := json.Unmarshal(raw, &output); err != nil {
-		return nil, fmt.Errorf("could not unmarshal the response: %w", err)
+		return nil, nil, fmt.Errorf("could not unmarshal the response: %w", err)
+	}
+
+	assets := make(map[string]string, len(positions))
+	for _, p := range positions {
+		assets[p.Key] = p.Asset
 	}
 
 	outputMap := make(map[string]float64, len(output.Results))
+	failures := make([]positionFailure, 0)
+	answered := make(map[string]bool, len(output.Results))
 	for _, o := range output.Results {
-		if o.Status.Code != 200 {
+		answered[o.Key] = true
+
+		if o.Status.Code != http.StatusOK {
+			failures = append(failures, positionFailure{
+				Service:  "recco",
+				Asset:    assets[o.Key],
+				Code:     fmt.Sprintf("%d", o.Status.Code),
+				Key:      o.Status.Key,
+				Messages: o.Status.Messages,
+			})
+
 			continue
 		}
 
 		outputMap[o.Key] = o.Value
 	}
 
-	return outputMap, nil
+	for _, p := range positions {
+		if !answered[p.Key] {
+			failures = append(failures, positionFailure{Service: "recco", Asset: p.Asset, Code: "0", Key: "missing"})
+		}
+	}
+
+	return outputMap, failures, nil
+}
+
+// decodeReccoPortfolio returns the ES of the single result of a portfolio
+// granularity answer.
+func decodeReccoPortfolio(raw []byte) (float64, error) {
+	var output ReccoOutput
+	if err := json.Unmarshal(raw, &output); err != nil {
+		return 0, fmt.Errorf("could not unmarshal the response: %w", err)
+	}
+
+	if len(output.Results) != 1 {
+		return 0, fmt.Errorf("expected a single portfolio result, got %d", len(output.Results))
+	}
+
+	o := output.Results[0]
+	if o.Status.Code != http.StatusOK {
+		return 0, fmt.Errorf("portfolio failed with code %d (%s): %s", o.Status.Code, o.Status.Key, strings.Join(o.Status.Messages, "; "))
+	}
+
+	return o.Value, nil
 }
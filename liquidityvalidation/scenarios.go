@@ -0,0 +1,299 @@
+package liquidityvalidation
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"toolkit/risk"
+)
+
+// Scenario mode: the ES is recomputed locally from the scenario values of the
+// Eve results, either priced from the Adam dumps or read from fixtures named
+// <asset ID>.json.
+var (
+	scenarioMode       = false
+	scenarioFixtures   = ""
+	scenarioOutputPath = "scenario-es.csv"
+)
+
+// scenarioResult is the part of an Eve result the ES is recomputed from.
+type scenarioResult struct {
+	Main struct {
+		NPV scenarioValue `json:"NPV"`
+	} `json:"main"`
+	Scenarios map[uint32]scenarioValue `json:"scenarios"`
+	Liquidity struct {
+		Horizon scenarioValue `json:"horizon"`
+	} `json:"liquidity"`
+}
+
+type scenarioValue struct {
+	Value  float64 `json:"value"`
+	Status string  `json:"status"`
+}
+
+// scenarioOutput compares the local ES of an asset with and without
+// liquidity-horizon scaling to Arcanist and Recco. ES are positive losses
+// relative to the NPV, like the Arcanist RELATIVE ones.
+//
+// The gap between the Arcanist liquidity ES and the local market ES splits
+// into the market gap (Arcanist market ES against the local one), the local
+// uplift of the horizon scaling and the uplift Arcanist adds on top of it:
+//
+//	arcanistLiquidityES - marketES = marketGap + uplift + unexplainedUplift
+type scenarioOutput struct {
+	ID        string
+	Horizon   int
+	Scenarios int
+	Excluded  int
+
+	// Scaling is sqrt(max(horizon, scenarioHorizon) / scenarioHorizon).
+	Scaling     float64
+	MarketES    float64
+	LiquidityES float64
+	Uplift      float64
+
+	ArcanistMarketES    *float64
+	ArcanistLiquidityES *float64
+	ReccoES             *float64
+
+	MarketGap         *float64
+	UnexplainedUplift *float64
+
+	// ImpliedHorizon is the horizon for which the square-root scaling of the
+	// Arcanist market ES gives the Arcanist liquidity ES.
+	ImpliedHorizon *float64
+}
+
+// loadScenarioResults returns the Eve results with scenario values of the
+// assets, from the fixtures when set and otherwise by pricing the Adam dumps.
+// Assets without result are logged and left out.
+func loadScenarioResults(outputMD []liquidityOutput, requests []Request) map[string]scenarioResult {
+	results := make(map[string]scenarioResult, len(outputMD))
+
+	if scenarioFixtures != "" {
+		for _, md := range outputMD {
+			raw, err := os.ReadFile(filepath.Join(scenarioFixtures, md.id+".json"))
+			if err != nil {
+				log.Infof("No scenario fixture for %s: %v", md.id, err)
+
+				continue
+			}
+
+			result, err := parseScenarioResult(raw)
+			if err != nil {
+				log.Infof("Invalid scenario fixture for %s: %v", md.id, err)
+
+				continue
+			}
+
+			results[md.id] = result
+		}
+
+		return results
+	}
+
+	ctx := context.Background()
+	for _, request := range requests {
+		// Horizons were priced from the same payloads, so Eve answers from the
+		// response cache.
+		raw, err := makeRequestEve(ctx, request.Payload)
+		if err != nil {
+			log.Infof("Error while pricing the scenarios of %s: %v", request.ID, err)
+
+			continue
+		}
+
+		result, err := parseScenarioResult(raw)
+		if err != nil {
+			log.Infof("Invalid Eve result for %s: %v", request.ID, err)
+
+			continue
+		}
+
+		results[request.ID] = result
+	}
+
+	return results
+}
+
+// parseScenarioResult reads an Eve result, as answered by the debug path or
+// wrapped under "results" as in the stored pricing results.
+func parseScenarioResult(raw []byte) (scenarioResult, error) {
+	var wrapped struct {
+		Results *scenarioResult `json:"results"`
+	}
+	if err := json.Unmarshal(raw, &wrapped); err != nil {
+		return scenarioResult{}, fmt.Errorf("could not unmarshal the result: %w", err)
+	}
+
+	result := wrapped.Results
+	if result == nil {
+		result = &scenarioResult{}
+		if err := json.Unmarshal(raw, result); err != nil {
+			return scenarioResult{}, fmt.Errorf("could not unmarshal the result: %w", err)
+		}
+	}
+
+	if len(result.Scenarios) == 0 {
+		return scenarioResult{}, errors.New("no scenario values")
+	}
+
+	return *result, nil
+}
+
+// localES recomputes the market and liquidity ES of a result over the Arcanist
+// scenarios, at the Arcanist confidence level. Scenarios not in Success are
+// left out.
+func localES(id string, result scenarioResult, horizon int) (scenarioOutput, error) {
+	npv := result.Main.NPV.Value
+	if npv == 0 {
+		return scenarioOutput{}, errors.New("relative P&L against a zero NPV")
+	}
+
+	output := scenarioOutput{ID: id, Horizon: horizon}
+
+	obs := make([]risk.Observation, 0, nbScenarios)
+	for i := 0; i < nbScenarios; i++ {
+		id := uint32(firstScenarioID + i)
+
+		scenario, ok := result.Scenarios[id]
+		if !ok || scenario.Status != "Success" {
+			output.Excluded++
+
+			continue
+		}
+
+		obs = append(obs, risk.Observation{ID: id, PnL: scenario.Value/npv - 1, Weight: 1})
+	}
+	output.Scenarios = len(obs)
+
+	measures, err := risk.Compute(obs, confidenceLevel)
+	if err != nil {
+		return scenarioOutput{}, fmt.Errorf("could not compute the ES: %w", err)
+	}
+
+	output.Scaling = horizonScaling(horizon)
+	output.MarketES = -measures.ES
+	output.LiquidityES = output.MarketES * output.Scaling
+	output.Uplift = output.LiquidityES - output.MarketES
+
+	return output, nil
+}
+
+// horizonScaling is the square-root scaling of a scenario P&L to the liquidity
+// horizon. A horizon under the scenario horizon does not shrink the P&L.
+func horizonScaling(horizon int) float64 {
+	return math.Sqrt(math.Max(float64(horizon), scenarioHorizon) / scenarioHorizon)
+}
+
+// compareScenarioES recomputes the ES of every asset with a result and
+// decomposes it against the Arcanist and Recco answers.
+func compareScenarioES(outputMD []liquidityOutput, results map[string]scenarioResult, outputArcanist, outputArcanistMD, outputRecco map[string]float64) []scenarioOutput {
+	outputs := make([]scenarioOutput, 0, len(results))
+	for _, md := range outputMD {
+		result, ok := results[md.id]
+		if !ok {
+			continue
+		}
+
+		output, err := localES(md.id, result, md.horizon)
+		if err != nil {
+			log.Infof("Could not recompute the ES of %s: %v", md.id, err)
+
+			continue
+		}
+
+		if v, ok := outputArcanistMD[md.id]; ok {
+			v = math.Abs(v)
+			gap := v - output.MarketES
+			output.ArcanistMarketES = &v
+			output.MarketGap = &gap
+		}
+
+		if v, ok := outputArcanist[md.id]; ok {
+			v = math.Abs(v)
+			output.ArcanistLiquidityES = &v
+
+			if output.ArcanistMarketES != nil {
+				unexplained := v - *output.ArcanistMarketES - output.Uplift
+				output.UnexplainedUplift = &unexplained
+
+				if *output.ArcanistMarketES > 0 {
+					ratio := v / *output.ArcanistMarketES
+					implied := scenarioHorizon * ratio * ratio
+					output.ImpliedHorizon = &implied
+				}
+			}
+		}
+
+		if v, ok := outputRecco[md.id]; ok {
+			v = math.Abs(v)
+			output.ReccoES = &v
+		}
+
+		outputs = append(outputs, output)
+	}
+
+	return outputs
+}
+
+func scenarioToCsv(path string, outputs []scenarioOutput) error {
+	csvFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer csvFile.Close()
+
+	csvwriter := csv.NewWriter(csvFile)
+	_ = csvwriter.Write([]string{
+		"id", "horizon", "scenarios", "excluded", "scaling",
+		"esMarketLocal", "esLiquidityLocal", "upliftLocal",
+		"esMarketArcanist", "esLiquidityArcanist", "esRecco",
+		"marketGap", "unexplainedUplift", "impliedHorizon",
+	})
+
+	for _, o := range outputs {
+		_ = csvwriter.Write([]string{
+			o.ID,
+			strconv.Itoa(o.Horizon),
+			strconv.Itoa(o.Scenarios),
+			strconv.Itoa(o.Excluded),
+			formatFloat(&o.Scaling),
+			formatFloat(&o.MarketES),
+			formatFloat(&o.LiquidityES),
+			formatFloat(&o.Uplift),
+			formatFloat(o.ArcanistMarketES),
+			formatFloat(o.ArcanistLiquidityES),
+			formatFloat(o.ReccoES),
+			formatFloat(o.MarketGap),
+			formatFloat(o.UnexplainedUplift),
+			formatFloat(o.ImpliedHorizon),
+		})
+	}
+
+	csvwriter.Flush()
+	if err := csvwriter.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// formatFloat formats an optional value, empty when missing.
+func formatFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
@@ -0,0 +1,74 @@
+package liquidityvalidation
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_localES(t *testing.T) {
+	t.Parallel()
+
+	// Relative P&L of -1%, -2%, ..., -500% over the default 500 scenarios,
+	// scenario 6500 failed.
+	scenarios := make([]string, 0, nbScenarios)
+	for i := 0; i < nbScenarios; i++ {
+		status := "Success"
+		if i == 0 {
+			status = "Failed"
+		}
+		scenarios = append(scenarios, fmt.Sprintf(`"%d": {"value": %v, "status": %q}`, firstScenarioID+i, 100-float64(i+1), status))
+	}
+	raw := `{"results": {"main": {"NPV": {"value": 100, "status": "Success"}}, "scenarios": {` + strings.Join(scenarios, ",") + `}}}`
+
+	result, err := parseScenarioResult([]byte(raw))
+	require.NoError(t, err)
+
+	// Tail of the 49.9 worst of 499 scenarios: -500% to -452%, and 0.9 of -451%.
+	output, err := localES("a", result, 120)
+	require.NoError(t, err)
+	assert.Equal(t, 499, output.Scenarios)
+	assert.Equal(t, 1, output.Excluded)
+	assert.InDelta(t, 2.0, output.Scaling, 1e-12)
+	assert.InDelta(t, (49*4.76+0.9*4.51)/49.9, output.MarketES, 1e-9)
+	assert.InDelta(t, output.MarketES, output.Uplift, 1e-12)
+
+	// A horizon under the scenario horizon does not shrink the ES.
+	output, err = localES("a", result, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, output.Scaling)
+	assert.Zero(t, output.Uplift)
+
+	_, err = parseScenarioResult([]byte(`{"liquidity": {"horizon": {"value": 3}}}`))
+	assert.Error(t, err)
+}
+
+func Test_compareScenarioES(t *testing.T) {
+	t.Parallel()
+
+	result := scenarioResult{Scenarios: map[uint32]scenarioValue{}}
+	result.Main.NPV.Value = 1
+	for i := 0; i < nbScenarios; i++ {
+		result.Scenarios[uint32(firstScenarioID+i)] = scenarioValue{Value: 1 - 0.001*float64(i%10), Status: "Success"}
+	}
+
+	outputs := compareScenarioES(
+		[]liquidityOutput{{id: "a", horizon: 120}, {id: "b", horizon: 30}},
+		map[string]scenarioResult{"a": result},
+		map[string]float64{"a": -0.02},
+		map[string]float64{"a": -0.008},
+		map[string]float64{"a": 0.019},
+	)
+	require.Len(t, outputs, 1)
+
+	o := outputs[0]
+	assert.InDelta(t, 0.009, o.MarketES, 1e-12)
+	assert.InDelta(t, -0.001, *o.MarketGap, 1e-12)
+	assert.InDelta(t, 0.003, *o.UnexplainedUplift, 1e-12)
+	assert.InDelta(t, 0.02-o.MarketES, *o.MarketGap+o.Uplift+*o.UnexplainedUplift, 1e-12)
+	assert.InDelta(t, 30*2.5*2.5, *o.ImpliedHorizon, 1e-9)
+	assert.InDelta(t, 0.019, *o.ReccoES, 1e-12)
+}
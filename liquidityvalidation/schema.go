@@ -0,0 +1,259 @@
+package liquidityvalidation
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Values of the Arcanist and Recco payloads known to the validation. Requests
+// with other values are rejected before being sent.
+var (
+	arcanistMetrics       = []string{"ES", "VaR"}
+	arcanistMetricUnits   = []string{"RELATIVE", "ABSOLUTE"}
+	arcanistQuantityUnits = []string{"ABSOLUTE", "RELATIVE"}
+	arcanistRiskTypes     = []string{"MARKET", "MARKET_LIQUIDITY"}
+
+	reccoMeasureTypes    = []string{"relative", "absolute"}
+	reccoAmountSchemes   = []string{"quantity", "amount"}
+	reccoIdentifierTypes = []string{"id", "isin"}
+)
+
+// failuresOutputPath is the CSV of the positions a service answered without
+// a result, none if empty. The failures are logged either way.
+var failuresOutputPath = ""
+
+// Failure codes of the Arcanist results, which carry no code of their own.
+const (
+	arcanistNoResult = "NO_RESULT"
+	arcanistMissing  = "MISSING"
+)
+
+// positionFailure is a position a service answered without a result.
+type positionFailure struct {
+	Service  string
+	Asset    string
+	Code     string
+	Key      string
+	Messages []string
+}
+
+// failureLog collects the position failures of a run. It is safe for
+// concurrent use.
+type failureLog struct {
+	mu       sync.Mutex
+	failures []positionFailure
+}
+
+// positionFailures collects the failures of every Arcanist and Recco request.
+var positionFailures = &failureLog{}
+
+func (l *failureLog) add(failures ...positionFailure) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.failures = append(l.failures, failures...)
+}
+
+// byCode returns the failures grouped by service and code, sorted.
+func (l *failureLog) byCode() map[string][]positionFailure {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	groups := make(map[string][]positionFailure)
+	for _, f := range l.failures {
+		group := f.Service + " " + f.Code
+		groups[group] = append(groups[group], f)
+	}
+
+	return groups
+}
+
+// logFailures logs the number of failures per service and code.
+func logFailures() {
+	groups := positionFailures.byCode()
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := groups[name][0]
+		log.Warnf("%s: %d positions failed with code %s (%s), e.g. %s: %s",
+			f.Service, len(groups[name]), f.Code, f.Key, f.Asset, strings.Join(f.Messages, "; "))
+	}
+}
+
+// writeFailures writes the failures grouped by service and code, with the
+// size of the group on every row.
+func writeFailures(path string) error {
+	groups := positionFailures.byCode()
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	csvFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer csvFile.Close()
+
+	csvwriter := csv.NewWriter(csvFile)
+	_ = csvwriter.Write([]string{"service", "code", "count", "key", "asset", "messages"})
+	for _, name := range names {
+		group := groups[name]
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].Asset < group[j].Asset
+		})
+
+		for _, f := range group {
+			_ = csvwriter.Write([]string{f.Service, f.Code, fmt.Sprintf("%d", len(group)), f.Key, f.Asset, strings.Join(f.Messages, "; ")})
+		}
+	}
+
+	csvwriter.Flush()
+	if err := csvwriter.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// reportFailures logs the failures and writes them to failuresOutputPath,
+// returning the outputs written.
+func reportFailures() []string {
+	logFailures()
+
+	if failuresOutputPath == "" {
+		return nil
+	}
+
+	if err := writeFailures(failuresOutputPath); err != nil {
+		log.Errorf("Error writing the failures: %v", err)
+
+		return nil
+	}
+
+	return []string{failuresOutputPath}
+}
+
+// validateArcanistRequest checks the payload before it is sent.
+func validateArcanistRequest(input ArcanistRequestInput) error {
+	var errs []error
+
+	c := input.Context
+	errs = append(errs,
+		oneOf("metric", c.Metric, arcanistMetrics),
+		oneOf("metric unit", c.MetricUnit, arcanistMetricUnits),
+		oneOf("risk type", c.RiskType, arcanistRiskTypes),
+		oneOf("quantity unit", input.QuantityUnit, arcanistQuantityUnits),
+		confidence(c.ConfidenceLevel),
+	)
+
+	if c.Snapshot == "" {
+		errs = append(errs, errors.New("no snapshot"))
+	}
+	if c.MetricCurrency == "" {
+		errs = append(errs, errors.New("no metric currency"))
+	}
+	if len(c.Scenarios) == 0 {
+		errs = append(errs, errors.New("no scenario"))
+	}
+	if c.TimeHorizon.ScenarioHorizon.Value <= 0 {
+		errs = append(errs, fmt.Errorf("scenario horizon %v is not positive", c.TimeHorizon.ScenarioHorizon.Value))
+	}
+
+	if len(input.Positions) == 0 {
+		errs = append(errs, errors.New("no position"))
+	}
+	for i, p := range input.Positions {
+		if p.Asset == "" {
+			errs = append(errs, fmt.Errorf("position %d has no asset", i))
+		}
+		if p.Currency == "" {
+			errs = append(errs, fmt.Errorf("position %d has no currency", i))
+		}
+		if p.Liquidity < 0 {
+			errs = append(errs, fmt.Errorf("position %d has a negative liquidity horizon %v", i, p.Liquidity))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid Arcanist request: %w", err)
+	}
+
+	return nil
+}
+
+// validateReccoRequest checks the payload before it is sent.
+func validateReccoRequest(input ReccoRequestInput) error {
+	var errs []error
+
+	errs = append(errs,
+		oneOf("measure type", input.Context.MeasureType, reccoMeasureTypes),
+		oneOf("amount scheme", input.Portfolio.AmountScheme, reccoAmountSchemes),
+		confidence(input.Context.ConfidenceLevel),
+	)
+
+	if input.Scenarios.TimeHorizon <= 0 {
+		errs = append(errs, fmt.Errorf("time horizon %d is not positive", input.Scenarios.TimeHorizon))
+	}
+	if input.Scenarios.Type == "" {
+		errs = append(errs, errors.New("no scenario type"))
+	}
+	if input.Portfolio.Currency == "" {
+		errs = append(errs, errors.New("no portfolio currency"))
+	}
+
+	if len(input.Portfolio.Positions) == 0 {
+		errs = append(errs, errors.New("no position"))
+	}
+	keys := make(map[string]bool, len(input.Portfolio.Positions))
+	for i, p := range input.Portfolio.Positions {
+		if p.Asset == "" {
+			errs = append(errs, fmt.Errorf("position %d has no asset", i))
+		}
+		if err := oneOf(fmt.Sprintf("identifier type of position %d", i), p.IdentifierType, reccoIdentifierTypes); err != nil {
+			errs = append(errs, err)
+		}
+		if p.Key == "" || keys[p.Key] {
+			errs = append(errs, fmt.Errorf("position %d has an empty or duplicate key %q", i, p.Key))
+		}
+		keys[p.Key] = true
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid Recco request: %w", err)
+	}
+
+	return nil
+}
+
+func oneOf(name, value string, allowed []string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s %q is not one of %s", name, value, strings.Join(allowed, ", "))
+}
+
+func confidence(level float64) error {
+	if level <= 0 || level >= 1 {
+		return fmt.Errorf("confidence level %v is not in (0, 1)", level)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package liquidityvalidation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_decodeArcanistResults(t *testing.T) {
+	t.Parallel()
+
+	positions := map[int]ArcanistPosition{0: {Asset: "A"}, 1: {Asset: "B"}, 2: {Asset: "C"}}
+	raw := []byte(`{"results": {
+		"0": {"result": -0.12},
+		"1": {"result": null, "status": "MISSING_MARKET_DATA", "error": "no price", "messages": ["since 2024-10-01"]}
+	}}`)
+
+	results, failures, err := decodeArcanistResults(raw, positions)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]float64{0: -0.12}, results)
+	assert.Equal(t, []positionFailure{
+		{Service: "arcanist", Asset: "B", Code: "MISSING_MARKET_DATA", Key: "1", Messages: []string{"no price", "since 2024-10-01"}},
+		{Service: "arcanist", Asset: "C", Code: arcanistMissing, Key: "2"},
+	}, failures)
+}
+
+func Test_decodeReccoResults(t *testing.T) {
+	t.Parallel()
+
+	positions := []ReccoPosition{{Asset: "A", Key: "0"}, {Asset: "B", Key: "1"}, {Asset: "C", Key: "2"}}
+	raw := []byte(`{"results": [
+		{"key": "0", "value": 0.1, "status": {"code": 200}},
+		{"key": "1", "value": 0, "status": {"code": 404, "key": "ASSET_NOT_FOUND", "messages": ["unknown asset B"]}}
+	]}`)
+
+	results, failures, err := decodeReccoResults(raw, positions)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"0": 0.1}, results)
+	assert.Equal(t, []positionFailure{
+		{Service: "recco", Asset: "B", Code: "404", Key: "ASSET_NOT_FOUND", Messages: []string{"unknown asset B"}},
+		{Service: "recco", Asset: "C", Code: "0", Key: "missing"},
+	}, failures)
+
+	log := &failureLog{}
+	log.add(failures...)
+	assert.Len(t, log.byCode()["recco 404"], 1)
+}
+
+func Test_decodeReccoPortfolio(t *testing.T) {
+	t.Parallel()
+
+	es, err := decodeReccoPortfolio([]byte(`{"results": [{"key": "portfolio", "value": 0.08, "status": {"code": 200}}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, 0.08, es)
+
+	_, err = decodeReccoPortfolio([]byte(`{"results": [{"key": "portfolio", "status": {"code": 422, "key": "MIXED_CURRENCIES"}}]}`))
+	assert.ErrorContains(t, err, "MIXED_CURRENCIES")
+
+	_, err = decodeReccoPortfolio([]byte(`{"results": []}`))
+	assert.ErrorContains(t, err, "single portfolio result")
+}
+
+func Test_validateReccoRequest(t *testing.T) {
+	t.Parallel()
+
+	input := ReccoRequestInput{
+		Context:   ReccoContext{MeasureType: "relative", ConfidenceLevel: 0.9},
+		Scenarios: ReccoScenarios{TimeHorizon: 30, Type: "historicalInnovations"},
+		Portfolio: ReccoPortfolio{
+			Currency:     "local",
+			AmountScheme: "quantity",
+			Positions:    []ReccoPosition{{Asset: "A", Amount: 1, IdentifierType: "id", Key: "A"}},
+		},
+	}
+	require.NoError(t, validateReccoRequest(input))
+
+	input.Context.MeasureType = "Relative"
+	input.Portfolio.AmountScheme = "shares"
+	input.Portfolio.Positions = append(input.Portfolio.Positions, ReccoPosition{Asset: "B", IdentifierType: "ticker", Key: "A"})
+	err := validateReccoRequest(input)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `measure type "Relative"`)
+	assert.ErrorContains(t, err, `amount scheme "shares"`)
+	assert.ErrorContains(t, err, `identifier type of position 1 "ticker"`)
+	assert.ErrorContains(t, err, `duplicate key "A"`)
+}
+
+func Test_validateArcanistRequest(t *testing.T) {
+	t.Parallel()
+
+	input := ArcanistRequestInput{
+		Context: ArcanistContext{
+			Snapshot: "latest", Metric: "ES", MetricUnit: "RELATIVE", MetricCurrency: "local", ConfidenceLevel: 0.9,
+			Scenarios:   map[string]ArcanistScenario{"6500": {ID: 6500, Weight: 1, Amplitude: 1}},
+			TimeHorizon: TimeHorizon{ScenarioHorizon: Value{Value: 30}},
+			RiskType:    "MARKET",
+		},
+		QuantityUnit: "ABSOLUTE",
+		Positions:    map[int]ArcanistPosition{0: {Asset: "A", Quantity: 1, Currency: "USD", Liquidity: 10}},
+	}
+	require.NoError(t, validateArcanistRequest(input))
+
+	input.Context.ConfidenceLevel = 90
+	input.Context.RiskType = "LIQUIDITY"
+	err := validateArcanistRequest(input)
+	assert.ErrorContains(t, err, "confidence level 90")
+	assert.ErrorContains(t, err, `risk type "LIQUIDITY"`)
+}
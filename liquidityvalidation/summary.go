@@ -0,0 +1,545 @@
+package liquidityvalidation
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Summary report of a run, written next to the output CSV when its paths are
+// given.
+var (
+	summaryOutputPath   = ""
+	summaryMarkdownPath = ""
+
+	outlierESGap   = 0.5
+	outlierHorizon = 10
+)
+
+// horizonBuckets are the horizon buckets of the confusion matrices, by upper
+// bound in days.
+var horizonBuckets = []struct {
+	name  string
+	upper int
+}{
+	{"0", 0},
+	{"1-5", 5},
+	{"6-10", 10},
+	{"11-20", 20},
+	{"21-30", 30},
+	{">30", math.MaxInt},
+}
+
+func horizonBucket(horizon int) string {
+	for _, b := range horizonBuckets {
+		if horizon <= b.upper {
+			return b.name
+		}
+	}
+
+	return horizonBuckets[len(horizonBuckets)-1].name
+}
+
+// assetRow gathers the answers of the services for an asset.
+type assetRow struct {
+	id        string
+	marketCap *float64
+	country   string
+
+	horizonMD        int
+	horizonPoC       *int
+	horizonNoVolumes *int
+	horizonVolumes   *int
+	horizonEstimate  *int
+
+	esArcanistLiquidity *float64
+	esArcanist          *float64
+	esRecco             *float64
+}
+
+// Distribution describes a set of values.
+type Distribution struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	P10    float64 `json:"p10"`
+	P90    float64 `json:"p90"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// ConfusionMatrix counts the assets per horizon bucket of a reference source
+// (rows) and of a compared one (columns).
+type ConfusionMatrix struct {
+	Reference string           `json:"reference"`
+	Compared  string           `json:"compared"`
+	Buckets   []string         `json:"buckets"`
+	Counts    map[string][]int `json:"counts"`
+
+	// Agreement is the share of assets in the same bucket.
+	Agreement float64 `json:"agreement"`
+}
+
+// GroupGap describes the relative gap |Arcanist| / |Recco| - 1 of the
+// liquidity ES over a group of assets.
+type GroupGap struct {
+	Group   string  `json:"group"`
+	Count   int     `json:"count"`
+	Mean    float64 `json:"mean"`
+	Median  float64 `json:"median"`
+	MeanAbs float64 `json:"meanAbs"`
+}
+
+// Outlier is an asset whose numbers disagree beyond the thresholds.
+type Outlier struct {
+	ID     string  `json:"id"`
+	Kind   string  `json:"kind"`
+	Value  float64 `json:"value"`
+	Detail string  `json:"detail"`
+}
+
+// Summary aggregates the per-asset output of a run.
+type Summary struct {
+	Assets int `json:"assets"`
+
+	// Horizons describes the horizons of every source with an answer.
+	Horizons map[string]Distribution `json:"horizons"`
+	Matrices []ConfusionMatrix       `json:"matrices"`
+
+	// Uplift is the relative ES uplift of the liquidity over the market
+	// Arcanist ES, UpliftAbs the absolute one.
+	Uplift    Distribution `json:"uplift"`
+	UpliftAbs Distribution `json:"upliftAbs"`
+
+	ByMarketCap []GroupGap `json:"byMarketCap"`
+	ByCountry   []GroupGap `json:"byCountry"`
+
+	Outliers []Outlier `json:"outliers"`
+}
+
+// Horizon sources.
+const (
+	sourceMD        = "horizonMD"
+	sourcePoC       = "horizonPoC"
+	sourceNoVolumes = "horizonNoVolumes"
+	sourceVolumes   = "horizonVolumes"
+	sourceEstimate  = "horizonVolumeEstimate"
+)
+
+var horizonSources = []string{sourceMD, sourcePoC, sourceNoVolumes, sourceVolumes, sourceEstimate}
+
+func (r assetRow) horizon(source string) *int {
+	switch source {
+	case sourceMD:
+		return &r.horizonMD
+	case sourcePoC:
+		return r.horizonPoC
+	case sourceNoVolumes:
+		return r.horizonNoVolumes
+	case sourceVolumes:
+		return r.horizonVolumes
+	case sourceEstimate:
+		return r.horizonEstimate
+	}
+
+	return nil
+}
+
+// assetRows joins the answers of the services per asset, in input order,
+// leaving out the assets Cerberus could not describe.
+func assetRows(outputMD []liquidityOutput, outputEve map[string]eveOutput, outputVolumes map[string]volumeEstimate, outputArcanist, outputArcanistMD, outputRecco map[string]float64) []assetRow {
+	optional := func(m map[string]float64, id string) *float64 {
+		v, ok := m[id]
+		if !ok {
+			return nil
+		}
+
+		return &v
+	}
+
+	rows := make([]assetRow, 0, len(outputMD))
+	for _, md := range outputMD {
+		if md.err != nil {
+			continue
+		}
+
+		row := assetRow{
+			id:                  md.id,
+			marketCap:           md.marketCap,
+			country:             md.country,
+			horizonMD:           md.horizon,
+			horizonPoC:          md.pocHorizon,
+			esArcanistLiquidity: optional(outputArcanist, md.id),
+			esArcanist:          optional(outputArcanistMD, md.id),
+			esRecco:             optional(outputRecco, md.id),
+		}
+
+		if eve, ok := outputEve[md.id]; ok {
+			noVolumes := eve.HorizonNoTradingVolumes
+			row.horizonNoVolumes = &noVolumes
+			row.horizonVolumes = eve.HorizonTradingVolumes
+		}
+
+		if estimate, ok := outputVolumes[md.id]; ok {
+			row.horizonEstimate = estimate.Horizon
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// summarize aggregates the rows.
+func summarize(rows []assetRow) Summary {
+	summary := Summary{Assets: len(rows), Horizons: make(map[string]Distribution)}
+
+	for _, source := range horizonSources {
+		values := make([]float64, 0, len(rows))
+		for _, r := range rows {
+			if h := r.horizon(source); h != nil {
+				values = append(values, float64(*h))
+			}
+		}
+
+		if len(values) > 0 {
+			summary.Horizons[source] = describe(values)
+		}
+	}
+
+	for _, compared := range []string{sourcePoC, sourceNoVolumes, sourceVolumes, sourceEstimate} {
+		summary.Matrices = append(summary.Matrices, confusionMatrix(rows, sourceMD, compared))
+	}
+
+	uplift := make([]float64, 0, len(rows))
+	upliftAbs := make([]float64, 0, len(rows))
+	byMarketCap := make(map[string][]float64)
+	byCountry := make(map[string][]float64)
+	for _, r := range rows {
+		if r.esArcanistLiquidity != nil && r.esArcanist != nil {
+			liquidity, market := math.Abs(*r.esArcanistLiquidity), math.Abs(*r.esArcanist)
+			upliftAbs = append(upliftAbs, liquidity-market)
+
+			if market > 0 {
+				u := liquidity/market - 1
+				uplift = append(uplift, u)
+
+				if u < 0 {
+					summary.Outliers = append(summary.Outliers, Outlier{
+						ID: r.id, Kind: "negativeUplift", Value: u,
+						Detail: fmt.Sprintf("liquidity ES %g under market ES %g", liquidity, market),
+					})
+				}
+			}
+		}
+
+		if r.esArcanistLiquidity != nil && r.esRecco != nil && *r.esRecco != 0 {
+			gap := math.Abs(*r.esArcanistLiquidity)/math.Abs(*r.esRecco) - 1
+			byMarketCap[marketCapBucket(r.marketCap)] = append(byMarketCap[marketCapBucket(r.marketCap)], gap)
+
+			country := r.country
+			if country == "" {
+				country = "unknown"
+			}
+			byCountry[country] = append(byCountry[country], gap)
+
+			if math.Abs(gap) > outlierESGap {
+				summary.Outliers = append(summary.Outliers, Outlier{
+					ID: r.id, Kind: "arcanistRecco", Value: gap,
+					Detail: fmt.Sprintf("Arcanist %g, Recco %g", *r.esArcanistLiquidity, *r.esRecco),
+				})
+			}
+		}
+
+		for _, compared := range []string{sourcePoC, sourceNoVolumes, sourceEstimate} {
+			h := r.horizon(compared)
+			if h == nil {
+				continue
+			}
+
+			if d := *h - r.horizonMD; d > outlierHorizon || d < -outlierHorizon {
+				summary.Outliers = append(summary.Outliers, Outlier{
+					ID: r.id, Kind: compared, Value: float64(d),
+					Detail: fmt.Sprintf("%s %d, horizonMD %d", compared, *h, r.horizonMD),
+				})
+			}
+		}
+	}
+
+	if len(uplift) > 0 {
+		summary.Uplift = describe(uplift)
+	}
+	if len(upliftAbs) > 0 {
+		summary.UpliftAbs = describe(upliftAbs)
+	}
+
+	bucketOrder := make([]string, 0, len(marketCapBuckets)+1)
+	for _, b := range marketCapBuckets {
+		bucketOrder = append(bucketOrder, b.name)
+	}
+	bucketOrder = append(bucketOrder, missingBucket)
+	summary.ByMarketCap = groupGaps(byMarketCap, bucketOrder)
+
+	countries := make([]string, 0, len(byCountry))
+	for c := range byCountry {
+		countries = append(countries, c)
+	}
+	sort.Strings(countries)
+	summary.ByCountry = groupGaps(byCountry, countries)
+
+	sort.SliceStable(summary.Outliers, func(i, j int) bool {
+		a, b := summary.Outliers[i], summary.Outliers[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+
+		return math.Abs(a.Value) > math.Abs(b.Value)
+	})
+
+	return summary
+}
+
+func confusionMatrix(rows []assetRow, reference, compared string) ConfusionMatrix {
+	m := ConfusionMatrix{Reference: reference, Compared: compared, Counts: make(map[string][]int)}
+
+	index := make(map[string]int, len(horizonBuckets))
+	for i, b := range horizonBuckets {
+		m.Buckets = append(m.Buckets, b.name)
+		m.Counts[b.name] = make([]int, len(horizonBuckets))
+		index[b.name] = i
+	}
+
+	total, same := 0, 0
+	for _, r := range rows {
+		ref, cmp := r.horizon(reference), r.horizon(compared)
+		if ref == nil || cmp == nil {
+			continue
+		}
+
+		row, col := horizonBucket(*ref), horizonBucket(*cmp)
+		m.Counts[row][index[col]]++
+
+		total++
+		if row == col {
+			same++
+		}
+	}
+
+	if total > 0 {
+		m.Agreement = float64(same) / float64(total)
+	}
+
+	return m
+}
+
+func groupGaps(gaps map[string][]float64, order []string) []GroupGap {
+	groups := make([]GroupGap, 0, len(order))
+	for _, name := range order {
+		values := gaps[name]
+		if len(values) == 0 {
+			continue
+		}
+
+		d := describe(values)
+		g := GroupGap{Group: name, Count: d.Count, Mean: d.Mean, Median: d.Median}
+		for _, v := range values {
+			g.MeanAbs += math.Abs(v)
+		}
+		g.MeanAbs /= float64(len(values))
+
+		groups = append(groups, g)
+	}
+
+	return groups
+}
+
+// describe returns the distribution of non-empty values.
+func describe(values []float64) Distribution {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	d := Distribution{
+		Count:  len(sorted),
+		Median: percentile(sorted, 0.5),
+		P10:    percentile(sorted, 0.1),
+		P90:    percentile(sorted, 0.9),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+	}
+
+	for _, v := range sorted {
+		d.Mean += v
+	}
+	d.Mean /= float64(len(sorted))
+
+	return d
+}
+
+// percentile interpolates linearly between the order statistics.
+func percentile(sorted []float64, p float64) float64 {
+	h := p * float64(len(sorted)-1)
+	i := int(h)
+	if i >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+
+	return sorted[i] + (h-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// writeSummary writes the summary as JSON, or as a long CSV of section, key,
+// metric and value rows, depending on the extension.
+func writeSummary(path string, summary Summary) error {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		raw, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not marshal the summary: %w", err)
+		}
+
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			return fmt.Errorf("could not write %s: %w", path, err)
+		}
+
+		return nil
+	}
+
+	csvFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer csvFile.Close()
+
+	csvwriter := csv.NewWriter(csvFile)
+	_ = csvwriter.Write([]string{"section", "key", "metric", "value"})
+
+	write := func(section, key, metric string, value float64) {
+		_ = csvwriter.Write([]string{section, key, metric, strconv.FormatFloat(value, 'f', -1, 64)})
+	}
+
+	distribution := func(section, key string, d Distribution) {
+		write(section, key, "count", float64(d.Count))
+		write(section, key, "mean", d.Mean)
+		write(section, key, "median", d.Median)
+		write(section, key, "p10", d.P10)
+		write(section, key, "p90", d.P90)
+		write(section, key, "min", d.Min)
+		write(section, key, "max", d.Max)
+	}
+
+	for _, source := range horizonSources {
+		if d, ok := summary.Horizons[source]; ok {
+			distribution("horizon", source, d)
+		}
+	}
+
+	for _, m := range summary.Matrices {
+		key := m.Reference + "/" + m.Compared
+		for _, row := range m.Buckets {
+			for j, col := range m.Buckets {
+				write("confusion", key, row+"/"+col, float64(m.Counts[row][j]))
+			}
+		}
+		write("confusion", key, "agreement", m.Agreement)
+	}
+
+	distribution("uplift", "relative", summary.Uplift)
+	distribution("uplift", "absolute", summary.UpliftAbs)
+
+	for section, groups := range map[string][]GroupGap{"arcanistReccoByMarketCap": summary.ByMarketCap, "arcanistReccoByCountry": summary.ByCountry} {
+		for _, g := range groups {
+			write(section, g.Group, "count", float64(g.Count))
+			write(section, g.Group, "mean", g.Mean)
+			write(section, g.Group, "median", g.Median)
+			write(section, g.Group, "meanAbs", g.MeanAbs)
+		}
+	}
+
+	for _, o := range summary.Outliers {
+		write("outlier", o.ID, o.Kind, o.Value)
+	}
+
+	csvwriter.Flush()
+	if err := csvwriter.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// writeSummaryMarkdown writes the summary as the Markdown of a validation
+// note.
+func writeSummaryMarkdown(path string, summary Summary) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	markdownSummary(file, summary)
+
+	return nil
+}
+
+func markdownSummary(w io.Writer, summary Summary) {
+	fmt.Fprintf(w, "# Liquidity validation\n\n%d assets.\n\n", summary.Assets)
+
+	fmt.Fprintln(w, "## Horizons")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Source | Count | Mean | Median | P10 | P90 | Min | Max |")
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|---:|---:|")
+	for _, source := range horizonSources {
+		if d, ok := summary.Horizons[source]; ok {
+			fmt.Fprintf(w, "| %s | %d | %.1f | %.1f | %.1f | %.1f | %.0f | %.0f |\n", source, d.Count, d.Mean, d.Median, d.P10, d.P90, d.Min, d.Max)
+		}
+	}
+
+	for _, m := range summary.Matrices {
+		fmt.Fprintf(w, "\n### %s (rows) vs %s (columns)\n\nSame bucket: %.1f%%.\n\n", m.Reference, m.Compared, 100*m.Agreement)
+		fmt.Fprintf(w, "| | %s |\n", strings.Join(m.Buckets, " | "))
+		fmt.Fprintf(w, "|---|%s\n", strings.Repeat("---:|", len(m.Buckets)))
+		for _, row := range m.Buckets {
+			counts := make([]string, len(m.Counts[row]))
+			for j, c := range m.Counts[row] {
+				counts[j] = strconv.Itoa(c)
+			}
+			fmt.Fprintf(w, "| **%s** | %s |\n", row, strings.Join(counts, " | "))
+		}
+	}
+
+	fmt.Fprintln(w, "\n## ES uplift of the liquidity horizon")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Uplift | Count | Mean | Median | P10 | P90 |")
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|")
+	fmt.Fprintf(w, "| relative | %d | %.2f%% | %.2f%% | %.2f%% | %.2f%% |\n",
+		summary.Uplift.Count, 100*summary.Uplift.Mean, 100*summary.Uplift.Median, 100*summary.Uplift.P10, 100*summary.Uplift.P90)
+	fmt.Fprintf(w, "| absolute | %d | %.4g | %.4g | %.4g | %.4g |\n",
+		summary.UpliftAbs.Count, summary.UpliftAbs.Mean, summary.UpliftAbs.Median, summary.UpliftAbs.P10, summary.UpliftAbs.P90)
+
+	for _, section := range []struct {
+		title  string
+		groups []GroupGap
+	}{{"market-cap bucket", summary.ByMarketCap}, {"issuer country", summary.ByCountry}} {
+		fmt.Fprintf(w, "\n## Arcanist vs Recco ES by %s\n\n", section.title)
+		fmt.Fprintln(w, "| Group | Count | Mean gap | Median gap | Mean abs gap |")
+		fmt.Fprintln(w, "|---|---:|---:|---:|---:|")
+		for _, g := range section.groups {
+			fmt.Fprintf(w, "| %s | %d | %.2f%% | %.2f%% | %.2f%% |\n", g.Group, g.Count, 100*g.Mean, 100*g.Median, 100*g.MeanAbs)
+		}
+	}
+
+	fmt.Fprintf(w, "\n## Outliers\n\n%d outliers (ES gap over %.0f%%, horizon gap over %d days, negative uplift).\n\n",
+		len(summary.Outliers), 100*outlierESGap, outlierHorizon)
+	if len(summary.Outliers) > 0 {
+		fmt.Fprintln(w, "| Asset | Kind | Value | Detail |")
+		fmt.Fprintln(w, "|---|---|---:|---|")
+		for _, o := range summary.Outliers {
+			fmt.Fprintf(w, "| %s | %s | %.4g | %s |\n", o.ID, o.Kind, o.Value, o.Detail)
+		}
+	}
+}
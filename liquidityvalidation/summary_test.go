@@ -0,0 +1,73 @@
+package liquidityvalidation
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_horizonBucket(t *testing.T) {
+	t.Parallel()
+
+	for horizon, bucket := range map[int]string{0: "0", 1: "1-5", 5: "1-5", 6: "6-10", 20: "11-20", 30: "21-30", 31: ">30"} {
+		assert.Equal(t, bucket, horizonBucket(horizon), horizon)
+	}
+}
+
+func Test_summarize(t *testing.T) {
+	t.Parallel()
+
+	ptr := func(v float64) *float64 { return &v }
+	days := func(v int) *int { return &v }
+
+	outputMD := []liquidityOutput{
+		{id: "A", horizon: 0, marketCap: ptr(3e11), pocHorizon: days(0), country: "US"},
+		{id: "B", horizon: 4, marketCap: ptr(5e9), pocHorizon: days(20), country: "FR"},
+		{id: "C", horizon: 30, pocHorizon: days(30)},
+	}
+	outputEve := map[string]eveOutput{
+		"A": {ID: "A", HorizonNoTradingVolumes: 0},
+		"B": {ID: "B", HorizonNoTradingVolumes: 5, HorizonTradingVolumes: days(3)},
+	}
+	outputArcanist := map[string]float64{"A": 10, "B": 12, "C": 9}
+	outputArcanistMD := map[string]float64{"A": 10, "B": 10, "C": 10}
+	outputRecco := map[string]float64{"A": 10, "B": 6}
+
+	summary := summarize(assetRows(outputMD, outputEve, nil, outputArcanist, outputArcanistMD, outputRecco))
+
+	assert.Equal(t, 3, summary.Assets)
+	assert.Equal(t, 3, summary.Horizons[sourceMD].Count)
+	assert.InDelta(t, 4, summary.Horizons[sourceMD].Median, 1e-9)
+	assert.Equal(t, 1, summary.Horizons[sourceVolumes].Count)
+	assert.NotContains(t, summary.Horizons, sourceEstimate)
+
+	require.Equal(t, sourcePoC, summary.Matrices[0].Compared)
+	assert.Equal(t, 1, summary.Matrices[0].Counts["1-5"][3])
+	assert.InDelta(t, 2.0/3, summary.Matrices[0].Agreement, 1e-9)
+
+	assert.Equal(t, 3, summary.Uplift.Count)
+	assert.InDelta(t, 0.2, summary.Uplift.Max, 1e-9)
+	assert.InDelta(t, -0.1, summary.Uplift.Min, 1e-9)
+
+	require.Len(t, summary.ByMarketCap, 2)
+	assert.Equal(t, "mega", summary.ByMarketCap[0].Group)
+	assert.InDelta(t, 1, summary.ByMarketCap[1].Mean, 1e-9)
+	require.Len(t, summary.ByCountry, 2)
+	assert.Equal(t, "FR", summary.ByCountry[0].Group)
+
+	kinds := make(map[string]string)
+	for _, o := range summary.Outliers {
+		kinds[o.ID+"/"+o.Kind] = o.Detail
+	}
+	assert.Contains(t, kinds, "B/arcanistRecco")
+	assert.Contains(t, kinds, "B/horizonPoC")
+	assert.Contains(t, kinds, "C/negativeUplift")
+	assert.Len(t, kinds, 3)
+
+	var md bytes.Buffer
+	markdownSummary(&md, summary)
+	assert.Contains(t, md.String(), "| horizonMD | 3 |")
+	assert.Contains(t, md.String(), "3 outliers")
+}
@@ -0,0 +1,279 @@
+package liquidityvalidation
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"toolkit/manifest"
+)
+
+// Horizon sweep: the liquidity-adjusted ES of every asset repriced for the
+// horizons 1 to sweepMaxHorizon days.
+var (
+	sweepMode        = false
+	sweepMaxHorizon  = 60
+	sweepTolerance   = 0.01
+	sweepMaxExponent = 0.55
+	sweepOutputPath  = "horizon-sweep.csv"
+)
+
+// Sweep flags.
+const (
+	sweepNonMonotone = "nonMonotone"
+	sweepSuperLinear = "superLinear"
+	sweepSuperSqrt   = "superSqrt"
+	sweepMissing     = "missing"
+)
+
+// sweepOutput is the ES-vs-horizon curve of an asset and its fit
+// |ES| = Scale * h^Exponent. ES holds the ES per horizon from 1 day, NaN when
+// Arcanist gave no result.
+type sweepOutput struct {
+	ID        string
+	HorizonMD int
+	ES        []float64
+
+	// FitFrom is the first horizon of the fit, the scenario horizon when the
+	// curve has enough points from it since shorter horizons are not scaled.
+	FitFrom  int
+	Scale    float64
+	Exponent float64
+	R2       float64
+
+	Flags []string
+}
+
+// runSweep reprices the described assets over the horizons and writes the
+// curves to sweepOutputPath.
+func runSweep(run *manifest.Manifest, outputMD []liquidityOutput) error {
+	if sweepMaxHorizon < 2 {
+		return fmt.Errorf("the sweep needs at least 2 horizons, got %d", sweepMaxHorizon)
+	}
+
+	assets := make([]liquidityOutput, 0, len(outputMD))
+	for _, md := range outputMD {
+		if md.err == nil {
+			assets = append(assets, md)
+		}
+	}
+
+	ctx := context.Background()
+	outputs := make([]sweepOutput, 0, len(assets))
+	flagged := 0
+	for start := 0; start < len(assets); start += batchSize {
+		batch := assets[start:min(start+batchSize, len(assets))]
+
+		curves, err := requestArcanistSweep(ctx, batch, sweepMaxHorizon)
+		if err != nil {
+			return fmt.Errorf("could not sweep the horizons: %w", err)
+		}
+
+		for i, md := range batch {
+			output := fitSweep(curves[i], int(scenarioHorizon), sweepTolerance, sweepMaxExponent)
+			output.ID, output.HorizonMD = md.id, md.horizon
+
+			if len(output.Flags) > 0 {
+				flagged++
+			}
+			outputs = append(outputs, output)
+		}
+
+		log.Printf("Processed sweep %d/%d assets", start+len(batch), len(assets))
+	}
+	run.Count("sweep", len(outputs), len(outputs)-flagged)
+
+	if flagged > 0 {
+		log.Warnf("%d/%d assets have an ES that is not monotone or grows faster than the square root of the horizon", flagged, len(outputs))
+	}
+
+	return sweepToCsv(sweepOutputPath, outputs)
+}
+
+// requestArcanistSweep prices the liquidity-adjusted ES of the assets for
+// horizons 1 to maxHorizon in a single request, a position per asset and
+// horizon. The curves are in asset order, NaN where Arcanist gave no result.
+func requestArcanistSweep(ctx context.Context, assets []liquidityOutput, maxHorizon int) ([][]float64, error) {
+	positions := make(map[int]ArcanistPosition, len(assets)*maxHorizon)
+	for i, md := range assets {
+		for h := 1; h <= maxHorizon; h++ {
+			positions[i*maxHorizon+h-1] = ArcanistPosition{
+				Asset:     md.id,
+				Quantity:  1.0,
+				Currency:  positionCurrency,
+				Liquidity: float64(h),
+			}
+		}
+	}
+
+	results, err := requestArcanistPositions(ctx, positions, true)
+	if err != nil {
+		return nil, err
+	}
+
+	curves := make([][]float64, len(assets))
+	for i := range assets {
+		curves[i] = make([]float64, maxHorizon)
+		for h := range curves[i] {
+			v, ok := results[i*maxHorizon+h]
+			if !ok {
+				v = math.NaN()
+			}
+			curves[i][h] = v
+		}
+	}
+
+	return curves, nil
+}
+
+// fitSweep checks the curve of ES per horizon from 1 day. The absolute ES must
+// not decrease by more than the tolerance from a horizon to the next, nor grow
+// faster than the horizon from the first horizon of the fit. A power law is
+// fitted in log-log from fitFrom days, square root of time giving an exponent
+// of 0.5, and the exponent must not be above maxExponent.
+func fitSweep(es []float64, fitFrom int, tolerance, maxExponent float64) sweepOutput {
+	output := sweepOutput{ES: es, FitFrom: 1}
+
+	xs := make([]float64, 0, len(es))
+	ys := make([]float64, 0, len(es))
+	for h := fitFrom; h <= len(es); h++ {
+		if v := math.Abs(es[h-1]); !math.IsNaN(v) && v > 0 {
+			xs, ys = append(xs, float64(h)), append(ys, v)
+		}
+	}
+
+	if len(xs) >= 3 {
+		output.FitFrom = fitFrom
+	} else {
+		xs, ys = xs[:0], ys[:0]
+		for h := 1; h <= len(es); h++ {
+			if v := math.Abs(es[h-1]); !math.IsNaN(v) && v > 0 {
+				xs, ys = append(xs, float64(h)), append(ys, v)
+			}
+		}
+	}
+
+	missing := false
+	for _, v := range es {
+		if math.IsNaN(v) {
+			missing = true
+		}
+	}
+	if missing {
+		output.Flags = append(output.Flags, sweepMissing)
+	}
+
+	if len(xs) >= 2 {
+		output.Scale, output.Exponent, output.R2 = powerFit(xs, ys)
+	}
+
+	previous := math.NaN()
+	for _, v := range es {
+		v = math.Abs(v)
+		if math.IsNaN(v) {
+			continue
+		}
+
+		if !math.IsNaN(previous) && v < previous*(1-tolerance) {
+			output.Flags = append(output.Flags, sweepNonMonotone)
+
+			break
+		}
+		previous = v
+	}
+
+	if len(xs) >= 2 && output.Exponent > maxExponent {
+		output.Flags = append(output.Flags, sweepSuperSqrt)
+	}
+
+	if len(xs) >= 2 {
+		for i := range xs {
+			if ys[i] > ys[0]*(xs[i]/xs[0])*(1+tolerance) {
+				output.Flags = append(output.Flags, sweepSuperLinear)
+
+				break
+			}
+		}
+	}
+
+	return output
+}
+
+// powerFit fits y = scale * x^exponent by least squares on the logarithms.
+func powerFit(xs, ys []float64) (scale, exponent, r2 float64) {
+	n := float64(len(xs))
+
+	var sx, sy, sxx, sxy, syy float64
+	for i := range xs {
+		x, y := math.Log(xs[i]), math.Log(ys[i])
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+		syy += y * y
+	}
+
+	varX := sxx - sx*sx/n
+	if varX == 0 {
+		return math.Exp(sy / n), 0, 0
+	}
+
+	exponent = (sxy - sx*sy/n) / varX
+	scale = math.Exp((sy - exponent*sx) / n)
+
+	r2 = 1
+	if varY := syy - sy*sy/n; varY > 1e-12 {
+		r2 = exponent * exponent * varX / varY
+	}
+
+	return scale, exponent, r2
+}
+
+func sweepToCsv(path string, outputs []sweepOutput) error {
+	csvFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer csvFile.Close()
+
+	csvwriter := csv.NewWriter(csvFile)
+
+	header := []string{"id", "horizonMD", "fitFrom", "scale", "exponent", "r2", "flags"}
+	for h := 1; h <= sweepMaxHorizon; h++ {
+		header = append(header, fmt.Sprintf("es-%d", h))
+	}
+	_ = csvwriter.Write(header)
+
+	for _, o := range outputs {
+		row := []string{
+			o.ID,
+			strconv.Itoa(o.HorizonMD),
+			strconv.Itoa(o.FitFrom),
+			strconv.FormatFloat(o.Scale, 'f', -1, 64),
+			strconv.FormatFloat(o.Exponent, 'f', -1, 64),
+			strconv.FormatFloat(o.R2, 'f', -1, 64),
+			strings.Join(o.Flags, " "),
+		}
+		for _, v := range o.ES {
+			if math.IsNaN(v) {
+				row = append(row, "")
+			} else {
+				row = append(row, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+		_ = csvwriter.Write(row)
+	}
+
+	csvwriter.Flush()
+	if err := csvwriter.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package liquidityvalidation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_fitSweep(t *testing.T) {
+	t.Parallel()
+
+	// Flat up to the 30-day scenario horizon, then square root of time.
+	curve := make([]float64, 60)
+	for h := 1; h <= 60; h++ {
+		curve[h-1] = -0.1 * math.Sqrt(math.Max(float64(h), 30)/30)
+	}
+
+	output := fitSweep(curve, 30, 0.01, 0.55)
+	assert.Empty(t, output.Flags)
+	assert.Equal(t, 30, output.FitFrom)
+	assert.InDelta(t, 0.5, output.Exponent, 1e-9)
+	assert.InDelta(t, 1, output.R2, 1e-9)
+
+	broken := append([]float64(nil), curve...)
+	broken[44] = -0.05
+	broken[10] = math.NaN()
+	assert.Equal(t, []string{sweepMissing, sweepNonMonotone}, fitSweep(broken, 30, 0.01, 0.55).Flags)
+
+	// Linear from the scenario horizon: neither decreasing nor super-linear,
+	// but far from square root of time.
+	linear := make([]float64, 60)
+	for h := 1; h <= 60; h++ {
+		linear[h-1] = -0.1 * math.Max(float64(h), 30) / 30
+	}
+	output = fitSweep(linear, 30, 0.01, 0.55)
+	assert.InDelta(t, 1, output.Exponent, 1e-9)
+	assert.Equal(t, []string{sweepSuperSqrt}, output.Flags)
+	assert.Empty(t, fitSweep(linear, 30, 0.01, 1.05).Flags)
+
+	quadratic := make([]float64, 10)
+	for h := 1; h <= 10; h++ {
+		quadratic[h-1] = float64(h * h)
+	}
+	output = fitSweep(quadratic, 30, 0.01, 0.55)
+	assert.Equal(t, 1, output.FitFrom)
+	assert.InDelta(t, 2, output.Exponent, 1e-9)
+	assert.Equal(t, []string{sweepSuperSqrt, sweepSuperLinear}, output.Flags)
+}
+
+func Test_powerFit(t *testing.T) {
+	t.Parallel()
+
+	scale, exponent, r2 := powerFit([]float64{1, 4, 9}, []float64{2, 4, 6})
+	require.InDelta(t, 0.5, exponent, 1e-9)
+	assert.InDelta(t, 2, scale, 1e-9)
+	assert.InDelta(t, 1, r2, 1e-9)
+}
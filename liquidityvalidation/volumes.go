@@ -0,0 +1,186 @@
+package liquidityvalidation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Volume-based horizon estimator: the position is liquidated at a share of the
+// average daily volume (ADV) of the trading volumes of the Adam payload.
+var (
+	participationRates = "0.1,0.25"
+	advWindow          = 20
+	positionQuantity   = 1.0
+)
+
+// dailyVolume is the volume traded on a day.
+type dailyVolume struct {
+	Date   string
+	Volume float64
+}
+
+// volumeEstimate is the horizon estimated from the trading volumes of an
+// asset. Horizon is the liquidation days at the first participation rate,
+// nil when the ADV is zero.
+type volumeEstimate struct {
+	Days            int
+	ADV             float64
+	SizeToADV       float64
+	LiquidationDays []float64
+	Horizon         *int
+}
+
+// estimateVolumeHorizons estimates the horizon of every dump carrying trading
+// volumes. Dumps without volumes are left out, unreadable ones logged.
+func estimateVolumeHorizons(requests []Request, rates []float64) map[string]volumeEstimate {
+	output := make(map[string]volumeEstimate, len(requests))
+	for _, request := range requests {
+		var payload struct {
+			TradingVolumes json.RawMessage `json:"tradingVolumes"`
+		}
+		if err := json.Unmarshal(request.Payload, &payload); err != nil {
+			log.Infof("Error while unmarshalling payload of %s: %v", request.ID, err)
+
+			continue
+		}
+
+		if len(payload.TradingVolumes) == 0 || string(payload.TradingVolumes) == "null" {
+			continue
+		}
+
+		volumes, err := parseTradingVolumes(payload.TradingVolumes)
+		if err != nil {
+			log.Infof("Invalid trading volumes of %s: %v", request.ID, err)
+
+			continue
+		}
+
+		estimate, err := estimateVolumeHorizon(volumes, positionQuantity, rates)
+		if err != nil {
+			log.Infof("Could not estimate the horizon of %s: %v", request.ID, err)
+
+			continue
+		}
+
+		output[request.ID] = estimate
+	}
+
+	return output
+}
+
+// parseTradingVolumes reads the volumes of the payload, sorted by date. They
+// may be an array of {date, volume} or {date, value} objects, or an object of
+// volumes, or of {value} metrics, keyed by date.
+func parseTradingVolumes(raw json.RawMessage) ([]dailyVolume, error) {
+	volumes := make([]dailyVolume, 0)
+
+	var rows []struct {
+		Date   string   `json:"date"`
+		Volume *float64 `json:"volume"`
+		Value  *float64 `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &rows); err == nil {
+		for _, row := range rows {
+			switch {
+			case row.Volume != nil:
+				volumes = append(volumes, dailyVolume{Date: row.Date, Volume: *row.Volume})
+			case row.Value != nil:
+				volumes = append(volumes, dailyVolume{Date: row.Date, Volume: *row.Value})
+			}
+		}
+	} else {
+		var byDate map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &byDate); err != nil {
+			return nil, errors.New("expected an array of daily volumes or an object keyed by date")
+		}
+
+		for date, v := range byDate {
+			var volume float64
+			if err := json.Unmarshal(v, &volume); err != nil {
+				var metric struct {
+					Value *float64 `json:"value"`
+				}
+				if err := json.Unmarshal(v, &metric); err != nil || metric.Value == nil {
+					return nil, fmt.Errorf("invalid volume on %s", date)
+				}
+				volume = *metric.Value
+			}
+
+			volumes = append(volumes, dailyVolume{Date: date, Volume: volume})
+		}
+	}
+
+	sort.SliceStable(volumes, func(i, j int) bool {
+		return volumes[i].Date < volumes[j].Date
+	})
+
+	return volumes, nil
+}
+
+// estimateVolumeHorizon returns the ADV over the last advWindow days and the
+// days needed to sell the quantity at each participation rate.
+func estimateVolumeHorizon(volumes []dailyVolume, quantity float64, rates []float64) (volumeEstimate, error) {
+	if len(volumes) == 0 {
+		return volumeEstimate{}, errors.New("no trading volume")
+	}
+
+	if len(volumes) > advWindow {
+		volumes = volumes[len(volumes)-advWindow:]
+	}
+
+	estimate := volumeEstimate{Days: len(volumes)}
+	for _, v := range volumes {
+		estimate.ADV += v.Volume
+	}
+	estimate.ADV /= float64(len(volumes))
+
+	if estimate.ADV <= 0 {
+		return estimate, nil
+	}
+
+	estimate.SizeToADV = math.Abs(quantity) / estimate.ADV
+	for _, rate := range rates {
+		estimate.LiquidationDays = append(estimate.LiquidationDays, estimate.SizeToADV/rate)
+	}
+
+	if len(rates) > 0 {
+		horizon := int(math.Ceil(estimate.LiquidationDays[0]))
+		estimate.Horizon = &horizon
+	}
+
+	return estimate, nil
+}
+
+// parseRates parses comma-separated participation rates in (0, 1].
+func parseRates(value string) ([]float64, error) {
+	rates := make([]float64, 0)
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		rate, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid participation rate %q: %w", part, err)
+		}
+
+		if rate <= 0 || rate > 1 {
+			return nil, fmt.Errorf("participation rate %v is not in (0, 1]", rate)
+		}
+
+		rates = append(rates, rate)
+	}
+
+	if len(rates) == 0 {
+		return nil, errors.New("no participation rate")
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,71 @@
+package liquidityvalidation
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseTradingVolumes(t *testing.T) {
+	t.Parallel()
+
+	for _, raw := range []string{
+		`[{"date": "2024-10-02", "volume": 300}, {"date": "2024-10-01", "volume": 100}]`,
+		`[{"date": "2024-10-02", "value": 300}, {"date": "2024-10-01", "value": 100}]`,
+		`{"2024-10-02": 300, "2024-10-01": 100}`,
+		`{"2024-10-02": {"value": 300}, "2024-10-01": {"value": 100}}`,
+	} {
+		volumes, err := parseTradingVolumes(json.RawMessage(raw))
+		require.NoError(t, err, raw)
+		assert.Equal(t, []dailyVolume{{"2024-10-01", 100}, {"2024-10-02", 300}}, volumes, raw)
+	}
+
+	_, err := parseTradingVolumes(json.RawMessage(`"volumes"`))
+	assert.Error(t, err)
+}
+
+func Test_estimateVolumeHorizons(t *testing.T) {
+	t.Parallel()
+
+	// 25 days of 1000, then 20 days of 2000 making the ADV.
+	volumes := make(map[string]float64)
+	for day := 1; day <= 45; day++ {
+		volume := 1000.0
+		if day > 25 {
+			volume = 2000
+		}
+		volumes[time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC).Format(time.DateOnly)] = volume
+	}
+
+	payload, err := json.Marshal(map[string]any{"asset": "a", "tradingVolumes": volumes})
+	require.NoError(t, err)
+
+	estimates := estimateVolumeHorizons([]Request{
+		{ID: "a", Payload: payload},
+		{ID: "b", Payload: json.RawMessage(`{"asset": "b"}`)},
+	}, []float64{0.1, 0.25})
+	require.Len(t, estimates, 1)
+
+	// One unit at 10% and 25% of an ADV of 2000.
+	e := estimates["a"]
+	assert.Equal(t, 20, e.Days)
+	assert.Equal(t, 2000.0, e.ADV)
+	assert.Equal(t, 0.0005, e.SizeToADV)
+	assert.InDeltaSlice(t, []float64{0.005, 0.002}, e.LiquidationDays, 1e-15)
+	require.NotNil(t, e.Horizon)
+	assert.Equal(t, 1, *e.Horizon)
+
+	e, err = estimateVolumeHorizon([]dailyVolume{{"2024-10-01", 100}}, 5000, []float64{0.1})
+	require.NoError(t, err)
+	assert.Equal(t, 500, *e.Horizon)
+
+	e, err = estimateVolumeHorizon([]dailyVolume{{"2024-10-01", 0}}, 1, []float64{0.1})
+	require.NoError(t, err)
+	assert.Nil(t, e.Horizon)
+
+	_, err = parseRates("0.1,1.5")
+	assert.Error(t, err)
+}
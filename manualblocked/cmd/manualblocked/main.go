@@ -0,0 +1,9 @@
+// Command manualblocked runs the manualblocked script on its own, outside of the
+// validation command.
+package main
+
+import "manualblocked"
+
+func main() {
+	manualblocked.Main()
+}
@@ -1,14 +1,27 @@
-package main
+package manualblocked
 
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"os"
 
 	log "github.com/sirupsen/logrus"
+
+	"toolkit/manifest"
 )
 
-func main() {
+// Main parses the command line flags and runs the script.
+func Main() {
+	outputPath := flag.String("output", "output.json", "output JSON file listing the manual and blocked proxies")
+	outputIssuersPath := flag.String("output-issuers", "output-issuers.json", "output JSON file listing the affected issuers")
+	flag.StringVar(&hippoURL, "hippo-url", hippoURL, "Hippo credit proxies endpoint")
+	flag.StringVar(&token, "token", token, "bearer token")
+	flag.Parse()
+
+	run := manifest.New("proxies check")
+	run.RecordFlags(flag.CommandLine)
+
 	ctx := context.Background()
 	// Call Hippo for manuals.
 	manualProxies, err := readManualProxies(ctx)
@@ -27,10 +40,15 @@ func main() {
 
 	// Save result in json file.
 	output, _ := json.MarshalIndent(manualAndBlockedProxies, "", " ")
-	_ = os.WriteFile("output.json", output, 0644)
+	_ = os.WriteFile(*outputPath, output, 0644)
 
 	outputIssuers, _ := json.MarshalIndent(affectedIssuers, "", " ")
-	_ = os.WriteFile("output-issuers.json", outputIssuers, 0644)
+	_ = os.WriteFile(*outputIssuersPath, outputIssuers, 0644)
+
+	run.Count("hippo", len(manualProxies), len(manualAndBlockedProxies))
+	if err := run.Write(*outputPath, *outputIssuersPath); err != nil {
+		log.Fatal("Error while writing the run manifest", err)
+	}
 
 	log.Info("Done")
 }
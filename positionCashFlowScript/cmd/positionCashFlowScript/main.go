@@ -0,0 +1,9 @@
+// Command positionCashFlowScript runs the positionCashFlowScript script on its own, outside of the
+// validation command.
+package main
+
+import "positionCashFlowScript"
+
+func main() {
+	positioncashflowscript.Main()
+}
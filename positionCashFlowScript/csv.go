@@ -1,4 +1,4 @@
-package main
+package positioncashflowscript
 
 import (
 	"encoding/csv"
@@ -6,17 +6,19 @@ import (
 	"os"
 
 	"github.com/sirupsen/logrus"
+
+	"toolkit/input"
 )
 
-const outputLocation = "./positions"
+var outputLocation = "./positions"
 
-func positionsToCsv(output *PositionsCashFlowsOutput) error {
+func positionsToCsv(positions []input.Position, output *PositionsCashFlowsOutput) error {
 	logrus.Infof("Building %s file...", outputLocation)
 
 	for id, result := range output.Results {
-		data := data[id]
+		data := positions[id]
 
-		fileName := fmt.Sprintf("%s/%s.csv", outputLocation, data.id)
+		fileName := fmt.Sprintf("%s/%s.csv", outputLocation, data.ID)
 		csvFile, err := os.Create(fileName)
 		if err != nil {
 			return fmt.Errorf("error while creating report file: %s", err)
@@ -27,19 +29,19 @@ func positionsToCsv(output *PositionsCashFlowsOutput) error {
 		defer csvwriter.Flush()
 
 		// ID.
-		err = csvwriter.Write([]string{"id", data.id})
+		err = csvwriter.Write([]string{"id", data.ID})
 		if err != nil {
 			return fmt.Errorf("error while writing id: %s", err)
 		}
 
 		// Currency.
-		err = csvwriter.Write([]string{"input currency", data.currency})
+		err = csvwriter.Write([]string{"input currency", data.Currency})
 		if err != nil {
 			return fmt.Errorf("error while writing currency: %s", err)
 		}
 
 		// Quantity.
-		err = csvwriter.Write([]string{"quantity", fmt.Sprintf("%f", data.quantity)})
+		err = csvwriter.Write([]string{"quantity", fmt.Sprintf("%f", data.Quantity)})
 		if err != nil {
 			return fmt.Errorf("error while writing quantity: %s", err)
 		}
@@ -1,22 +1,57 @@
-package main
+package positioncashflowscript
 
 import (
 	"context"
+	"flag"
 
 	"github.com/sirupsen/logrus"
+
+	"toolkit/input"
+	"toolkit/manifest"
 )
 
-func main() {
+// Main parses the command line flags and runs the script.
+func Main() {
+	inputPath := flag.String("input", "positions.csv", "CSV or JSON positions file with the id, currency and quantity of each asset, - for stdin")
+	column := flag.String("column", "", "name of the ID column of a CSV input")
+	flag.Var(&snapshot, "snapshot", "snapshot date of the cash flows (YYYY-MM-DD)")
+	flag.Var(&start, "start", "first cash flow date (YYYY-MM-DD)")
+	flag.Var(&end, "end", "last cash flow date (YYYY-MM-DD)")
+	flag.StringVar(&outputCurrency, "currency", outputCurrency, "currency of the cash flows")
+	flag.StringVar(&unit, "unit", unit, "unit of the cash flows")
+	flag.StringVar(&outputLocation, "output", outputLocation, "output folder, one CSV per position")
+	flag.StringVar(&positionsURL, "arcanist-url", positionsURL, "Arcanist positions cash flows endpoint")
+	flag.StringVar(&cerberusHost, "cerberus-url", cerberusHost, "Cerberus host resolving the ISINs")
+	flag.StringVar(&token, "token", token, "bearer token")
+	flag.Parse()
+
+	run := manifest.New("cashflows")
+	run.RecordFlags(flag.CommandLine)
+
+	positions, err := input.ReadPositions(*inputPath, input.Options{Column: *column, Resolver: input.Cerberus(cerberusHost, token)})
+	if err != nil {
+		logrus.Fatalf("Error reading the positions: %v", err)
+	}
+
+	if err := run.AddInput(*inputPath); err != nil {
+		logrus.Fatalf("Error hashing the positions: %v", err)
+	}
+
 	// Call to Arcanist.
 	ctx := context.Background()
-	output, err := positionsCashFlows(ctx)
+	output, err := positionsCashFlows(ctx, positions)
 	if err != nil {
 		logrus.Fatalf("Error calling positionsCashFlows: %v", err)
 	}
 
 	// Conversion of results in csv.
-	err = positionsToCsv(output)
+	err = positionsToCsv(positions, output)
 	if err != nil {
 		logrus.Fatalf("Error converting results to csv: %v", err)
 	}
+
+	run.Count("arcanist", len(positions), len(output.Results))
+	if err := run.Write(outputLocation); err != nil {
+		logrus.Fatalf("Error writing the run manifest: %v", err)
+	}
 }
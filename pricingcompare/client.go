@@ -0,0 +1,118 @@
+package pricingcompare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"toolkit/cache"
+)
+
+// environment holds the services of an environment.
+type environment struct {
+	name     string
+	snapshot string
+	adamURL  string
+	eveURL   string
+	token    string
+}
+
+type RequestInput struct {
+	Asset            string   `json:"asset"`
+	TargetCurrencies []string `json:"targetCurrencies"`
+	Run              RunDate  `json:"run"`
+	AsOf             bool     `json:"asOf"`
+}
+
+type RunDate struct {
+	Date string `json:"date"`
+}
+
+// responseCache stores the Adam dumps, which are bound to a snapshot. Eve
+// answers are never cached since the point is to price with the deployed
+// version. It is nil when caching is disabled.
+var responseCache *cache.Cache
+
+// dump returns the Eve request of the asset from the Adam of the environment.
+func (e environment) dump(ctx context.Context, id string) (json.RawMessage, error) {
+	body, err := json.Marshal(RequestInput{
+		Run:              RunDate{Date: e.snapshot},
+		Asset:            id,
+		TargetCurrencies: []string{"local"},
+		AsOf:             true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the dump request: %w", err)
+	}
+
+	key := cache.Key{Service: "adam", Method: http.MethodPost, URL: e.adamURL, Snapshot: e.snapshot, Body: body}
+	if responseCache != nil {
+		cached, ok, err := responseCache.Get(key)
+		if err != nil {
+			log.Warnf("could not read adam response from cache: %v", err)
+		} else if ok {
+			return cached, nil
+		}
+	}
+
+	raw, err := e.send(ctx, http.MethodPost, e.adamURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("adam %s: %w", e.name, err)
+	}
+
+	if responseCache != nil {
+		if err := responseCache.Put(key, raw, 0); err != nil {
+			log.Warnf("could not store adam response in cache: %v", err)
+		}
+	}
+
+	return raw, nil
+}
+
+// price returns the result of the request from the Eve of the environment.
+func (e environment) price(ctx context.Context, request json.RawMessage) (json.RawMessage, error) {
+	raw, err := e.send(ctx, http.MethodPut, e.eveURL, request)
+	if err != nil {
+		return nil, fmt.Errorf("eve %s: %w", e.name, err)
+	}
+
+	return raw, nil
+}
+
+func (e environment) send(ctx context.Context, method, url string, body []byte) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("could not create the request: %w", err)
+	}
+	req.Header.Set("x-internal-service", "validation")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	if e.token != "" {
+		req.Header.Set("Authorization", "Bearer "+e.token)
+	}
+
+	client := &http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not send the request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read response body: %w", err)
+	}
+
+	if s := res.StatusCode; s != http.StatusOK {
+		return nil, fmt.Errorf("received non-200 status code: %d", s)
+	}
+
+	return json.RawMessage(raw), nil
+}
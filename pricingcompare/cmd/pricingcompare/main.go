@@ -0,0 +1,9 @@
+// Command pricingcompare runs the pricingcompare script on its own, outside of the
+// validation command.
+package main
+
+import "pricingcompare"
+
+func main() {
+	pricingcompare.Main()
+}
@@ -0,0 +1,257 @@
+package pricingcompare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"toolkit/manifest"
+	"toolkit/pricingdiff"
+)
+
+const unknownAssetType = "Unknown"
+
+// assetComparison is the comparison of the DEV and PROD pricings of an asset.
+// Error is set when the asset could not be priced in both environments.
+type assetComparison struct {
+	ID        string `json:"id"`
+	AssetType string `json:"assetType"`
+	Error     string `json:"error,omitempty"`
+
+	NPVDev     float64 `json:"npvDev"`
+	NPVProd    float64 `json:"npvProd"`
+	NPVAbsDiff float64 `json:"npvAbsDiff"`
+	NPVRelDiff float64 `json:"npvRelDiff"`
+
+	// Differences counts the metrics out of tolerance, StatusMismatches those
+	// whose status differs.
+	Differences      int `json:"differences"`
+	StatusMismatches int `json:"statusMismatches"`
+
+	// WorstSensitivity is the sensitivity with the largest relative gap.
+	WorstSensitivity        string  `json:"worstSensitivity,omitempty"`
+	WorstSensitivityAbsDiff float64 `json:"worstSensitivityAbsDiff,omitempty"`
+	WorstSensitivityRelDiff float64 `json:"worstSensitivityRelDiff,omitempty"`
+}
+
+type npvResult struct {
+	Results struct {
+		Main struct {
+			NPV struct {
+				Value  float64 `json:"value"`
+				Status string  `json:"status"`
+			} `json:"NPV"`
+		} `json:"main"`
+	} `json:"results"`
+}
+
+// compareAsset dumps and prices the asset in both environments and compares
+// the results.
+func compareAsset(id string, comparer pricingdiff.Comparer, sections []string, run *manifest.Manifest) assetComparison {
+	ctx := context.Background()
+	c := assetComparison{ID: id, AssetType: unknownAssetType}
+
+	results := make([]json.RawMessage, 0, 2)
+	for _, env := range []environment{dev, prod} {
+		stage := strings.ToLower(env.name)
+
+		request, err := env.dump(ctx, id)
+		if err != nil {
+			run.Count("adam-"+stage, 1, 0)
+			c.Error = err.Error()
+			log.Infof("Asset %s: %v", id, err)
+
+			return c
+		}
+		run.Count("adam-"+stage, 1, 1)
+
+		if t := assetType(request); t != "" {
+			c.AssetType = t
+		}
+
+		result, err := env.price(ctx, request)
+		save(id, env, "request", request)
+		if err != nil {
+			run.Count("eve-"+stage, 1, 0)
+			c.Error = err.Error()
+			log.Infof("Asset %s: %v", id, err)
+
+			return c
+		}
+		run.Count("eve-"+stage, 1, 1)
+		save(id, env, "result", result)
+
+		results = append(results, result)
+	}
+
+	if err := fillComparison(&c, results[0], results[1], comparer, sections); err != nil {
+		c.Error = err.Error()
+		log.Infof("Asset %s: %v", id, err)
+	}
+
+	return c
+}
+
+// fillComparison sets the NPV and the differences of the DEV and PROD
+// results.
+func fillComparison(c *assetComparison, devResult, prodResult []byte, comparer pricingdiff.Comparer, sections []string) error {
+	var npvDev, npvProd npvResult
+	if err := json.Unmarshal(devResult, &npvDev); err != nil {
+		return fmt.Errorf("could not unmarshal DEV result: %w", err)
+	}
+
+	if err := json.Unmarshal(prodResult, &npvProd); err != nil {
+		return fmt.Errorf("could not unmarshal PROD result: %w", err)
+	}
+
+	c.NPVDev = npvDev.Results.Main.NPV.Value
+	c.NPVProd = npvProd.Results.Main.NPV.Value
+	c.NPVAbsDiff, c.NPVRelDiff = pricingdiff.Deviation(c.NPVDev, c.NPVProd)
+
+	diffs, err := comparer.CompareResults(devResult, prodResult, sections...)
+	if err != nil {
+		return err
+	}
+
+	c.Differences = len(diffs)
+	for _, d := range diffs {
+		if d.Kind == pricingdiff.KindStatus {
+			c.StatusMismatches++
+
+			continue
+		}
+
+		if strings.HasPrefix(d.Path, "results.sensitivities") && d.RelDiff > c.WorstSensitivityRelDiff {
+			c.WorstSensitivity = strings.TrimPrefix(d.Path, "results.sensitivities.")
+			c.WorstSensitivityAbsDiff = d.AbsDiff
+			c.WorstSensitivityRelDiff = d.RelDiff
+		}
+	}
+
+	return nil
+}
+
+func assetType(request json.RawMessage) string {
+	var r struct {
+		AssetType string `json:"assetType"`
+	}
+
+	if err := json.Unmarshal(request, &r); err != nil {
+		return ""
+	}
+
+	return r.AssetType
+}
+
+// save writes the document to the dump folder, if any, as
+// <id>_<env>_<kind>.json.
+func save(id string, env environment, kind string, document json.RawMessage) {
+	if dumpDir == "" {
+		return
+	}
+
+	path := filepath.Join(dumpDir, fmt.Sprintf("%s_%s_%s.json", id, strings.ToLower(env.name), kind))
+	if err := os.WriteFile(path, document, 0o644); err != nil {
+		log.Warnf("could not save %s: %v", path, err)
+	}
+}
+
+// typeSummary aggregates the comparisons of an asset type.
+type typeSummary struct {
+	AssetType             string  `json:"assetType"`
+	Assets                int     `json:"assets"`
+	Failed                int     `json:"failed"`
+	OutOfTolerance        int     `json:"outOfTolerance"`
+	StatusMismatches      int     `json:"statusMismatches"`
+	MeanNPVRelDiff        float64 `json:"meanNPVRelDiff"`
+	MaxNPVRelDiff         float64 `json:"maxNPVRelDiff"`
+	MaxSensitivityRelDiff float64 `json:"maxSensitivityRelDiff"`
+	Worst                 string  `json:"worst,omitempty"`
+}
+
+// summarize aggregates the comparisons per asset type, the types with the
+// largest NPV gap first.
+func summarize(comparisons []assetComparison) []typeSummary {
+	index := make(map[string]int)
+	summaries := make([]typeSummary, 0)
+	for _, c := range comparisons {
+		i, ok := index[c.AssetType]
+		if !ok {
+			i = len(summaries)
+			index[c.AssetType] = i
+			summaries = append(summaries, typeSummary{AssetType: c.AssetType})
+		}
+
+		s := &summaries[i]
+		s.Assets++
+
+		if c.Error != "" {
+			s.Failed++
+
+			continue
+		}
+
+		if c.Differences > 0 {
+			s.OutOfTolerance++
+		}
+		if c.StatusMismatches > 0 {
+			s.StatusMismatches++
+		}
+
+		s.MeanNPVRelDiff += c.NPVRelDiff
+		if c.NPVRelDiff >= s.MaxNPVRelDiff {
+			s.MaxNPVRelDiff = c.NPVRelDiff
+			s.Worst = c.ID
+		}
+		s.MaxSensitivityRelDiff = math.Max(s.MaxSensitivityRelDiff, c.WorstSensitivityRelDiff)
+	}
+
+	for i := range summaries {
+		if compared := summaries[i].Assets - summaries[i].Failed; compared > 0 {
+			summaries[i].MeanNPVRelDiff /= float64(compared)
+		}
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].MaxNPVRelDiff > summaries[j].MaxNPVRelDiff
+	})
+
+	return summaries
+}
+
+// rank returns at most n compared assets out of tolerance, worst first: status
+// mismatches, then the NPV gap, then the sensitivity gap.
+func rank(comparisons []assetComparison, n int) []assetComparison {
+	ranked := make([]assetComparison, 0)
+	for _, c := range comparisons {
+		if c.Error == "" && c.Differences > 0 {
+			ranked = append(ranked, c)
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if (a.StatusMismatches > 0) != (b.StatusMismatches > 0) {
+			return a.StatusMismatches > 0
+		}
+
+		if a.NPVRelDiff != b.NPVRelDiff {
+			return a.NPVRelDiff > b.NPVRelDiff
+		}
+
+		return a.WorstSensitivityRelDiff > b.WorstSensitivityRelDiff
+	})
+
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+
+	return ranked
+}
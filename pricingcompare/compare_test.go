@@ -0,0 +1,64 @@
+package pricingcompare
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"toolkit/pricingdiff"
+)
+
+func Test_fillComparison(t *testing.T) {
+	t.Parallel()
+
+	devResult, err := os.ReadFile("../data/dev_result.json")
+	require.NoError(t, err)
+
+	prodResult, err := os.ReadFile("../data/prod_result.json")
+	require.NoError(t, err)
+
+	tol, err := pricingdiff.ParseTolerances("main=1e-8:1e-6,sensitivities=1e-10:1e-4")
+	require.NoError(t, err)
+
+	c := assetComparison{ID: "7ea5a5f1-4440-418b-9579-68b2f620cb4e"}
+	require.NoError(t, fillComparison(&c, devResult, prodResult, pricingdiff.Comparer{Tolerances: tol}, []string{"main", "sensitivities"}))
+
+	assert.InDelta(t, 0.5835139136527071, c.NPVDev, 1e-12)
+	assert.InDelta(t, 0.5840125986441699, c.NPVProd, 1e-12)
+	assert.InDelta(t, 0.000854, c.NPVRelDiff, 1e-6)
+	assert.Zero(t, c.StatusMismatches)
+	assert.Positive(t, c.Differences)
+	assert.NotEmpty(t, c.WorstSensitivity)
+}
+
+func Test_summarize_rank(t *testing.T) {
+	t.Parallel()
+
+	comparisons := []assetComparison{
+		{ID: "a", AssetType: "Bond", NPVRelDiff: 0.01, Differences: 1},
+		{ID: "b", AssetType: "Bond", NPVRelDiff: 0.03, Differences: 2},
+		{ID: "c", AssetType: "Bond", Error: "adam DEV: received non-200 status code: 404"},
+		{ID: "d", AssetType: "CallableBarrierReverseConvertible", NPVRelDiff: 0.001, Differences: 1, StatusMismatches: 1},
+		{ID: "e", AssetType: "CallableBarrierReverseConvertible"},
+	}
+
+	summaries := summarize(comparisons)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, typeSummary{
+		AssetType:      "Bond",
+		Assets:         3,
+		Failed:         1,
+		OutOfTolerance: 2,
+		MeanNPVRelDiff: 0.02,
+		MaxNPVRelDiff:  0.03,
+		Worst:          "b",
+	}, summaries[0])
+	assert.Equal(t, 1, summaries[1].StatusMismatches)
+
+	worst := rank(comparisons, 2)
+	require.Len(t, worst, 2)
+	assert.Equal(t, "d", worst[0].ID)
+	assert.Equal(t, "b", worst[1].ID)
+}
@@ -0,0 +1,99 @@
+package pricingcompare
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func outputToCsv(outputPath string, comparisons []assetComparison) error {
+	log.Infof("Building output csv")
+
+	csvFile, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error while creating report file: %s", err)
+	}
+	defer csvFile.Close()
+
+	csvwriter := csv.NewWriter(csvFile)
+	defer csvwriter.Flush()
+
+	header := []string{
+		"id", "assetType", "error", "npvDev", "npvProd", "npvAbsDiff", "npvRelDiff",
+		"differences", "statusMismatches", "worstSensitivity", "worstSensitivityAbsDiff", "worstSensitivityRelDiff",
+	}
+	if err := csvwriter.Write(header); err != nil {
+		return fmt.Errorf("error while writing header: %s", err)
+	}
+
+	for _, c := range comparisons {
+		err := csvwriter.Write([]string{
+			c.ID,
+			c.AssetType,
+			c.Error,
+			formatFloat(c.NPVDev),
+			formatFloat(c.NPVProd),
+			formatFloat(c.NPVAbsDiff),
+			formatFloat(c.NPVRelDiff),
+			strconv.Itoa(c.Differences),
+			strconv.Itoa(c.StatusMismatches),
+			c.WorstSensitivity,
+			formatFloat(c.WorstSensitivityAbsDiff),
+			formatFloat(c.WorstSensitivityRelDiff),
+		})
+		if err != nil {
+			return fmt.Errorf("error while writing results: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func writeReport(path string, comparisons []assetComparison, summaries []typeSummary, worst []assetComparison) error {
+	report := struct {
+		Summaries []typeSummary     `json:"summaries"`
+		Worst     []assetComparison `json:"worst"`
+		Assets    []assetComparison `json:"assets"`
+	}{summaries, worst, comparisons}
+
+	raw, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal report: %w", err)
+	}
+
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func printSummary(w io.Writer, summaries []typeSummary, worst []assetComparison) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "ASSET TYPE\tASSETS\tFAILED\tOUT OF TOL\tSTATUS\tMEAN NPV REL\tMAX NPV REL\tMAX SENS REL\tWORST")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.3g\t%.3g\t%.3g\t%s\n",
+			s.AssetType, s.Assets, s.Failed, s.OutOfTolerance, s.StatusMismatches, s.MeanNPVRelDiff, s.MaxNPVRelDiff, s.MaxSensitivityRelDiff, s.Worst)
+	}
+
+	if len(worst) > 0 {
+		fmt.Fprintln(tw, "\nID\tASSET TYPE\tNPV DEV\tNPV PROD\tNPV REL\tSTATUS\tWORST SENSITIVITY\tSENS REL")
+		for _, c := range worst {
+			fmt.Fprintf(tw, "%s\t%s\t%.6g\t%.6g\t%.3g\t%d\t%s\t%.3g\n",
+				c.ID, c.AssetType, c.NPVDev, c.NPVProd, c.NPVRelDiff, c.StatusMismatches, c.WorstSensitivity, c.WorstSensitivityRelDiff)
+		}
+	}
+
+	tw.Flush()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
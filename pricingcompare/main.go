@@ -0,0 +1,158 @@
+/*
+Package pricingcompare prices a list of assets in DEV and PROD and ranks the
+gaps. Every asset is dumped by the Adam of each environment, priced by the Eve
+of the same environment, and the two results are compared on the NPV and the
+sensitivities; gaps are aggregated per asset type with the worst assets
+listed first.
+*/
+package pricingcompare
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"toolkit/cache"
+	"toolkit/input"
+	"toolkit/manifest"
+	"toolkit/pricingdiff"
+)
+
+var (
+	dev = environment{
+		name:     "DEV",
+		snapshot: "2024-10-14T00:30:04Z",
+		adamURL:  "http://adam-http.service.consul/debug/dump/request",
+		eveURL:   "http://eve-live.service.consul/debug/value",
+	}
+	prod = environment{
+		name:     "PROD",
+		snapshot: "2024-10-13T19:30:05Z",
+		adamURL:  "https://api.edgelab.ch/adam/debug/dump/request",
+		eveURL:   "https://api.edgelab.ch/eve/debug/value",
+	}
+
+	cerberusHost = "http://cerberus.service.consul"
+
+	inputPath   = "input.csv"
+	inputColumn = ""
+	outputPath  = "output.csv"
+	reportPath  = ""
+	dumpDir     = ""
+	cacheDir    = ".cache"
+	tolerances  = "main=1e-8:1e-6,sensitivities=1e-10:1e-4"
+	sections    = "main,sensitivities"
+	nbWorst     = 20
+)
+
+// Main parses the command line flags and runs the script.
+func Main() {
+	parseFlags()
+
+	run := manifest.New("pricing compare")
+	run.RecordFlags(flag.CommandLine)
+	run.Snapshot = dev.snapshot + " " + prod.snapshot
+
+	tol, err := pricingdiff.ParseTolerances(tolerances)
+	if err != nil {
+		log.Fatal("Error while parsing the tolerances: ", err)
+	}
+
+	if cacheDir != "" {
+		responseCache, err = cache.Open(cacheDir)
+		if err != nil {
+			log.Fatal("Error while opening the response cache: ", err)
+		}
+	}
+
+	if dumpDir != "" {
+		if err := os.MkdirAll(dumpDir, 0o755); err != nil {
+			log.Fatal("Error while creating the dump folder: ", err)
+		}
+	}
+
+	assetIDs, err := input.Read(inputPath, input.Options{Column: inputColumn, Resolver: input.Cerberus(cerberusHost, "")})
+	if err != nil {
+		log.Fatal("Error while reading the file: ", err)
+	}
+
+	if err := run.AddInput(inputPath); err != nil {
+		log.Fatal("Error while hashing the input: ", err)
+	}
+
+	comparer := pricingdiff.Comparer{Tolerances: tol}
+	compareSections := strings.Split(sections, ",")
+
+	log.Infof("Comparing %d assets between DEV and PROD", len(assetIDs))
+
+	comparisons := make([]assetComparison, 0, len(assetIDs))
+	for i, id := range assetIDs {
+		comparisons = append(comparisons, compareAsset(id, comparer, compareSections, run))
+
+		if (i+1)%100 == 0 {
+			log.Infof("Processed %d/%d assets (%f%%)", i+1, len(assetIDs), float64(i+1)/float64(len(assetIDs))*100)
+		}
+	}
+
+	compared := 0
+	for _, c := range comparisons {
+		if c.Error == "" {
+			compared++
+		}
+	}
+	run.Count("compare", len(comparisons), compared)
+
+	summaries := summarize(comparisons)
+	worst := rank(comparisons, nbWorst)
+
+	printSummary(os.Stdout, summaries, worst)
+
+	if err := outputToCsv(outputPath, comparisons); err != nil {
+		log.Fatalf("Error converting results to csv: %v", err)
+	}
+
+	outputs := []string{outputPath}
+	if reportPath != "" {
+		if err := writeReport(reportPath, comparisons, summaries, worst); err != nil {
+			log.Fatalf("Error writing the report: %v", err)
+		}
+
+		outputs = append(outputs, reportPath)
+	}
+
+	if err := run.Write(outputs...); err != nil {
+		log.Fatalf("Error writing the run manifest: %v", err)
+	}
+}
+
+func parseFlags() {
+	flag.StringVar(&inputPath, "input", inputPath, "CSV or JSON list of asset IDs or ISINs, - for stdin")
+	flag.StringVar(&inputColumn, "column", inputColumn, "name or index of the ID column of a CSV input")
+	flag.StringVar(&outputPath, "output", outputPath, "output CSV file, one line per asset")
+	flag.StringVar(&reportPath, "report", reportPath, "optional JSON report with the summaries per asset type and the worst assets")
+	flag.StringVar(&dumpDir, "dump-dir", dumpDir, "optional folder where the requests and results of both environments are saved")
+	flag.StringVar(&cacheDir, "cache-dir", cacheDir, "directory of the Adam dump cache, empty to disable it")
+	flag.StringVar(&tolerances, "tolerance", tolerances, "comma-separated metric=abs:rel tolerances")
+	flag.StringVar(&sections, "sections", sections, "comma-separated result sections to compare")
+	flag.IntVar(&nbWorst, "worst", nbWorst, "number of worst assets listed")
+	flag.StringVar(&cerberusHost, "cerberus-url", cerberusHost, "Cerberus host resolving the ISINs")
+
+	flag.StringVar(&dev.snapshot, "snapshot-dev", dev.snapshot, "snapshot used in DEV")
+	flag.StringVar(&prod.snapshot, "snapshot-prod", prod.snapshot, "snapshot used in PROD")
+	flag.StringVar(&dev.token, "token-dev", dev.token, "bearer token for DEV")
+	flag.StringVar(&prod.token, "token-prod", prod.token, "bearer token for PROD, required")
+	flag.StringVar(&dev.adamURL, "adam-url-dev", dev.adamURL, "Adam dump endpoint in DEV")
+	flag.StringVar(&prod.adamURL, "adam-url-prod", prod.adamURL, "Adam dump endpoint in PROD")
+	flag.StringVar(&dev.eveURL, "eve-url-dev", dev.eveURL, "Eve debug endpoint in DEV")
+	flag.StringVar(&prod.eveURL, "eve-url-prod", prod.eveURL, "Eve debug endpoint in PROD")
+
+	flag.Parse()
+
+	// PROD rejects unauthenticated requests, which would otherwise show up as
+	// a failure of every asset.
+	if prod.token == "" {
+		log.Fatal("-token-prod is required")
+	}
+}
@@ -0,0 +1,9 @@
+// Command removecocosuspects runs the removecocosuspects script on its own, outside of the
+// validation command.
+package main
+
+import "removecocosuspects"
+
+func main() {
+	removecocosuspects.Main()
+}
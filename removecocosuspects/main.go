@@ -1,42 +1,62 @@
-package main
+package removecocosuspects
 
 import (
-	"encoding/csv"
 	"encoding/json"
+	"flag"
 	"os"
 
 	log "github.com/sirupsen/logrus"
+
+	"toolkit/checkpoint"
+	"toolkit/input"
+	"toolkit/manifest"
 )
 
-func main() {
-	// Read input csv.
-	file, err := os.Open("credit_suspect_assets.csv")
+// Main parses the command line flags and runs the script.
+func Main() {
+	inputPath := flag.String("input", "credit_suspect_assets.csv", "CSV or JSON list of the credit suspect bonds, - for stdin")
+	column := flag.String("column", "1", "name or index of the bond ID column of a CSV input")
+	header := flag.Bool("header", true, "read the first row of a CSV input as a header")
+	outputPath := flag.String("output", "output.json", "output JSON file listing the coco bonds")
+	resume := flag.Bool("resume", false, "skip the bonds already checked by an interrupted run")
+	checkpointPath := flag.String("checkpoint", "checkpoint/coco.jsonl", "journal of the checked bonds")
+	flag.StringVar(&cerberusHost, "cerberus-url", cerberusHost, "Cerberus host")
+	flag.StringVar(&edgelabBearerToken, "token", edgelabBearerToken, "bearer token")
+	flag.Parse()
+
+	run := manifest.New("coco filter")
+	run.RecordFlags(flag.CommandLine)
+
+	// Read input bonds.
+	bondIDs, err := input.Read(*inputPath, input.Options{Column: *column, Header: *header, Resolver: input.Cerberus(cerberusHost, edgelabBearerToken)})
 	if err != nil {
 		log.Fatal("Error while reading the file", err)
 	}
 
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		log.Fatal("Error while reading the file", err)
+	if err := run.AddInput(*inputPath); err != nil {
+		log.Fatal("Error while hashing the input", err)
 	}
 
-	bondIDs := make([]string, 0)
-	for _, record := range records[1:] {
-		bondIDs = append(bondIDs, record[1])
+	journal, err := checkpoint.Open(*checkpointPath, *resume)
+	if err != nil {
+		log.Fatal("Error while opening the checkpoint", err)
 	}
+	defer journal.Close()
 
 	// Call cerberus for coco bonds.
-	cocoBonds, err := filterCocoBonds(bondIDs)
+	cocoBonds, checked, err := filterCocoBonds(bondIDs, journal)
 	if err != nil {
 		log.Fatal("Error while filtering coco bonds", err)
 	}
+	run.Count("cerberus", len(bondIDs), checked)
 
 	// Write output csv.
 	output, _ := json.MarshalIndent(cocoBonds, "", " ")
-	_ = os.WriteFile("output.json", output, 0644)
+	_ = os.WriteFile(*outputPath, output, 0644)
+
+	if err := run.Write(*outputPath); err != nil {
+		log.Fatal("Error while writing the run manifest", err)
+	}
 
 	log.Info("Done")
 }
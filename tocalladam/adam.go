@@ -1,4 +1,4 @@
-package main
+package tocalladam
 
 import (
 	"bytes"
@@ -32,10 +32,12 @@ type SensitivityOutput struct {
 	Callable  bool
 }
 
-const (
+var (
 	positionsURL = "http://adam-http.service.consul/sensitivity"
 	priceURL     = "http://adam-http.service.consul/price"
 	snapshot     = "2024-05-08T00:30:05Z"
+
+	sensitivityCurrency = "USD"
 )
 
 func sensitivityAdam(ids []string) ([]SensitivityOutput, error) {
@@ -105,7 +107,7 @@ func senstivityCall(ctx context.Context, id, sensitivityType string) (float64, s
 		Snapshot: snapshot,
 		Asset:    id,
 		Metric:   "RHO",
-		Currency: "USD",
+		Currency: sensitivityCurrency,
 		Type:     sensitivityType,
 	}
 
@@ -0,0 +1,9 @@
+// Command tocalladam runs the tocalladam script on its own, outside of the
+// validation command.
+package main
+
+import "tocalladam"
+
+func main() {
+	tocalladam.Main()
+}
@@ -1,4 +1,4 @@
-package main
+package tocalladam
 
 import (
 	"context"
@@ -7,7 +7,7 @@ import (
 	"net/http"
 )
 
-const etymologistURL = "http://etymologist.service.consul/description/assets/"
+var etymologistURL = "http://etymologist.service.consul/description/assets/"
 
 func etymologist(output []SensitivityOutput) ([]SensitivityOutput, error) {
 	ctx := context.Background()
@@ -1,30 +1,41 @@
-package main
+package tocalladam
 
 import (
-	"encoding/csv"
-	"os"
+	"flag"
 
 	log "github.com/sirupsen/logrus"
+
+	"toolkit/input"
+	"toolkit/manifest"
 )
 
-func main() {
-	// Read input csv.
-	file, err := os.Open("input.csv")
-	if err != nil {
-		log.Fatal("Error while reading the file", err)
-	}
+// cerberusHost resolves the ISINs given in the input.
+var cerberusHost = "http://cerberus.service.consul"
 
-	defer file.Close()
+// Main parses the command line flags and runs the script.
+func Main() {
+	inputPath := flag.String("input", "input.csv", "CSV or JSON list of bond IDs or ISINs, - for stdin")
+	column := flag.String("column", "", "name or index of the ID column of a CSV input")
+	flag.StringVar(&cerberusHost, "cerberus-url", cerberusHost, "Cerberus host resolving the ISINs")
+	outputPath := flag.String("output", "output.csv", "output CSV file")
+	flag.StringVar(&snapshot, "snapshot", snapshot, "snapshot of the sensitivities")
+	flag.StringVar(&sensitivityCurrency, "currency", sensitivityCurrency, "currency of the rho sensitivities")
+	flag.StringVar(&positionsURL, "adam-sensitivity-url", positionsURL, "Adam sensitivity endpoint")
+	flag.StringVar(&priceURL, "adam-price-url", priceURL, "Adam price endpoint")
+	flag.StringVar(&etymologistURL, "etymologist-url", etymologistURL, "Etymologist asset description endpoint")
+	flag.Parse()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	run := manifest.New("tocall adam")
+	run.RecordFlags(flag.CommandLine)
+
+	// Read input csv.
+	bondIDs, err := input.Read(*inputPath, input.Options{Column: *column, Resolver: input.Cerberus(cerberusHost, "")})
 	if err != nil {
 		log.Fatal("Error while reading the file", err)
 	}
 
-	bondIDs := make([]string, 0)
-	for _, record := range records[1:] {
-		bondIDs = append(bondIDs, record[0])
+	if err := run.AddInput(*inputPath); err != nil {
+		log.Fatal("Error while hashing the input", err)
 	}
 
 	// Call Adam's sensitivity for each possible value and each parameter of the sensitivity endpoint.
@@ -32,6 +43,7 @@ func main() {
 	if err != nil {
 		log.Fatal("Error while calling sensitivity", err)
 	}
+	run.Count("adam", len(bondIDs), len(output))
 
 	// Call etymologist.
 	output, err = etymologist(output)
@@ -40,10 +52,14 @@ func main() {
 	}
 
 	// Write output csv.
-	err = outputToCsv(output)
+	err = outputToCsv(*outputPath, output)
 	if err != nil {
 		log.Fatalf("Error converting results to csv: %v", err)
 	}
 
+	if err := run.Write(*outputPath); err != nil {
+		log.Fatalf("Error writing the run manifest: %v", err)
+	}
+
 	log.Info("Done")
 }
@@ -1,4 +1,4 @@
-package main
+package tocalleve
 
 import (
 	"bytes"
@@ -32,7 +32,7 @@ type Request struct {
 	Payload json.RawMessage
 }
 
-const (
+var (
 	requestURL = "http://adam-http.service.consul/debug/dump/request"
 	snapshot   = "2024-04-11T00:30:05Z"
 )
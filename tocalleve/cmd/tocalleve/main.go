@@ -0,0 +1,9 @@
+// Command tocalleve runs the tocalleve script on its own, outside of the
+// validation command.
+package main
+
+import "tocalleve"
+
+func main() {
+	tocalleve.Main()
+}
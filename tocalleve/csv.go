@@ -1,4 +1,4 @@
-package main
+package tocalleve
 
 import (
 	"encoding/csv"
@@ -8,10 +8,10 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func outputToCsv(output []SensitivitiesOutput) error {
+func outputToCsv(outputPath string, output []SensitivitiesOutput) error {
 	log.Infof("Building output csv")
 
-	csvFile, err := os.Create("output.csv")
+	csvFile, err := os.Create(outputPath)
 	if err != nil {
 		return fmt.Errorf("error while creating report file: %s", err)
 	}
@@ -1,4 +1,4 @@
-package main
+package tocalleve
 
 import (
 	"bytes"
@@ -38,7 +38,7 @@ type SenstitivitiesBody struct {
 	Convexity map[string]PriceResult `json:"convexity"`
 }
 
-const eveURL = "http://eve-live.service.consul/debug/value"
+var eveURL = "http://eve-live.service.consul/debug/value"
 
 func priceEve(requests []Request) ([]SensitivitiesOutput, error) {
 	ctx := context.Background()
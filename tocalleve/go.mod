@@ -13,3 +13,7 @@ require (
 	github.com/edgelaboratories/go-libraries/daycount v1.0.0 // indirect
 	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
 )
+
+require toolkit v0.0.0
+
+replace toolkit => ../toolkit
@@ -1,30 +1,39 @@
-package main
+package tocalleve
 
 import (
-	"encoding/csv"
-	"os"
+	"flag"
 
 	log "github.com/sirupsen/logrus"
+
+	"toolkit/input"
+	"toolkit/manifest"
 )
 
-func main() {
-	// Read input csv.
-	file, err := os.Open("input.csv")
-	if err != nil {
-		log.Fatal("Error while reading the file", err)
-	}
+// cerberusHost resolves the ISINs given in the input.
+var cerberusHost = "http://cerberus.service.consul"
+
+// Main parses the command line flags and runs the script.
+func Main() {
+	inputPath := flag.String("input", "input.csv", "CSV or JSON list of bond IDs or ISINs, - for stdin")
+	column := flag.String("column", "", "name or index of the ID column of a CSV input")
+	flag.StringVar(&cerberusHost, "cerberus-url", cerberusHost, "Cerberus host resolving the ISINs")
+	outputPath := flag.String("output", "output.csv", "output CSV file")
+	flag.StringVar(&snapshot, "snapshot", snapshot, "snapshot of the Adam dumps")
+	flag.StringVar(&requestURL, "adam-url", requestURL, "Adam dump endpoint")
+	flag.StringVar(&eveURL, "eve-url", eveURL, "Eve debug endpoint")
+	flag.Parse()
 
-	defer file.Close()
+	run := manifest.New("tocall eve")
+	run.RecordFlags(flag.CommandLine)
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	// Read input csv.
+	bondIDs, err := input.Read(*inputPath, input.Options{Column: *column, Resolver: input.Cerberus(cerberusHost, "")})
 	if err != nil {
 		log.Fatal("Error while reading the file", err)
 	}
 
-	bondIDs := make([]string, 0)
-	for _, record := range records[1:] {
-		bondIDs = append(bondIDs, record[0])
+	if err := run.AddInput(*inputPath); err != nil {
+		log.Fatal("Error while hashing the input", err)
 	}
 
 	log.Info("Starting the process in Adam")
@@ -34,6 +43,7 @@ func main() {
 	if err != nil {
 		log.Fatal("Error while calling request", err)
 	}
+	run.Count("adam", len(bondIDs), len(output))
 
 	log.Info("Starting the process in Eve")
 
@@ -42,11 +52,15 @@ func main() {
 	if err != nil {
 		log.Fatal("Error while calling eve : ", err)
 	}
+	run.Count("eve", len(output), len(sensitivities))
 
 	// Write output csv.
-	err = outputToCsv(sensitivities)
+	err = outputToCsv(*outputPath, sensitivities)
 	if err != nil {
 		log.Fatalf("Error converting results to csv: %v", err)
 	}
 
+	if err := run.Write(*outputPath); err != nil {
+		log.Fatalf("Error writing the run manifest: %v", err)
+	}
 }
@@ -0,0 +1,292 @@
+/*
+Package backtest backtests predicted VaR and ES against realised P&L.
+
+As in package risk, values follow the P&L sign: VaR and ES are negative for a
+loss, and an exception is a day whose P&L falls below the VaR. The tests are
+the Kupiec proportion of failures test, the Christoffersen independence and
+conditional coverage tests, the Basel traffic light scaled to the sample size,
+and the Z1 and Z2 ES tests of Acerbi and Szekely (2014).
+*/
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Observation is the predicted VaR and ES of a day and the P&L realised over
+// the following period.
+type Observation struct {
+	Date time.Time `json:"date"`
+	VaR  float64   `json:"var"`
+	ES   float64   `json:"es"`
+	PnL  float64   `json:"pnl"`
+}
+
+// Test is the outcome of a likelihood-ratio test.
+type Test struct {
+	Statistic float64 `json:"statistic"`
+	PValue    float64 `json:"pValue"`
+
+	// Reject tells whether the model is rejected at the significance level.
+	Reject bool `json:"reject"`
+}
+
+// Traffic-light zones.
+const (
+	Green  = "green"
+	Yellow = "yellow"
+	Red    = "red"
+)
+
+// Thresholds of the Acerbi-Szekely Z2 statistic at the 5% and 0.01%
+// significance levels, stable across the distributions of their paper.
+const (
+	Z2Yellow = -0.70
+	Z2Red    = -1.80
+)
+
+// Result is the backtest of a series.
+type Result struct {
+	ID           string  `json:"id"`
+	Confidence   float64 `json:"confidence"`
+	Significance float64 `json:"significance"`
+	From         string  `json:"from"`
+	To           string  `json:"to"`
+
+	Observations       int     `json:"observations"`
+	Exceptions         int     `json:"exceptions"`
+	ExpectedExceptions float64 `json:"expectedExceptions"`
+	ExceptionRate      float64 `json:"exceptionRate"`
+
+	Kupiec              Test `json:"kupiec"`
+	Independence        Test `json:"independence"`
+	ConditionalCoverage Test `json:"conditionalCoverage"`
+
+	// Zone is the traffic light of the exception count, ZoneProbability
+	// the probability of at most that many exceptions under the model.
+	Zone            string  `json:"zone"`
+	ZoneProbability float64 `json:"zoneProbability"`
+
+	// Z1 is only defined with exceptions and ES predictions, Z2 with ES
+	// predictions. ESZone is the traffic light of Z2.
+	Z1     *float64 `json:"z1,omitempty"`
+	Z2     *float64 `json:"z2,omitempty"`
+	ESZone string   `json:"esZone,omitempty"`
+}
+
+// ErrNoObservation is returned for an empty series.
+var ErrNoObservation = errors.New("no observation")
+
+// Run backtests the observations at the confidence level of the predictions;
+// tests reject at the significance level. Observations are sorted by date.
+// The ES tests are skipped when an ES prediction is zero.
+func Run(id string, obs []Observation, confidence, significance float64) (Result, error) {
+	if confidence <= 0 || confidence >= 1 {
+		return Result{}, fmt.Errorf("confidence level %v is not in (0, 1)", confidence)
+	}
+
+	if significance <= 0 || significance >= 1 {
+		return Result{}, fmt.Errorf("significance level %v is not in (0, 1)", significance)
+	}
+
+	if len(obs) == 0 {
+		return Result{}, ErrNoObservation
+	}
+
+	sorted := make([]Observation, len(obs))
+	copy(sorted, obs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	p := 1 - confidence
+	n := len(sorted)
+
+	hits := make([]bool, n)
+	x := 0
+	for i, o := range sorted {
+		if o.PnL < o.VaR {
+			hits[i] = true
+			x++
+		}
+	}
+
+	r := Result{
+		ID:                 id,
+		Confidence:         confidence,
+		Significance:       significance,
+		From:               sorted[0].Date.Format(time.DateOnly),
+		To:                 sorted[n-1].Date.Format(time.DateOnly),
+		Observations:       n,
+		Exceptions:         x,
+		ExpectedExceptions: p * float64(n),
+		ExceptionRate:      float64(x) / float64(n),
+	}
+
+	r.Kupiec = chiSquareTest(kupiec(n, x, p), 1, significance)
+	lrInd := independence(hits)
+	r.Independence = chiSquareTest(lrInd, 1, significance)
+	r.ConditionalCoverage = chiSquareTest(r.Kupiec.Statistic+lrInd, 2, significance)
+
+	r.ZoneProbability = binomialCDF(x, n, p)
+	r.Zone = zone(r.ZoneProbability)
+
+	if z1, z2, ok := acerbiSzekely(sorted, hits, p); ok {
+		r.Z2 = &z2
+		if x > 0 {
+			r.Z1 = &z1
+		}
+
+		switch {
+		case z2 <= Z2Red:
+			r.ESZone = Red
+		case z2 <= Z2Yellow:
+			r.ESZone = Yellow
+		default:
+			r.ESZone = Green
+		}
+	}
+
+	return r, nil
+}
+
+// kupiec returns the likelihood ratio of x exceptions out of n against an
+// exception probability p.
+func kupiec(n, x int, p float64) float64 {
+	observed := float64(x) / float64(n)
+
+	return -2 * (logLikelihood(n-x, x, p) - logLikelihood(n-x, x, observed))
+}
+
+// independence returns the Christoffersen likelihood ratio of a first-order
+// Markov chain of exceptions against independent exceptions.
+func independence(hits []bool) float64 {
+	var n00, n01, n10, n11 int
+	for i := 1; i < len(hits); i++ {
+		switch {
+		case !hits[i-1] && !hits[i]:
+			n00++
+		case !hits[i-1] && hits[i]:
+			n01++
+		case hits[i-1] && !hits[i]:
+			n10++
+		default:
+			n11++
+		}
+	}
+
+	total := n00 + n01 + n10 + n11
+	if total == 0 {
+		return 0
+	}
+
+	pi := float64(n01+n11) / float64(total)
+	pi01, pi11 := 0.0, 0.0
+	if n00+n01 > 0 {
+		pi01 = float64(n01) / float64(n00+n01)
+	}
+	if n10+n11 > 0 {
+		pi11 = float64(n11) / float64(n10+n11)
+	}
+
+	restricted := logLikelihood(n00+n10, n01+n11, pi)
+	unrestricted := logLikelihood(n00, n01, pi01) + logLikelihood(n10, n11, pi11)
+
+	return math.Max(0, -2*(restricted-unrestricted))
+}
+
+// logLikelihood returns log((1-p)^misses · p^hits), 0·log(0) being 0.
+func logLikelihood(misses, hits int, p float64) float64 {
+	ll := 0.0
+	if misses > 0 {
+		ll += float64(misses) * math.Log(1-p)
+	}
+	if hits > 0 {
+		ll += float64(hits) * math.Log(p)
+	}
+
+	return ll
+}
+
+// acerbiSzekely returns the Z1 and Z2 statistics, false when an ES
+// prediction is zero. Both are 0 when the model is right and negative when it
+// underestimates the risk.
+func acerbiSzekely(obs []Observation, hits []bool, p float64) (float64, float64, bool) {
+	var sum float64
+	x := 0
+	for i, o := range obs {
+		if o.ES == 0 {
+			return 0, 0, false
+		}
+
+		if hits[i] {
+			sum += o.PnL / o.ES
+			x++
+		}
+	}
+
+	z2 := 1 - sum/(float64(len(obs))*p)
+
+	z1 := 0.0
+	if x > 0 {
+		z1 = 1 - sum/float64(x)
+	}
+
+	return z1, z2, true
+}
+
+func chiSquareTest(statistic float64, dof int, significance float64) Test {
+	pValue := chiSquareSurvival(statistic, dof)
+
+	return Test{Statistic: statistic, PValue: pValue, Reject: pValue < significance}
+}
+
+// chiSquareSurvival returns P(X > x) for a chi-square of 1 or 2 degrees of
+// freedom.
+func chiSquareSurvival(x float64, dof int) float64 {
+	if x <= 0 {
+		return 1
+	}
+
+	if dof == 2 {
+		return math.Exp(-x / 2)
+	}
+
+	return math.Erfc(math.Sqrt(x / 2))
+}
+
+// binomialCDF returns P(X <= x) for X binomial of n trials of probability p.
+func binomialCDF(x, n int, p float64) float64 {
+	cdf := 0.0
+	for k := 0; k <= x && k <= n; k++ {
+		cdf += math.Exp(logChoose(n, k) + float64(k)*math.Log(p) + float64(n-k)*math.Log1p(-p))
+	}
+
+	return math.Min(cdf, 1)
+}
+
+func logChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+
+	return a - b - c
+}
+
+// zone returns the Basel traffic light of a cumulative probability: green
+// below 95%, red from 99.99%. At 99% over 250 days, green is up to 4
+// exceptions and red from 10.
+func zone(cdf float64) string {
+	switch {
+	case cdf < 0.95:
+		return Green
+	case cdf < 0.9999:
+		return Yellow
+	default:
+		return Red
+	}
+}
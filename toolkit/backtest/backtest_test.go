@@ -0,0 +1,128 @@
+package backtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// series returns n days with a VaR of -1 and an ES of -1.5, the P&L breaching
+// the VaR at the given days.
+func series(n int, pnl float64, exceptions ...int) []Observation {
+	obs := make([]Observation, n)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range obs {
+		obs[i] = Observation{Date: start.AddDate(0, 0, i), VaR: -1, ES: -1.5, PnL: 0.1}
+	}
+
+	for _, i := range exceptions {
+		obs[i].PnL = pnl
+	}
+
+	return obs
+}
+
+func Test_Run_TrafficLight(t *testing.T) {
+	t.Parallel()
+
+	for exceptions, want := range map[int]string{0: Green, 4: Green, 5: Yellow, 9: Yellow, 10: Red} {
+		days := make([]int, exceptions)
+		for i := range days {
+			days[i] = 20 * i
+		}
+
+		r, err := Run("a", series(250, -2, days...), 0.99, 0.05)
+		require.NoError(t, err)
+		assert.Equal(t, want, r.Zone, "%d exceptions", exceptions)
+		assert.Equal(t, exceptions, r.Exceptions)
+	}
+}
+
+func Test_Run_Kupiec(t *testing.T) {
+	t.Parallel()
+
+	// 2.5 exceptions expected: 2 is fine, 12 is not.
+	r, err := Run("a", series(250, -2, 10, 100), 0.99, 0.05)
+	require.NoError(t, err)
+	assert.InDelta(t, 2.5, r.ExpectedExceptions, 1e-12)
+	assert.False(t, r.Kupiec.Reject)
+	assert.Greater(t, r.Kupiec.PValue, 0.5)
+
+	days := []int{1, 20, 40, 60, 80, 100, 120, 140, 160, 180, 200, 220}
+	r, err = Run("a", series(250, -2, days...), 0.99, 0.05)
+	require.NoError(t, err)
+	assert.True(t, r.Kupiec.Reject)
+	assert.False(t, r.Independence.Reject)
+
+	// LR = -2 [248 ln 0.99 + 2 ln 0.01 - 248 ln(248/250) - 2 ln(2/250)].
+	r, err = Run("a", series(250, -2, 10, 100), 0.99, 0.05)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.1076, r.Kupiec.Statistic, 1e-3)
+}
+
+func Test_Run_Independence(t *testing.T) {
+	t.Parallel()
+
+	// Five exceptions in a row.
+	r, err := Run("a", series(250, -2, 100, 101, 102, 103, 104), 0.99, 0.05)
+	require.NoError(t, err)
+	assert.True(t, r.Independence.Reject)
+	assert.True(t, r.ConditionalCoverage.Reject)
+}
+
+func Test_Run_AcerbiSzekely(t *testing.T) {
+	t.Parallel()
+
+	// Exceptions exactly at the ES, as many as expected: Z1 = Z2 = 0.
+	obs := series(100, -1.5, 10, 20, 30, 40, 50, 60, 70, 80, 90, 99)
+	r, err := Run("a", obs, 0.9, 0.05)
+	require.NoError(t, err)
+	require.NotNil(t, r.Z1)
+	require.NotNil(t, r.Z2)
+	assert.InDelta(t, 0.0, *r.Z1, 1e-12)
+	assert.InDelta(t, 0.0, *r.Z2, 1e-12)
+	assert.Equal(t, Green, r.ESZone)
+
+	// Exceptions twice as deep as the ES.
+	obs = series(100, -3, 10, 20, 30, 40, 50, 60, 70, 80, 90, 99)
+	r, err = Run("a", obs, 0.9, 0.05)
+	require.NoError(t, err)
+	assert.InDelta(t, -1.0, *r.Z2, 1e-12)
+	assert.Equal(t, Yellow, r.ESZone)
+
+	// No ES prediction.
+	for i := range obs {
+		obs[i].ES = 0
+	}
+	r, err = Run("a", obs, 0.9, 0.05)
+	require.NoError(t, err)
+	assert.Nil(t, r.Z2)
+	assert.Empty(t, r.ESZone)
+}
+
+func Test_ReadCSV(t *testing.T) {
+	t.Parallel()
+
+	raw := "Date,ID,VaR,ES,PnL\n" +
+		"2024-01-02,a,1,1.5,-0.2\n" +
+		"2024-01-01,b,2,,3\n" +
+		"2024-01-03T00:00:00Z,a,1,1.5,2\n"
+
+	series, err := ReadCSV(strings.NewReader(raw), true)
+	require.NoError(t, err)
+	require.Len(t, series, 2)
+	assert.Equal(t, "a", series[0].ID)
+	assert.Len(t, series[0].Observations, 2)
+	assert.Equal(t, Observation{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), VaR: -1, ES: -1.5, PnL: 0.2}, series[0].Observations[0])
+	assert.Equal(t, -2.0, series[1].Observations[0].VaR)
+
+	series, err = ReadCSV(strings.NewReader("date,var,pnl\n2024-01-01,-1,0.5\n"), false)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultID, series[0].ID)
+
+	_, err = ReadCSV(strings.NewReader("date,pnl\n"), false)
+	assert.ErrorContains(t, err, "var")
+}
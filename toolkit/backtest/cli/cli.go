@@ -0,0 +1,163 @@
+// Package cli implements the backtest command, see toolkit/cmd/backtest.
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"toolkit/backtest"
+	"toolkit/manifest"
+)
+
+var (
+	inputPath    = "backtest.csv"
+	outputPath   = ""
+	confidence   = 0.99
+	significance = 0.05
+	lossPositive = false
+)
+
+// Main parses the command line and runs the backtest command.
+func Main() {
+	flag.StringVar(&inputPath, "input", inputPath, "CSV of date, id, var, es and pnl, - for stdin")
+	flag.StringVar(&outputPath, "output", outputPath, "optional .json or .csv report")
+	flag.Float64Var(&confidence, "confidence", confidence, "confidence level of the predicted VaR and ES")
+	flag.Float64Var(&significance, "significance", significance, "significance level of the tests")
+	flag.BoolVar(&lossPositive, "loss-positive", lossPositive, "read VaR, ES and P&L as positive losses")
+	flag.Parse()
+
+	run := manifest.New("backtest")
+	run.RecordFlags(flag.CommandLine)
+
+	in := os.Stdin
+	if inputPath != "-" {
+		file, err := os.Open(inputPath)
+		if err != nil {
+			log.Fatalf("could not open %s: %v", inputPath, err)
+		}
+		defer file.Close()
+
+		in = file
+	}
+
+	series, err := backtest.ReadCSV(in, lossPositive)
+	if err != nil {
+		log.Fatalf("could not read %s: %v", inputPath, err)
+	}
+
+	if err := run.AddInput(inputPath); err != nil {
+		log.Fatal(err)
+	}
+
+	results := make([]backtest.Result, 0, len(series))
+	for _, s := range series {
+		r, err := backtest.Run(s.ID, s.Observations, confidence, significance)
+		if err != nil {
+			log.Fatalf("could not backtest %s: %v", s.ID, err)
+		}
+
+		results = append(results, r)
+	}
+	run.Count("backtest", len(series), len(results))
+
+	printResults(os.Stdout, results)
+
+	if outputPath == "" {
+		return
+	}
+
+	if err := write(outputPath, results); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := run.Write(outputPath); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printResults(w io.Writer, results []backtest.Result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDAYS\tEXCEPTIONS\tEXPECTED\tZONE\tKUPIEC P\tINDEP P\tCC P\tZ1\tZ2\tES ZONE")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.ID, r.Observations, r.Exceptions, r.ExpectedExceptions, r.Zone,
+			pValue(r.Kupiec), pValue(r.Independence), pValue(r.ConditionalCoverage),
+			optional(r.Z1), optional(r.Z2), r.ESZone)
+	}
+	tw.Flush()
+}
+
+func write(path string, results []backtest.Result) error {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		raw, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not marshal report: %w", err)
+		}
+
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			return fmt.Errorf("could not write %s: %w", path, err)
+		}
+
+		return nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	_ = writer.Write([]string{
+		"id", "from", "to", "confidence", "observations", "exceptions", "expectedExceptions", "zone", "zoneProbability",
+		"kupiecLR", "kupiecP", "independenceLR", "independenceP", "conditionalCoverageLR", "conditionalCoverageP", "z1", "z2", "esZone",
+	})
+
+	for _, r := range results {
+		_ = writer.Write([]string{
+			r.ID, r.From, r.To, formatFloat(r.Confidence), strconv.Itoa(r.Observations), strconv.Itoa(r.Exceptions),
+			formatFloat(r.ExpectedExceptions), r.Zone, formatFloat(r.ZoneProbability),
+			formatFloat(r.Kupiec.Statistic), formatFloat(r.Kupiec.PValue),
+			formatFloat(r.Independence.Statistic), formatFloat(r.Independence.PValue),
+			formatFloat(r.ConditionalCoverage.Statistic), formatFloat(r.ConditionalCoverage.PValue),
+			optional(r.Z1), optional(r.Z2), r.ESZone,
+		})
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// pValue formats the p-value of the test, starred when the test rejects.
+func pValue(t backtest.Test) string {
+	if t.Reject {
+		return fmt.Sprintf("%.3g*", t.PValue)
+	}
+
+	return fmt.Sprintf("%.3g", t.PValue)
+}
+
+func optional(v *float64) string {
+	if v == nil {
+		return ""
+	}
+
+	return formatFloat(*v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
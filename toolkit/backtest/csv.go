@@ -0,0 +1,131 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultID names the series of a CSV without id column.
+const DefaultID = "portfolio"
+
+// Series is the observations of an asset or a portfolio.
+type Series struct {
+	ID           string
+	Observations []Observation
+}
+
+// ReadCSV reads series from a CSV with a header holding date, var, pnl and
+// optionally id and es columns, in any order and case. Dates are YYYY-MM-DD or
+// RFC 3339. Rows without id belong to DefaultID. When lossPositive is set, VaR,
+// ES and P&L are read as positive losses and turned to the P&L sign. Series
+// come in order of first appearance.
+func ReadCSV(r io.Reader, lossPositive bool) ([]Series, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\xef\xbb\xbf")))] = i
+	}
+
+	for _, required := range []string{"date", "var", "pnl"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing column %q", required)
+		}
+	}
+
+	sign := 1.0
+	if lossPositive {
+		sign = -1.0
+	}
+
+	index := make(map[string]int)
+	series := make([]Series, 0)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read line %d: %w", line, err)
+		}
+
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+
+			return strings.TrimSpace(record[i])
+		}
+
+		date, err := parseDate(field("date"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var o Observation
+		o.Date = date
+		for _, v := range []struct {
+			name     string
+			target   *float64
+			optional bool
+		}{{"var", &o.VaR, false}, {"es", &o.ES, true}, {"pnl", &o.PnL, false}} {
+			raw := field(v.name)
+			if raw == "" && v.optional {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s: %w", line, v.name, err)
+			}
+
+			*v.target = sign * value
+		}
+
+		id := field("id")
+		if id == "" {
+			id = DefaultID
+		}
+
+		i, ok := index[id]
+		if !ok {
+			i = len(series)
+			index[id] = i
+			series = append(series, Series{ID: id})
+		}
+
+		series[i].Observations = append(series[i].Observations, o)
+	}
+
+	return series, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, nil
+	}
+
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	return date, nil
+}
@@ -0,0 +1,292 @@
+/*
+Package cache provides a content-addressed on-disk cache for the responses of
+the services called by the validation scripts.
+
+An entry is keyed on the service name, the HTTP method, the URL, the snapshot
+and the hash of the request body. Responses of snapshot-bound endpoints never
+change and are stored without expiry, while the others (e.g. Cerberus
+descriptions) are stored with a TTL.
+*/
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	metaSuffix = ".meta.json"
+	bodySuffix = ".body"
+)
+
+// Key identifies a cached response.
+type Key struct {
+	Service  string
+	Method   string
+	URL      string
+	Snapshot string
+	Body     []byte
+}
+
+// Hash returns the content address of the key.
+func (k Key) Hash() string {
+	h := sha256.New()
+	for _, part := range []string{k.Service, k.Method, k.URL, k.Snapshot, requestHash(k.Body)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func requestHash(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Entry describes a cached response, without its body.
+type Entry struct {
+	Hash        string     `json:"hash"`
+	Service     string     `json:"service"`
+	Method      string     `json:"method"`
+	URL         string     `json:"url"`
+	Snapshot    string     `json:"snapshot,omitempty"`
+	RequestHash string     `json:"requestHash"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	Size        int64      `json:"size"`
+}
+
+// Expired tells whether the entry is past its TTL at the given time.
+func (e Entry) Expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
+}
+
+// Cache is a response cache rooted in a directory, with one sub-directory per service.
+type Cache struct {
+	dir string
+	now func() time.Time
+}
+
+// Open creates the cache directory if needed and returns the cache rooted there.
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create cache directory %s: %w", dir, err)
+	}
+
+	return &Cache{dir: dir, now: time.Now}, nil
+}
+
+// Dir returns the root directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+func (c *Cache) path(service, hash string) string {
+	return filepath.Join(c.dir, sanitize(service), hash)
+}
+
+// Get returns the cached body for the key, if any and not expired.
+func (c *Cache) Get(key Key) ([]byte, bool, error) {
+	hash := key.Hash()
+	base := c.path(key.Service, hash)
+
+	entry, err := readEntry(base + metaSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if entry.Expired(c.now()) {
+		return nil, false, nil
+	}
+
+	body, err := os.ReadFile(base + bodySuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("could not read cached body %s: %w", hash, err)
+	}
+
+	return body, true, nil
+}
+
+// Put stores the body for the key. A zero TTL means the entry never expires.
+func (c *Cache) Put(key Key, body []byte, ttl time.Duration) error {
+	hash := key.Hash()
+	base := c.path(key.Service, hash)
+
+	if err := os.MkdirAll(filepath.Dir(base), 0o755); err != nil {
+		return fmt.Errorf("could not create cache directory for service %s: %w", key.Service, err)
+	}
+
+	now := c.now().UTC()
+	entry := Entry{
+		Hash:        hash,
+		Service:     key.Service,
+		Method:      key.Method,
+		URL:         key.URL,
+		Snapshot:    key.Snapshot,
+		RequestHash: requestHash(key.Body),
+		CreatedAt:   now,
+		Size:        int64(len(body)),
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		entry.ExpiresAt = &expiresAt
+	}
+
+	meta, err := json.MarshalIndent(entry, "", " ")
+	if err != nil {
+		return fmt.Errorf("could not marshal cache entry %s: %w", hash, err)
+	}
+
+	// The body goes first: an entry is only visible once its metadata exists.
+	if err := writeAtomic(base+bodySuffix, body); err != nil {
+		return err
+	}
+
+	return writeAtomic(base+metaSuffix, meta)
+}
+
+// Entries lists every entry of the cache, sorted by service then creation time.
+func (c *Cache) Entries() ([]Entry, error) {
+	entries := make([]Entry, 0)
+
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !strings.HasSuffix(path, metaSuffix) {
+			return nil
+		}
+
+		entry, err := readEntry(path)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, entry)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list cache entries: %w", err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Service != entries[j].Service {
+			return entries[i].Service < entries[j].Service
+		}
+
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+
+	return entries, nil
+}
+
+// Invalidate removes every entry matched by the filter and returns how many were removed.
+func (c *Cache) Invalidate(match func(Entry) bool) (int, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !match(entry) {
+			continue
+		}
+
+		base := c.path(entry.Service, entry.Hash)
+		if err := os.Remove(base + metaSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, fmt.Errorf("could not remove cache entry %s: %w", entry.Hash, err)
+		}
+		if err := os.Remove(base + bodySuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, fmt.Errorf("could not remove cache entry %s: %w", entry.Hash, err)
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
+
+// Expired is a filter matching the entries past their TTL.
+func (c *Cache) Expired() func(Entry) bool {
+	now := c.now()
+
+	return func(e Entry) bool {
+		return e.Expired(now)
+	}
+}
+
+func readEntry(path string) (Entry, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, fmt.Errorf("could not read cache entry %s: %w", path, err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return Entry{}, fmt.Errorf("could not unmarshal cache entry %s: %w", path, err)
+	}
+
+	return entry, nil
+}
+
+func writeAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not create temporary cache file: %w", err)
+	}
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return fmt.Errorf("could not write cache file %s: %w", path, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+
+		return fmt.Errorf("could not close cache file %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+
+		return fmt.Errorf("could not move cache file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func sanitize(service string) string {
+	if service == "" {
+		return "default"
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == ' ' {
+			return '_'
+		}
+
+		return r
+	}, service)
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Key_Hash(t *testing.T) {
+	t.Parallel()
+
+	base := Key{Service: "adam", Method: "POST", URL: "http://adam/debug/dump/request", Snapshot: "2024-10-13T19:30:05Z", Body: []byte(`{"asset":"a"}`)}
+
+	assert.Equal(t, base.Hash(), base.Hash())
+
+	for _, other := range []Key{
+		{Service: "eve", Method: base.Method, URL: base.URL, Snapshot: base.Snapshot, Body: base.Body},
+		{Service: base.Service, Method: "GET", URL: base.URL, Snapshot: base.Snapshot, Body: base.Body},
+		{Service: base.Service, Method: base.Method, URL: "http://adam/other", Snapshot: base.Snapshot, Body: base.Body},
+		{Service: base.Service, Method: base.Method, URL: base.URL, Snapshot: "2024-10-14T00:30:04Z", Body: base.Body},
+		{Service: base.Service, Method: base.Method, URL: base.URL, Snapshot: base.Snapshot, Body: []byte(`{"asset":"b"}`)},
+	} {
+		assert.NotEqual(t, base.Hash(), other.Hash())
+	}
+}
+
+func Test_Cache_GetPut(t *testing.T) {
+	t.Parallel()
+
+	c, err := Open(t.TempDir())
+	require.NoError(t, err)
+
+	key := Key{Service: "arcanist", Method: "POST", URL: "http://arcanist/v6", Snapshot: "s", Body: []byte("{}")}
+
+	_, ok, err := c.Get(key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, c.Put(key, []byte(`{"results":{}}`), 0))
+
+	body, ok, err := c.Get(key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `{"results":{}}`, string(body))
+
+	entries, err := c.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "arcanist", entries[0].Service)
+	assert.Nil(t, entries[0].ExpiresAt)
+	assert.EqualValues(t, 14, entries[0].Size)
+}
+
+func Test_Cache_TTL(t *testing.T) {
+	t.Parallel()
+
+	c, err := Open(t.TempDir())
+	require.NoError(t, err)
+
+	now := time.Date(2024, 10, 14, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	key := Key{Service: "cerberus", Method: "GET", URL: "http://cerberus/issuers/x"}
+	require.NoError(t, c.Put(key, []byte("{}"), time.Hour))
+
+	_, ok, err := c.Get(key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Hour)
+
+	_, ok, err = c.Get(key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	removed, err := c.Invalidate(c.Expired())
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	entries, err := c.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func Test_Cache_Invalidate(t *testing.T) {
+	t.Parallel()
+
+	c, err := Open(t.TempDir())
+	require.NoError(t, err)
+
+	for i, service := range []string{"adam", "eve", "eve"} {
+		key := Key{Service: service, Method: "POST", URL: "http://" + service, Body: []byte{byte(i)}}
+		require.NoError(t, c.Put(key, []byte("{}"), 0))
+	}
+
+	removed, err := c.Invalidate(func(e Entry) bool { return e.Service == "eve" })
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	entries, err := c.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "adam", entries[0].Service)
+}
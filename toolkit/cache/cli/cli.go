@@ -0,0 +1,155 @@
+// Package cli implements the cache command, see toolkit/cmd/cache.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"toolkit/cache"
+)
+
+const usage = `Usage: cache <list|stats|invalidate> [flags]
+
+  list        print the cached entries
+  stats       print the number and size of entries per service
+  invalidate  remove the matching entries (-all to remove everything)
+`
+
+// Main parses the command line and runs the cache command.
+func Main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	dir := flags.String("dir", ".cache", "cache directory")
+	service := flags.String("service", "", "only match entries of this service")
+	snapshot := flags.String("snapshot", "", "only match entries of this snapshot")
+	urlPart := flags.String("url", "", "only match entries whose URL contains this string")
+	olderThan := flags.Duration("older-than", 0, "only match entries created more than this duration ago")
+	expired := flags.Bool("expired", false, "only match entries past their TTL")
+	all := flags.Bool("all", false, "match every entry (required by invalidate when no other filter is set)")
+	_ = flags.Parse(os.Args[2:])
+
+	c, err := cache.Open(*dir)
+	if err != nil {
+		log.Fatalf("could not open cache: %v", err)
+	}
+
+	now := time.Now()
+	isExpired := c.Expired()
+	filtered := *service != "" || *snapshot != "" || *urlPart != "" || *olderThan > 0 || *expired
+	match := func(e cache.Entry) bool {
+		if *service != "" && e.Service != *service {
+			return false
+		}
+		if *snapshot != "" && e.Snapshot != *snapshot {
+			return false
+		}
+		if *urlPart != "" && !strings.Contains(e.URL, *urlPart) {
+			return false
+		}
+		if *olderThan > 0 && now.Sub(e.CreatedAt) < *olderThan {
+			return false
+		}
+		if *expired && !isExpired(e) {
+			return false
+		}
+
+		return true
+	}
+
+	switch command {
+	case "list":
+		entries, err := c.Entries()
+		if err != nil {
+			log.Fatalf("could not list cache: %v", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "service\tmethod\tsnapshot\tcreated\texpires\tsize\turl")
+		for _, e := range entries {
+			if !match(e) {
+				continue
+			}
+
+			expires := "never"
+			if e.ExpiresAt != nil {
+				expires = e.ExpiresAt.Format(time.RFC3339)
+				if isExpired(e) {
+					expires += " (expired)"
+				}
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", e.Service, e.Method, e.Snapshot, e.CreatedAt.Format(time.RFC3339), expires, e.Size, e.URL)
+		}
+		w.Flush()
+
+	case "stats":
+		entries, err := c.Entries()
+		if err != nil {
+			log.Fatalf("could not list cache: %v", err)
+		}
+
+		type serviceStats struct {
+			count, expired int
+			size           int64
+		}
+		stats := make(map[string]*serviceStats)
+		for _, e := range entries {
+			if !match(e) {
+				continue
+			}
+
+			s, ok := stats[e.Service]
+			if !ok {
+				s = &serviceStats{}
+				stats[e.Service] = s
+			}
+
+			s.count++
+			s.size += e.Size
+			if isExpired(e) {
+				s.expired++
+			}
+		}
+
+		services := make([]string, 0, len(stats))
+		for s := range stats {
+			services = append(services, s)
+		}
+		sort.Strings(services)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "service\tentries\texpired\tsize (MB)")
+		for _, s := range services {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%.2f\n", s, stats[s].count, stats[s].expired, float64(stats[s].size)/1e6)
+		}
+		w.Flush()
+
+	case "invalidate":
+		if !filtered && !*all {
+			log.Fatal("refusing to invalidate the whole cache without -all")
+		}
+
+		removed, err := c.Invalidate(match)
+		if err != nil {
+			log.Fatalf("could not invalidate cache: %v", err)
+		}
+
+		log.Printf("Removed %d entries from %s", removed, c.Dir())
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Package checkpoint provides a JSONL journal of the items completed by a
+long-running batch, so that an interrupted run can resume where it stopped.
+
+Each line of the journal holds the ID of a completed item and its result. A
+line cut by a crash is dropped when the journal is reopened.
+*/
+package checkpoint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type record struct {
+	ID     string          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	At     time.Time       `json:"at"`
+}
+
+// Journal records completed items. It is safe for concurrent use.
+type Journal struct {
+	mu      sync.Mutex
+	file    *os.File
+	results map[string]json.RawMessage
+	order   []string
+}
+
+// Open opens the journal at path, creating its directory if needed. When
+// resume is set the items of a previous run are loaded, otherwise the journal
+// is truncated.
+func Open(path string, resume bool) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("could not create checkpoint directory: %w", err)
+	}
+
+	flags := os.O_RDWR | os.O_CREATE
+	if !resume {
+		flags |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open checkpoint %s: %w", path, err)
+	}
+
+	j := &Journal{
+		file:    file,
+		results: make(map[string]json.RawMessage),
+	}
+
+	if err := j.load(); err != nil {
+		file.Close()
+
+		return nil, fmt.Errorf("could not load checkpoint %s: %w", path, err)
+	}
+
+	return j, nil
+}
+
+// load reads the existing records and truncates the file after the last
+// complete one, so that new records are appended on a clean line.
+func (j *Journal) load() error {
+	reader := bufio.NewReader(j.file)
+
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var r record
+		if err := json.Unmarshal(bytes.TrimSpace(line), &r); err != nil || r.ID == "" {
+			break
+		}
+
+		if _, ok := j.results[r.ID]; !ok {
+			j.order = append(j.order, r.ID)
+		}
+		j.results[r.ID] = r.Result
+
+		offset += int64(len(line))
+	}
+
+	if err := j.file.Truncate(offset); err != nil {
+		return err
+	}
+
+	_, err := j.file.Seek(offset, io.SeekStart)
+
+	return err
+}
+
+// Len returns the number of completed items.
+func (j *Journal) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return len(j.order)
+}
+
+// Done tells whether the item was completed.
+func (j *Journal) Done(id string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, ok := j.results[id]
+
+	return ok
+}
+
+// Result decodes the recorded result of the item into v.
+func (j *Journal) Result(id string, v any) (bool, error) {
+	j.mu.Lock()
+	raw, ok := j.results[id]
+	j.mu.Unlock()
+
+	if !ok || len(raw) == 0 {
+		return ok, nil
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("could not unmarshal checkpoint result of %s: %w", id, err)
+	}
+
+	return true, nil
+}
+
+// Replay calls fn with every completed item, in completion order.
+func (j *Journal) Replay(fn func(id string, result json.RawMessage) error) error {
+	j.mu.Lock()
+	order := make([]string, len(j.order))
+	copy(order, j.order)
+	j.mu.Unlock()
+
+	for _, id := range order {
+		j.mu.Lock()
+		raw := j.results[id]
+		j.mu.Unlock()
+
+		if err := fn(id, raw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Record marks the item as completed with its result, which may be nil.
+func (j *Journal) Record(id string, result any) error {
+	var raw json.RawMessage
+	if result != nil {
+		var err error
+		raw, err = json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("could not marshal checkpoint result of %s: %w", id, err)
+		}
+	}
+
+	line, err := json.Marshal(record{ID: id, Result: raw, At: time.Now().UTC()})
+	if err != nil {
+		return fmt.Errorf("could not marshal checkpoint record of %s: %w", id, err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("could not write checkpoint record of %s: %w", id, err)
+	}
+
+	if _, ok := j.results[id]; !ok {
+		j.order = append(j.order, id)
+	}
+	j.results[id] = raw
+
+	return nil
+}
+
+// Close flushes the journal to disk and closes it.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+
+		return fmt.Errorf("could not sync checkpoint: %w", err)
+	}
+
+	return j.file.Close()
+}
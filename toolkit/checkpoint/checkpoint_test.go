@@ -0,0 +1,100 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type result struct {
+	Value float64 `json:"value"`
+}
+
+func Test_Journal_Resume(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "run", "journal.jsonl")
+
+	j, err := Open(path, false)
+	require.NoError(t, err)
+	require.NoError(t, j.Record("a", result{1.0}))
+	require.NoError(t, j.Record("b", nil))
+	require.NoError(t, j.Close())
+
+	j, err = Open(path, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, j.Len())
+	assert.True(t, j.Done("a"))
+	assert.True(t, j.Done("b"))
+	assert.False(t, j.Done("c"))
+
+	var r result
+	ok, err := j.Result("a", &r)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.InDelta(t, 1.0, r.Value, 1e-15)
+
+	require.NoError(t, j.Record("c", result{3.0}))
+
+	ids := make([]string, 0)
+	require.NoError(t, j.Replay(func(id string, _ json.RawMessage) error {
+		ids = append(ids, id)
+
+		return nil
+	}))
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+	require.NoError(t, j.Close())
+}
+
+func Test_Journal_NoResumeTruncates(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	j, err := Open(path, false)
+	require.NoError(t, err)
+	require.NoError(t, j.Record("a", nil))
+	require.NoError(t, j.Close())
+
+	j, err = Open(path, false)
+	require.NoError(t, err)
+	assert.Equal(t, 0, j.Len())
+	require.NoError(t, j.Close())
+}
+
+func Test_Journal_TruncatedLine(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	j, err := Open(path, false)
+	require.NoError(t, err)
+	require.NoError(t, j.Record("a", result{1.0}))
+	require.NoError(t, j.Close())
+
+	// Simulate a crash in the middle of a write.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"id":"b","res`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	j, err = Open(path, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, j.Len())
+	assert.False(t, j.Done("b"))
+
+	require.NoError(t, j.Record("b", result{2.0}))
+	require.NoError(t, j.Close())
+
+	j, err = Open(path, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, j.Len())
+	assert.True(t, j.Done("b"))
+	require.NoError(t, j.Close())
+}
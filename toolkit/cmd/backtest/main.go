@@ -0,0 +1,16 @@
+/*
+Command backtest backtests predicted VaR and ES against realised P&L:
+
+	backtest -input backtest.csv -confidence 0.99 -output report.json
+
+The input CSV holds date, var, pnl and optionally id and es columns; each id
+is an asset or a portfolio backtested on its own. Values follow the P&L sign
+(losses negative) unless -loss-positive is set.
+*/
+package main
+
+import "toolkit/backtest/cli"
+
+func main() {
+	cli.Main()
+}
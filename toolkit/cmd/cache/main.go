@@ -0,0 +1,9 @@
+// Command cache lists, measures and invalidates the on-disk cache of the
+// service responses.
+package main
+
+import "toolkit/cache/cli"
+
+func main() {
+	cli.Main()
+}
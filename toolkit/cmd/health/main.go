@@ -0,0 +1,17 @@
+/*
+Command health checks the scenario statuses and values of Eve pricing results:
+
+	health -input dev_result.json,prod_result.json -scenarios 2501-3000,6500-6999
+
+Every result gets a health score, the percentage of its scenarios in Success
+and free of anomaly (non-numeric, zero, far beyond the NPV or jumping from the
+previous scenario). The command exits with status 1 when a score is under
+-min-score.
+*/
+package main
+
+import "toolkit/health/cli"
+
+func main() {
+	cli.Main()
+}
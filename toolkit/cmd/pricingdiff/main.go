@@ -0,0 +1,17 @@
+/*
+Command pricingdiff explains a pricing gap between two environments by
+comparing their Eve results and requests:
+
+	pricingdiff -a data/dev_result.json -b data/prod_result.json \
+		-request-a data/dev_request.json -request-b data/prod_request.json \
+		-tolerance main.NPV=1e-8:1e-6,scenarios=:1e-4 -output diff.json
+
+The report is printed on stdout and, with -output, written as JSON.
+*/
+package main
+
+import "toolkit/pricingdiff/cli"
+
+func main() {
+	cli.Main()
+}
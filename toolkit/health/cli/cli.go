@@ -0,0 +1,200 @@
+// Package cli implements the health command, see toolkit/cmd/health.
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"toolkit/health"
+	"toolkit/manifest"
+)
+
+var (
+	inputPaths = "./data/dev_result.json"
+	scenarios  = ""
+	outputPath = ""
+	far        = health.DefaultOptions.Far
+	jump       = health.DefaultOptions.Jump
+	minScore   = 0.0
+	nbWorst    = 10
+)
+
+// Main parses the command line and runs the health command.
+func Main() {
+	flag.StringVar(&inputPaths, "input", inputPaths, "comma-separated Eve pricing result files")
+	flag.StringVar(&scenarios, "scenarios", scenarios, "comma-separated inclusive ranges of scenario IDs, all scenarios if empty")
+	flag.StringVar(&outputPath, "output", outputPath, "optional .json or .csv report")
+	flag.Float64Var(&far, "far", far, "flag values moving more than this multiple of the NPV away from it")
+	flag.Float64Var(&jump, "jump", jump, "flag changes between neighbouring scenarios larger than this multiple of the median change")
+	flag.Float64Var(&minScore, "min-score", minScore, "exit with status 1 when a health score is under this percentage")
+	flag.IntVar(&nbWorst, "worst", nbWorst, "number of anomalies listed per result")
+	flag.Parse()
+
+	run := manifest.New("health")
+	run.RecordFlags(flag.CommandLine)
+
+	opts := health.Options{Far: far, Jump: jump}
+	if scenarios != "" {
+		ranges, err := health.ParseRanges(scenarios)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.Ranges = ranges
+	}
+
+	paths := strings.Split(inputPaths, ",")
+	reports := make([]health.Report, 0, len(paths))
+	healthy := 0
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("could not read %s: %v", path, err)
+		}
+
+		if err := run.AddInput(path); err != nil {
+			log.Fatal(err)
+		}
+
+		report, err := health.Analyze(raw, opts)
+		if err != nil {
+			log.Fatalf("could not analyze %s: %v", path, err)
+		}
+		report.Input = path
+
+		if report.Score >= minScore {
+			healthy++
+		}
+
+		reports = append(reports, report)
+	}
+	run.Count("health", len(reports), healthy)
+
+	printReports(os.Stdout, reports)
+
+	if outputPath != "" {
+		if err := write(outputPath, reports); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := run.Write(outputPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if healthy < len(reports) {
+		os.Exit(1)
+	}
+}
+
+func printReports(w io.Writer, reports []health.Report) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INPUT\tNPV\tRANGE\tSCENARIOS\tCLEAN\tSCORE\tSTATUSES\tANOMALIES")
+	for _, r := range reports {
+		for _, h := range r.Ranges {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%.2f\t%s\t%s\n",
+				r.Input, r.NPVStatus, h.Range, h.Scenarios, h.Clean, h.Score, formatCounts(h.Statuses), formatCounts(h.Anomalies))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t(all)\t\t\t%.2f\t\t\n", r.Input, r.NPVStatus, r.Score)
+	}
+	tw.Flush()
+
+	for _, r := range reports {
+		failed := make([]string, 0)
+		for metric, statuses := range r.Metrics {
+			for status, n := range statuses {
+				if status != "Success" {
+					failed = append(failed, fmt.Sprintf("%s %s:%d", metric, status, n))
+				}
+			}
+		}
+		sort.Strings(failed)
+
+		if len(failed) == 0 && len(r.Anomalies) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n%s\n", r.Input)
+		for _, f := range failed {
+			fmt.Fprintf(w, "  metric %s\n", f)
+		}
+
+		for i, a := range r.Anomalies {
+			if i == nbWorst {
+				fmt.Fprintf(w, "  ... %d more anomalies\n", len(r.Anomalies)-nbWorst)
+
+				break
+			}
+
+			value := "-"
+			if a.Value != nil {
+				value = strconv.FormatFloat(*a.Value, 'g', 6, 64)
+			}
+			fmt.Fprintf(w, "  scenario %d: %s (%s, %s)\n", a.Scenario, a.Kind, a.Status, value)
+		}
+	}
+}
+
+func write(path string, reports []health.Report) error {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		raw, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not marshal report: %w", err)
+		}
+
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			return fmt.Errorf("could not write %s: %w", path, err)
+		}
+
+		return nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	_ = writer.Write([]string{"input", "npvStatus", "range", "scenarios", "clean", "score", "statuses", "anomalies"})
+	for _, r := range reports {
+		for _, h := range r.Ranges {
+			_ = writer.Write([]string{
+				r.Input, r.NPVStatus, h.Range, strconv.Itoa(h.Scenarios), strconv.Itoa(h.Clean),
+				strconv.FormatFloat(h.Score, 'f', -1, 64), formatCounts(h.Statuses), formatCounts(h.Anomalies),
+			})
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// formatCounts lists counts as "Failed:2 Success:498".
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s:%d", k, counts[k])
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,367 @@
+// Package health checks the scenario values of Eve pricing results before risk
+// measures are computed from them.
+//
+// Every {value, status} metric of a result is counted per status. Scenario
+// values are then screened for anomalies: values that are not numbers, zeros,
+// values far beyond the NPV and jumps between neighbouring scenarios. The
+// health score of a range is the share of its scenarios that are in Success
+// and free of anomaly.
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"toolkit/pricingdiff"
+)
+
+// Anomaly kinds.
+const (
+	KindStatus = "status"
+	KindNaN    = "nan"
+	KindZero   = "zero"
+	KindFar    = "far"
+	KindJump   = "jump"
+)
+
+const statusSuccess = "Success"
+
+// Range is an inclusive range of scenario IDs.
+type Range struct {
+	From, To uint32
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+func (r Range) contains(id uint32) bool {
+	return id >= r.From && id <= r.To
+}
+
+// ParseRanges parses comma-separated ranges such as "2501-3000,6500-6999".
+func ParseRanges(value string) ([]Range, error) {
+	ranges := make([]Range, 0)
+	for _, part := range strings.Split(value, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("scenario range %q is not of the form from-to", part)
+		}
+
+		from, err := strconv.ParseUint(bounds[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scenario range %q: %w", part, err)
+		}
+
+		to, err := strconv.ParseUint(bounds[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scenario range %q: %w", part, err)
+		}
+
+		if from > to {
+			return nil, fmt.Errorf("scenario range %q is empty", part)
+		}
+
+		ranges = append(ranges, Range{From: uint32(from), To: uint32(to)})
+	}
+
+	return ranges, nil
+}
+
+// Options tune the anomaly detection.
+type Options struct {
+	// Ranges of scenarios to check, all scenarios in a single range if empty.
+	Ranges []Range
+
+	// Far flags a value moving more than Far times the NPV away from it.
+	Far float64
+
+	// Jump flags a change between neighbouring scenario IDs larger than Jump
+	// times the median change of the range.
+	Jump float64
+}
+
+// DefaultOptions are the options of Analyze when zero.
+var DefaultOptions = Options{Far: 5, Jump: 20}
+
+// Anomaly is a scenario whose value looks broken.
+type Anomaly struct {
+	Scenario uint32 `json:"scenario"`
+	Kind     string `json:"kind"`
+	Status   string `json:"status,omitempty"`
+
+	// Value is nil when the scenario has no numeric value.
+	Value *float64 `json:"value,omitempty"`
+}
+
+// RangeHealth is the health of a range of scenarios.
+type RangeHealth struct {
+	Range     string         `json:"range"`
+	Scenarios int            `json:"scenarios"`
+	Statuses  map[string]int `json:"statuses"`
+	Anomalies map[string]int `json:"anomalies"`
+	Clean     int            `json:"clean"`
+
+	// Score is the percentage of clean scenarios.
+	Score float64 `json:"score"`
+}
+
+// Report is the health of a pricing result.
+type Report struct {
+	Input     string  `json:"input,omitempty"`
+	NPV       float64 `json:"npv"`
+	NPVStatus string  `json:"npvStatus"`
+
+	// Metrics counts the statuses of the metrics out of the scenarios, keyed
+	// by their path with numeric and date segments collapsed to "*".
+	Metrics map[string]map[string]int `json:"metrics"`
+
+	Ranges    []RangeHealth `json:"ranges"`
+	Anomalies []Anomaly     `json:"anomalies,omitempty"`
+
+	// Score is the percentage of clean scenarios over all ranges, zero when
+	// the NPV itself is not in Success.
+	Score float64 `json:"score"`
+}
+
+// scenario is a scenario value as found in a result, the value being NaN when
+// it is missing or not a number.
+type scenario struct {
+	id     uint32
+	value  float64
+	status string
+}
+
+// Analyze checks the pricing result in raw, as answered by Eve or wrapped under
+// "results".
+func Analyze(raw []byte, opts Options) (Report, error) {
+	if opts.Far == 0 {
+		opts.Far = DefaultOptions.Far
+	}
+	if opts.Jump == 0 {
+		opts.Jump = DefaultOptions.Jump
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return Report{}, fmt.Errorf("could not unmarshal the result: %w", err)
+	}
+
+	if results, ok := doc["results"].(map[string]any); ok {
+		doc = results
+	}
+
+	report := Report{Metrics: make(map[string]map[string]int)}
+
+	for key, v := range doc {
+		if key != "scenarios" {
+			countMetrics(key, v, report.Metrics)
+		}
+	}
+
+	npv, _ := metricOf(dig(doc, "main", "NPV"))
+	report.NPVStatus = npv.status
+	if math.IsNaN(npv.value) {
+		report.NPVStatus = "Missing"
+	} else {
+		report.NPV = npv.value
+	}
+
+	scenarios := make([]scenario, 0)
+	if values, ok := doc["scenarios"].(map[string]any); ok {
+		for key, v := range values {
+			id, err := strconv.ParseUint(key, 10, 32)
+			if err != nil {
+				return Report{}, fmt.Errorf("invalid scenario ID %q", key)
+			}
+
+			s, ok := metricOf(v)
+			if !ok {
+				s.status = "Missing"
+			}
+			s.id = uint32(id)
+
+			scenarios = append(scenarios, s)
+		}
+	}
+
+	sort.Slice(scenarios, func(i, j int) bool {
+		return scenarios[i].id < scenarios[j].id
+	})
+
+	ranges := opts.Ranges
+	if len(ranges) == 0 {
+		ranges = []Range{{From: 0, To: math.MaxUint32}}
+		if len(scenarios) > 0 {
+			ranges[0] = Range{From: scenarios[0].id, To: scenarios[len(scenarios)-1].id}
+		}
+	}
+
+	var total, clean int
+	for _, r := range ranges {
+		in := make([]scenario, 0)
+		for _, s := range scenarios {
+			if r.contains(s.id) {
+				in = append(in, s)
+			}
+		}
+
+		health, anomalies := checkRange(r, in, report.NPV, opts)
+		report.Ranges = append(report.Ranges, health)
+		report.Anomalies = append(report.Anomalies, anomalies...)
+
+		total += health.Scenarios
+		clean += health.Clean
+	}
+
+	if total > 0 && report.NPVStatus == statusSuccess {
+		report.Score = 100 * float64(clean) / float64(total)
+	}
+
+	return report, nil
+}
+
+// checkRange screens the sorted scenarios of a range.
+func checkRange(r Range, scenarios []scenario, npv float64, opts Options) (RangeHealth, []Anomaly) {
+	health := RangeHealth{
+		Range:     r.String(),
+		Scenarios: len(scenarios),
+		Statuses:  make(map[string]int),
+		Anomalies: make(map[string]int),
+	}
+
+	broken := make(map[uint32]bool)
+	anomalies := make([]Anomaly, 0)
+	flag := func(s scenario, kind string) {
+		a := Anomaly{Scenario: s.id, Kind: kind, Status: s.status}
+		if !math.IsNaN(s.value) && !math.IsInf(s.value, 0) {
+			value := s.value
+			a.Value = &value
+		}
+
+		anomalies = append(anomalies, a)
+		health.Anomalies[kind]++
+		broken[s.id] = true
+	}
+
+	valid := make([]scenario, 0, len(scenarios))
+	for _, s := range scenarios {
+		health.Statuses[s.status]++
+
+		switch {
+		case s.status != statusSuccess:
+			flag(s, KindStatus)
+		case math.IsNaN(s.value) || math.IsInf(s.value, 0):
+			flag(s, KindNaN)
+		case s.value == 0 && npv != 0:
+			flag(s, KindZero)
+		case npv != 0 && math.Abs(s.value-npv) > opts.Far*math.Abs(npv):
+			flag(s, KindFar)
+		default:
+			valid = append(valid, s)
+		}
+	}
+
+	// Jumps between neighbouring scenarios, against the median change so that
+	// a volatile period does not flag every scenario.
+	changes := make([]float64, 0, len(valid))
+	for i := 1; i < len(valid); i++ {
+		if valid[i].id == valid[i-1].id+1 {
+			changes = append(changes, math.Abs(valid[i].value-valid[i-1].value))
+		}
+	}
+
+	if len(changes) > 0 {
+		sorted := append([]float64(nil), changes...)
+		sort.Float64s(sorted)
+		median := sorted[len(sorted)/2]
+
+		threshold := opts.Jump * median
+		if median == 0 {
+			threshold = opts.Jump * 1e-9 * math.Max(math.Abs(npv), 1)
+		}
+
+		for i := 1; i < len(valid); i++ {
+			if valid[i].id == valid[i-1].id+1 && math.Abs(valid[i].value-valid[i-1].value) > threshold {
+				flag(valid[i], KindJump)
+			}
+		}
+	}
+
+	health.Clean = len(scenarios) - len(broken)
+	if len(scenarios) > 0 {
+		health.Score = 100 * float64(health.Clean) / float64(len(scenarios))
+	}
+
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return anomalies[i].Scenario < anomalies[j].Scenario
+	})
+
+	return health, anomalies
+}
+
+// countMetrics counts the statuses of the {value, status} leaves under path.
+func countMetrics(path string, v any, metrics map[string]map[string]int) {
+	switch v := v.(type) {
+	case map[string]any:
+		if _, ok := v["status"]; ok {
+			s, _ := metricOf(v)
+
+			pattern := pricingdiff.Pattern(path)
+			if metrics[pattern] == nil {
+				metrics[pattern] = make(map[string]int)
+			}
+			metrics[pattern][s.status]++
+
+			return
+		}
+
+		for key, child := range v {
+			countMetrics(path+"."+key, child, metrics)
+		}
+	case []any:
+		for i, child := range v {
+			countMetrics(fmt.Sprintf("%s[%d]", path, i), child, metrics)
+		}
+	}
+}
+
+// metricOf reads a {value, status} leaf. Values that are missing, null or not
+// numbers, such as "NaN", are NaN.
+func metricOf(v any) (scenario, bool) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return scenario{value: math.NaN()}, false
+	}
+
+	s := scenario{value: math.NaN()}
+	s.status, _ = m["status"].(string)
+
+	switch value := m["value"].(type) {
+	case float64:
+		s.value = value
+	case string:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			s.value = f
+		}
+	}
+
+	return s, true
+}
+
+func dig(doc map[string]any, keys ...string) any {
+	var v any = doc
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+
+	return v
+}
@@ -0,0 +1,110 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// result returns a pricing result of NPV 100 whose scenarios 1 to 100 move
+// smoothly, overridden by the given values.
+func result(t *testing.T, npvStatus string, overrides map[int]any) []byte {
+	t.Helper()
+
+	scenarios := make(map[string]any, 100)
+	for i := 1; i <= 100; i++ {
+		var value any = 100 + float64(i%10)/10
+		status := "Success"
+		if v, ok := overrides[i]; ok {
+			if s, ok := v.(string); ok && (s == "Failed" || s == "Timeout") {
+				status = s
+			} else {
+				value = v
+			}
+		}
+		scenarios[fmt.Sprint(i)] = map[string]any{"value": value, "status": status}
+	}
+
+	raw, err := json.Marshal(map[string]any{
+		"results": map[string]any{
+			"main": map[string]any{
+				"NPV":   map[string]any{"value": 100, "status": npvStatus},
+				"theta": map[string]any{"status": "Failed"},
+			},
+			"sensitivities": map[string]any{
+				"delta": map[string]any{
+					"2024-01-01": map[string]any{"value": 1, "status": "Success"},
+					"2024-02-01": map[string]any{"value": 1, "status": "Success"},
+				},
+			},
+			"scenarios": scenarios,
+		},
+	})
+	require.NoError(t, err)
+
+	return raw
+}
+
+func Test_Analyze(t *testing.T) {
+	t.Parallel()
+
+	raw := result(t, "Success", map[int]any{
+		3:  "Failed",
+		10: "NaN",
+		20: 0,
+		30: 1000,
+		50: 150,
+		70: nil,
+	})
+
+	report, err := Analyze(raw, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"Success": 1}, report.Metrics["main.NPV"])
+	assert.Equal(t, map[string]int{"Failed": 1}, report.Metrics["main.theta"])
+	assert.Equal(t, map[string]int{"Success": 2}, report.Metrics["sensitivities.delta.*"])
+
+	require.Len(t, report.Ranges, 1)
+	r := report.Ranges[0]
+	assert.Equal(t, "1-100", r.Range)
+	assert.Equal(t, map[string]int{"Success": 99, "Failed": 1}, r.Statuses)
+
+	// The spike at 50 jumps in and out.
+	assert.Equal(t, map[string]int{KindStatus: 1, KindNaN: 2, KindZero: 1, KindFar: 1, KindJump: 2}, r.Anomalies)
+	assert.Equal(t, 93, r.Clean)
+	assert.InDelta(t, 93.0, report.Score, 1e-12)
+
+	kinds := make(map[uint32]string)
+	for _, a := range report.Anomalies {
+		kinds[a.Scenario] = a.Kind
+	}
+	assert.Equal(t, map[uint32]string{3: KindStatus, 10: KindNaN, 20: KindZero, 30: KindFar, 50: KindJump, 51: KindJump, 70: KindNaN}, kinds)
+
+	// The report marshals despite the NaN.
+	_, err = json.Marshal(report)
+	assert.NoError(t, err)
+}
+
+func Test_Analyze_Ranges(t *testing.T) {
+	t.Parallel()
+
+	ranges, err := ParseRanges("1-50,51-100")
+	require.NoError(t, err)
+
+	report, err := Analyze(result(t, "Success", map[int]any{60: "Timeout"}), Options{Ranges: ranges})
+	require.NoError(t, err)
+	require.Len(t, report.Ranges, 2)
+	assert.Equal(t, 100.0, report.Ranges[0].Score)
+	assert.Equal(t, 98.0, report.Ranges[1].Score)
+	assert.Equal(t, 99.0, report.Score)
+
+	// A failed NPV makes every risk number meaningless.
+	report, err = Analyze(result(t, "Failed", nil), Options{})
+	require.NoError(t, err)
+	assert.Zero(t, report.Score)
+
+	_, err = ParseRanges("10-1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,57 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Cerberus returns a resolver looking the ISINs up in Cerberus. The token is
+// only sent when set, as the internal hosts do not need one.
+func Cerberus(host, token string) Resolver {
+	return func(isin string) (string, error) {
+		url := fmt.Sprintf("%s/assets/isin/%s?view=full", host, isin)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+		if err != nil {
+			return "", fmt.Errorf("could not create http request for url %s: %w", url, err)
+		}
+		req.Header.Set("x-internal-service", "validation")
+		req.Header.Set("Accept", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		client := &http.Client{}
+		res, err := client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("could not send the request: %w", err)
+		}
+
+		defer res.Body.Close()
+
+		raw, err := io.ReadAll(res.Body)
+		if err != nil {
+			return "", fmt.Errorf("could not read response body: %w", err)
+		}
+
+		if res.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("unexpected status code %d: %s", res.StatusCode, string(raw))
+		}
+
+		var output struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(raw, &output); err != nil {
+			return "", fmt.Errorf("received non-JSON response: %s, error was: %w", string(raw), err)
+		}
+
+		if output.ID == "" {
+			return "", fmt.Errorf("no asset with ISIN %s", isin)
+		}
+
+		return output.ID, nil
+	}
+}
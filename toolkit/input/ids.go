@@ -0,0 +1,79 @@
+package input
+
+import "strings"
+
+// IsID tells whether s is an EdgeLab UUID or an ISIN.
+func IsID(s string) bool {
+	return IsUUID(s) || IsISIN(s)
+}
+
+// IsUUID tells whether s is a UUID in its canonical 8-4-4-4-12 form.
+func IsUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !isHex(c) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// IsISIN tells whether s is an ISIN: a 2-letter country code, 9 alphanumeric
+// characters and a Luhn check digit.
+func IsISIN(s string) bool {
+	if len(s) != 12 {
+		return false
+	}
+
+	s = strings.ToUpper(s)
+	for i, c := range s {
+		switch {
+		case i < 2 && (c < 'A' || c > 'Z'):
+			return false
+		case i == 11 && (c < '0' || c > '9'):
+			return false
+		case (c < '0' || c > '9') && (c < 'A' || c > 'Z'):
+			return false
+		}
+	}
+
+	// Letters count as two digits, A being 10.
+	digits := make([]int, 0, 24)
+	for _, c := range s {
+		if c >= 'A' {
+			v := int(c-'A') + 10
+			digits = append(digits, v/10, v%10)
+		} else {
+			digits = append(digits, int(c-'0'))
+		}
+	}
+
+	sum := 0
+	for i := range digits {
+		d := digits[len(digits)-1-i]
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+
+	return sum%10 == 0
+}
+
+func isHex(c rune) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
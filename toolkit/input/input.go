@@ -0,0 +1,277 @@
+/*
+Package input reads the lists of asset and issuer IDs given to the scripts.
+
+A list is a CSV file, with or without header, or a JSON array of strings, read
+from a file or from stdin when the path is "-". The first CSV row is a header
+when the header is forced, when the column is given by name or when it holds a
+known ID column name, such as master-id or isin; any other first row is an
+entry. A JSON object holding a single array, such as {"input": [...]}, is
+accepted too. Every entry must be an
+EdgeLab UUID or an ISIN; ISINs are turned into IDs by the resolver, and the
+resulting IDs are de-duplicated in input order.
+*/
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// headerNames are the names of the ID column recognised as a header, compared
+// case-insensitively with '-' read as '_'.
+var headerNames = []string{"id", "ids", "asset", "asset_id", "master_id", "master_ids", "isin", "issuer", "issuer_id"}
+
+// isHeaderName reports whether the cell is a known ID column name.
+func isHeaderName(cell string) bool {
+	name := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(cell)), "-", "_")
+	for _, h := range headerNames {
+		if name == h {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolver returns the EdgeLab ID of the asset with the ISIN.
+type Resolver func(isin string) (string, error)
+
+// Options configures the reading of an ID list.
+type Options struct {
+	// Column is the name or the 0-based index of the CSV column holding the
+	// IDs. It defaults to the first column. A name requires a header.
+	Column string
+
+	// Header reads the first CSV row as a header whatever it holds.
+	Header bool
+
+	// Resolver resolves the ISINs of the list. When nil, an ISIN is an error.
+	Resolver Resolver
+
+	// OnError, when set, is called with the invalid or unresolved entries,
+	// which are then skipped instead of failing the whole list.
+	OnError func(entry string, err error)
+}
+
+// Read reads the ID list at path, or on stdin when path is "-".
+func Read(path string, opts Options) ([]string, error) {
+	raw, err := readAll(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(raw, strings.EqualFold(filepath.Ext(path), ".json"), opts)
+}
+
+// Parse parses an ID list. The content is read as JSON when asJSON is set or
+// when it starts with '[' or '{', and as CSV otherwise.
+func Parse(raw []byte, asJSON bool, opts Options) ([]string, error) {
+	raw = bytes.TrimPrefix(raw, []byte("\xef\xbb\xbf"))
+
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		asJSON = true
+	}
+
+	var (
+		entries []string
+		err     error
+	)
+	if asJSON {
+		entries, err = parseJSON(trimmed)
+	} else {
+		entries, err = parseCSV(raw, opts.Column, opts.Header)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resolve(entries, opts)
+}
+
+func readAll(path string) ([]byte, error) {
+	if path == "-" {
+		raw, err := io.ReadAll(bufio.NewReader(os.Stdin))
+		if err != nil {
+			return nil, fmt.Errorf("could not read stdin: %w", err)
+		}
+
+		return raw, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	return raw, nil
+}
+
+func parseJSON(raw []byte) ([]string, error) {
+	var entries []string
+	if err := json.Unmarshal(raw, &entries); err == nil {
+		return entries, nil
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of strings: %w", err)
+	}
+
+	if len(object) != 1 {
+		return nil, fmt.Errorf("expected a JSON object with a single array, got %d fields", len(object))
+	}
+
+	for field, value := range object {
+		if err := json.Unmarshal(value, &entries); err != nil {
+			return nil, fmt.Errorf("expected %q to be an array of strings: %w", field, err)
+		}
+	}
+
+	return entries, nil
+}
+
+func parseCSV(raw []byte, column string, header bool) ([]string, error) {
+	reader := csv.NewReader(bytes.NewReader(raw))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse CSV: %w", err)
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	index, named := 0, false
+	if column != "" {
+		if i, err := strconv.Atoi(column); err == nil {
+			if i < 0 {
+				return nil, fmt.Errorf("invalid column index %d", i)
+			}
+			index = i
+		} else {
+			named = true
+		}
+	}
+
+	// The first row is a header when forced, when the column is named or when
+	// it holds a known column name. Any other invalid first row fails like the
+	// others.
+	header = header || named || (index < len(records[0]) && isHeaderName(records[0][index]))
+	if named {
+		index = -1
+		for i, name := range records[0] {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				index = i
+
+				break
+			}
+		}
+
+		if index < 0 {
+			return nil, fmt.Errorf("could not find column %q in header %v", column, records[0])
+		}
+	}
+
+	if header {
+		records = records[1:]
+	}
+
+	entries := make([]string, 0, len(records))
+	for i, record := range records {
+		if index >= len(record) {
+			// Blank lines have a single empty field.
+			if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+				continue
+			}
+
+			return nil, fmt.Errorf("line %d has no column %d", i+1, index)
+		}
+
+		entries = append(entries, record[index])
+	}
+
+	return entries, nil
+}
+
+// resolve validates the entries, resolves the ISINs and removes duplicates.
+func resolve(entries []string, opts Options) ([]string, error) {
+	ids := make([]string, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	resolved := make(map[string]string)
+
+	var errs []error
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id := entry
+		switch {
+		case IsUUID(entry):
+			id = strings.ToLower(entry)
+		case IsISIN(entry):
+			if cached, ok := resolved[entry]; ok {
+				id = cached
+
+				break
+			}
+
+			if opts.Resolver == nil {
+				errs = append(errs, fail(opts, entry, fmt.Errorf("ISIN %s cannot be resolved to an ID", entry))...)
+
+				continue
+			}
+
+			var err error
+			id, err = opts.Resolver(entry)
+			if err != nil {
+				errs = append(errs, fail(opts, entry, fmt.Errorf("could not resolve ISIN %s: %w", entry, err))...)
+
+				continue
+			}
+			resolved[entry] = id
+		default:
+			errs = append(errs, fail(opts, entry, fmt.Errorf("%q is neither an ID nor an ISIN", entry))...)
+
+			continue
+		}
+
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return ids, nil
+}
+
+// fail reports the error of the entry through OnError, or returns it to be
+// joined with the others.
+func fail(opts Options, entry string, err error) []error {
+	if opts.OnError != nil {
+		opts.OnError(entry, err)
+
+		return nil
+	}
+
+	return []error{err}
+}
@@ -0,0 +1,177 @@
+package input
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	idA = "bcb8fbf0-4267-4817-a3be-c544c1efa00f"
+	idB = "cedb6b04-7f30-4179-b0cb-e34d2d1c6fc0"
+)
+
+func Test_IsISIN(t *testing.T) {
+	t.Parallel()
+
+	for _, isin := range []string{"CH0210483332", "US0378331005", "FR0000120271", "XS2706984338"} {
+		assert.True(t, IsISIN(isin), isin)
+	}
+
+	for _, s := range []string{"US0378331006", "0S0378331005", "US037833100", idA, ""} {
+		assert.False(t, IsISIN(s), s)
+	}
+}
+
+func Test_IsUUID(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsUUID(idA))
+	assert.True(t, IsUUID("BCB8FBF0-4267-4817-A3BE-C544C1EFA00F"))
+	assert.False(t, IsUUID("bcb8fbf0-4267-4817-a3be-c544c1efa00"))
+	assert.False(t, IsUUID("bcb8fbf0_4267-4817-a3be-c544c1efa00f"))
+	assert.False(t, IsUUID("master-id"))
+}
+
+func Test_Parse_CSV(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		raw    string
+		column string
+	}{
+		{"header", "master-id\n" + idA + "\n" + idB + "\n", ""},
+		{"no header", idA + "\n" + idB + "\n", ""},
+		{"plural header", "Master-IDs\n" + idA + "\n" + idB + "\n", ""},
+		{"byte order mark", "\xef\xbb\xbfmaster_id\n" + idA + "\n" + idB + "\n", ""},
+		{"column index", "isin,id\nx," + idA + "\ny," + idB + "\n", "1"},
+		{"column name", "isin,asset_id\nx," + idA + "\ny," + idB + "\n", "asset_id"},
+		{"duplicates and blank lines", idA + "\n\n" + idB + "\n" + idA + "\n", ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ids, err := Parse([]byte(tt.raw), false, Options{Column: tt.column})
+			require.NoError(t, err)
+			assert.Equal(t, []string{idA, idB}, ids)
+		})
+	}
+}
+
+func Test_Parse_Header(t *testing.T) {
+	t.Parallel()
+
+	raw := []byte("isin,bond\nx," + idA + "\ny," + idB + "\n")
+
+	ids, err := Parse(raw, false, Options{Column: "1", Header: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{idA, idB}, ids)
+
+	_, err = Parse(raw, false, Options{Column: "1"})
+	assert.ErrorContains(t, err, "bond")
+}
+
+func Test_Parse_JSON(t *testing.T) {
+	t.Parallel()
+
+	for _, raw := range []string{
+		`["` + idA + `", "` + idB + `"]`,
+		`{"input": ["` + idA + `", "` + idB + `"]}`,
+	} {
+		ids, err := Parse([]byte(raw), false, Options{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{idA, idB}, ids)
+	}
+
+	_, err := Parse([]byte(`{"a": [], "b": []}`), true, Options{})
+	assert.Error(t, err)
+}
+
+func Test_Parse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse([]byte(idA+"\nnot-an-id\n"), false, Options{})
+	assert.ErrorContains(t, err, "not-an-id")
+
+	var skipped []string
+	ids, err := Parse([]byte(idA+"\nnot-an-id\n"), false, Options{OnError: func(entry string, _ error) {
+		skipped = append(skipped, entry)
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{idA}, ids)
+	assert.Equal(t, []string{"not-an-id"}, skipped)
+
+	// A first row that is neither an ID nor a known column name is not
+	// taken for a header.
+	_, err = Parse([]byte("not-an-id\n"+idA+"\n"), false, Options{})
+	assert.ErrorContains(t, err, "not-an-id")
+
+	_, err = Parse([]byte("isin\n"+idA+"\n"), false, Options{Column: "asset"})
+	assert.ErrorContains(t, err, "could not find column")
+
+	_, err = Parse([]byte(idA+"\n"), false, Options{Column: "-1"})
+	assert.ErrorContains(t, err, "invalid column index -1")
+}
+
+func Test_Parse_ResolveISIN(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	resolver := func(isin string) (string, error) {
+		calls++
+
+		switch isin {
+		case "CH0210483332":
+			return idB, nil
+		default:
+			return "", errors.New("not found")
+		}
+	}
+
+	ids, err := Parse([]byte(`["`+idA+`", "CH0210483332", "CH0210483332"]`), true, Options{Resolver: resolver})
+	require.NoError(t, err)
+	assert.Equal(t, []string{idA, idB}, ids)
+	assert.Equal(t, 1, calls)
+
+	_, err = Parse([]byte(`["US0378331005"]`), true, Options{Resolver: resolver})
+	assert.ErrorContains(t, err, "US0378331005")
+
+	_, err = Parse([]byte(`["US0378331005"]`), true, Options{})
+	assert.ErrorContains(t, err, "cannot be resolved")
+}
+
+func Test_ReadPositions(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	csvPath := filepath.Join(dir, "positions.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("asset,currency,quantity\n"+idA+",EUR,2.5\n"+idB+",USD,\n"+idA+",EUR,1\n"), 0o644))
+
+	positions, err := ReadPositions(csvPath, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []Position{
+		{ID: idA, Currency: "EUR", Quantity: 2.5},
+		{ID: idB, Currency: "USD", Quantity: 1.0},
+		{ID: idA, Currency: "EUR", Quantity: 1.0},
+	}, positions)
+
+	jsonPath := filepath.Join(dir, "positions.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`[{"id":"`+idA+`","currency":"EUR","quantity":3},{"asset":"`+idB+`","currency":"USD"}]`), 0o644))
+
+	positions, err = ReadPositions(jsonPath, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []Position{
+		{ID: idA, Currency: "EUR", Quantity: 3.0},
+		{ID: idB, Currency: "USD", Quantity: 1.0},
+	}, positions)
+}
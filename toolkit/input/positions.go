@@ -0,0 +1,152 @@
+package input
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Position is a quantity of an asset held in a currency.
+type Position struct {
+	ID       string  `json:"id"`
+	Currency string  `json:"currency"`
+	Quantity float64 `json:"quantity"`
+}
+
+// idColumns are the header names recognised as the ID column of a positions
+// file, in order of preference.
+var idColumns = []string{"id", "asset", "master-id", "master_id", "isin"}
+
+// ReadPositions reads a positions file at path, or on stdin when path is "-".
+//
+// A CSV file has a header with an ID column (Options.Column, or one of id,
+// asset, master-id, master_id and isin), a currency column and an optional
+// quantity column defaulting to 1. A JSON file is an array of objects with the
+// id (or asset), currency and quantity fields. Positions are not de-duplicated.
+func ReadPositions(path string, opts Options) ([]Position, error) {
+	raw, err := readAll(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw = bytes.TrimPrefix(raw, []byte("\xef\xbb\xbf"))
+
+	var positions []Position
+	trimmed := bytes.TrimSpace(raw)
+	if strings.EqualFold(filepath.Ext(path), ".json") || (len(trimmed) > 0 && trimmed[0] == '[') {
+		positions, err = parsePositionsJSON(trimmed)
+	} else {
+		positions, err = parsePositionsCSV(raw, opts.Column)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve position by position, as resolve drops the duplicates.
+	for i := range positions {
+		ids, err := resolve([]string{positions[i].ID}, Options{Resolver: opts.Resolver})
+		if err != nil {
+			return nil, fmt.Errorf("position %d: %w", i+1, err)
+		}
+
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("position %d has no ID", i+1)
+		}
+
+		positions[i].ID = ids[0]
+	}
+
+	return positions, nil
+}
+
+func parsePositionsJSON(raw []byte) ([]Position, error) {
+	var rows []struct {
+		ID       string   `json:"id"`
+		Asset    string   `json:"asset"`
+		Currency string   `json:"currency"`
+		Quantity *float64 `json:"quantity"`
+	}
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of positions: %w", err)
+	}
+
+	positions := make([]Position, len(rows))
+	for i, row := range rows {
+		positions[i] = Position{ID: row.ID, Currency: row.Currency, Quantity: 1.0}
+		if row.ID == "" {
+			positions[i].ID = row.Asset
+		}
+		if row.Quantity != nil {
+			positions[i].Quantity = *row.Quantity
+		}
+	}
+
+	return positions, nil
+}
+
+func parsePositionsCSV(raw []byte, column string) ([]Position, error) {
+	reader := csv.NewReader(bytes.NewReader(raw))
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse CSV: %w", err)
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		header[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	candidates := idColumns
+	if column != "" {
+		candidates = []string{strings.ToLower(column)}
+	}
+
+	idIndex := -1
+	for _, name := range candidates {
+		if i, ok := header[name]; ok {
+			idIndex = i
+
+			break
+		}
+	}
+	if idIndex < 0 {
+		return nil, fmt.Errorf("could not find the ID column in header %v", records[0])
+	}
+
+	currencyIndex, ok := header["currency"]
+	if !ok {
+		return nil, fmt.Errorf("could not find the currency column in header %v", records[0])
+	}
+
+	quantityIndex, hasQuantity := header["quantity"]
+
+	positions := make([]Position, 0, len(records)-1)
+	for i, record := range records[1:] {
+		position := Position{
+			ID:       strings.TrimSpace(record[idIndex]),
+			Currency: strings.TrimSpace(record[currencyIndex]),
+			Quantity: 1.0,
+		}
+
+		if hasQuantity && strings.TrimSpace(record[quantityIndex]) != "" {
+			position.Quantity, err = strconv.ParseFloat(strings.TrimSpace(record[quantityIndex]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quantity: %w", i+2, err)
+			}
+		}
+
+		positions = append(positions, position)
+	}
+
+	return positions, nil
+}
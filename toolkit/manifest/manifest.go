@@ -0,0 +1,250 @@
+/*
+Package manifest records the provenance of a script run next to its output: the
+environment, snapshot, service URLs, hashed inputs, parameters, code version,
+timing and the number of items that succeeded or failed at each stage.
+
+A manifest is written as <output>.manifest.json next to an output file, or as
+manifest.json inside an output folder.
+*/
+package manifest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Input is an input file of the run.
+type Input struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// Stage counts the items processed by a stage of the run.
+type Stage struct {
+	Name      string `json:"name"`
+	Total     int    `json:"total"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+}
+
+// Manifest describes a run. It is safe for concurrent use.
+type Manifest struct {
+	mu sync.Mutex
+
+	Command     string            `json:"command"`
+	Args        []string          `json:"args"`
+	Environment string            `json:"environment,omitempty"`
+	Snapshot    string            `json:"snapshot,omitempty"`
+	Services    map[string]string `json:"services,omitempty"`
+	Inputs      []Input           `json:"inputs,omitempty"`
+	Parameters  map[string]string `json:"parameters,omitempty"`
+	GitCommit   string            `json:"gitCommit,omitempty"`
+	GitDirty    bool              `json:"gitDirty,omitempty"`
+	StartedAt   time.Time         `json:"startedAt"`
+	FinishedAt  time.Time         `json:"finishedAt"`
+	Stages      []Stage           `json:"stages,omitempty"`
+	Outputs     []string          `json:"outputs,omitempty"`
+}
+
+// New starts the manifest of the command, stamping the start time and the
+// code version.
+func New(command string) *Manifest {
+	m := &Manifest{
+		Command:    command,
+		Args:       redactArgs(os.Args[1:]),
+		Services:   make(map[string]string),
+		Parameters: make(map[string]string),
+		StartedAt:  time.Now().UTC(),
+	}
+
+	m.GitCommit, m.GitDirty = gitVersion()
+
+	return m
+}
+
+// RecordFlags records the values of the flags. Secrets are redacted, URL flags
+// are listed as services, and the env and snapshot flags fill the environment
+// and snapshot of the run.
+func (m *Manifest) RecordFlags(fs *flag.FlagSet) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+
+		switch {
+		case isSecret(f.Name):
+			value = redacted
+		case strings.Contains(f.Name, "url"):
+			m.Services[f.Name] = value
+		case f.Name == "env":
+			m.Environment = value
+		case f.Name == "snapshot":
+			m.Snapshot = value
+		}
+
+		m.Parameters[f.Name] = value
+	})
+}
+
+// AddInput records an input file with its hash. Stdin ("-") is recorded
+// without hash.
+func (m *Manifest) AddInput(path string) error {
+	in := Input{Path: path}
+
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("could not open input %s: %w", path, err)
+		}
+		defer file.Close()
+
+		h := sha256.New()
+		n, err := io.Copy(h, file)
+		if err != nil {
+			return fmt.Errorf("could not hash input %s: %w", path, err)
+		}
+
+		in.SHA256 = hex.EncodeToString(h.Sum(nil))
+		in.Size = n
+	}
+
+	m.mu.Lock()
+	m.Inputs = append(m.Inputs, in)
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Count records that succeeded of the total items of the stage went through.
+// Repeated counts of a stage add up.
+func (m *Manifest) Count(stage string, total, succeeded int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.Stages {
+		if m.Stages[i].Name == stage {
+			m.Stages[i].Total += total
+			m.Stages[i].Succeeded += succeeded
+			m.Stages[i].Failed += total - succeeded
+
+			return
+		}
+	}
+
+	m.Stages = append(m.Stages, Stage{Name: stage, Total: total, Succeeded: succeeded, Failed: total - succeeded})
+}
+
+// Write stamps the end time and writes the manifest next to the output, which
+// may be a file or a folder. Several outputs share the manifest of the first.
+func (m *Manifest) Write(outputs ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(outputs) == 0 {
+		return fmt.Errorf("no output to write the manifest of %s next to", m.Command)
+	}
+
+	m.Outputs = outputs
+	m.FinishedAt = time.Now().UTC()
+
+	var raw bytes.Buffer
+	encoder := json.NewEncoder(&raw)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(m); err != nil {
+		return fmt.Errorf("could not marshal manifest: %w", err)
+	}
+
+	path := Path(outputs[0])
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("could not create manifest directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, raw.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("could not write manifest %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Path returns the path of the manifest of the output.
+func Path(output string) string {
+	if strings.HasSuffix(output, "/") || strings.HasSuffix(output, string(filepath.Separator)) {
+		return filepath.Join(output, "manifest.json")
+	}
+
+	if info, err := os.Stat(output); err == nil && info.IsDir() {
+		return filepath.Join(output, "manifest.json")
+	}
+
+	return output + ".manifest.json"
+}
+
+const redacted = "<redacted>"
+
+func isSecret(name string) bool {
+	name = strings.ToLower(name)
+
+	return strings.Contains(name, "token") || strings.Contains(name, "password") || strings.Contains(name, "secret")
+}
+
+// redactArgs hides the values of the secret flags of the command line.
+func redactArgs(args []string) []string {
+	out := make([]string, len(args))
+	copy(out, args)
+
+	for i := 0; i < len(out); i++ {
+		name := strings.TrimLeft(out[i], "-")
+		if !strings.HasPrefix(out[i], "-") || !isSecret(name) {
+			continue
+		}
+
+		if j := strings.Index(name, "="); j >= 0 {
+			out[i] = out[i][:len(out[i])-len(name)+j+1] + redacted
+		} else if i+1 < len(out) {
+			out[i+1] = redacted
+			i++
+		}
+	}
+
+	return out
+}
+
+// gitVersion returns the commit the binary was built from, and whether the
+// tree had local changes. It falls back on the repository of the working
+// directory when the build carries no VCS stamp, as with go run.
+func gitVersion() (string, bool) {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		settings := make(map[string]string, len(info.Settings))
+		for _, s := range info.Settings {
+			settings[s.Key] = s.Value
+		}
+
+		if revision := settings["vcs.revision"]; revision != "" {
+			return revision, settings["vcs.modified"] == "true"
+		}
+	}
+
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", false
+	}
+
+	status, err := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output()
+
+	return strings.TrimSpace(string(out)), err == nil && len(strings.TrimSpace(string(status))) > 0
+}
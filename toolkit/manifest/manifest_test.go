@@ -0,0 +1,73 @@
+package manifest
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Manifest_RecordFlags(t *testing.T) {
+	t.Parallel()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("env", "PROD", "")
+	fs.String("snapshot", "2024-10-13T19:30:05Z", "")
+	fs.String("token-prod", "secret", "")
+	fs.String("arcanist-url", "https://api.edgelab.ch/arcanist", "")
+	fs.Int("batch-size", 50, "")
+	require.NoError(t, fs.Parse([]string{"-batch-size", "10"}))
+
+	m := New("test")
+	m.RecordFlags(fs)
+
+	assert.Equal(t, "PROD", m.Environment)
+	assert.Equal(t, "2024-10-13T19:30:05Z", m.Snapshot)
+	assert.Equal(t, map[string]string{"arcanist-url": "https://api.edgelab.ch/arcanist"}, m.Services)
+	assert.Equal(t, "10", m.Parameters["batch-size"])
+	assert.Equal(t, redacted, m.Parameters["token-prod"])
+}
+
+func Test_redactArgs(t *testing.T) {
+	t.Parallel()
+
+	args := []string{"-token", "a", "-input", "in.csv", "--token-dev=b", "-resume"}
+	assert.Equal(t, []string{"-token", redacted, "-input", "in.csv", "--token-dev=" + redacted, "-resume"}, redactArgs(args))
+}
+
+func Test_Manifest_Write(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	input := filepath.Join(dir, "input.csv")
+	require.NoError(t, os.WriteFile(input, []byte("id\n"), 0o644))
+
+	m := New("test")
+	require.NoError(t, m.AddInput(input))
+	m.Count("eve", 3, 2)
+	m.Count("eve", 1, 1)
+
+	output := filepath.Join(dir, "output.csv")
+	require.NoError(t, m.Write(output))
+
+	raw, err := os.ReadFile(output + ".manifest.json")
+	require.NoError(t, err)
+
+	var written Manifest
+	require.NoError(t, json.Unmarshal(raw, &written))
+
+	require.Len(t, written.Inputs, 1)
+	assert.Equal(t, "984a644ec3b56d32b0404777e1eb73390c4b0742a6a0e183f07861056b6746de", written.Inputs[0].SHA256)
+	assert.EqualValues(t, 3, written.Inputs[0].Size)
+	assert.Equal(t, []Stage{{Name: "eve", Total: 4, Succeeded: 3, Failed: 1}}, written.Stages)
+	assert.Equal(t, []string{output}, written.Outputs)
+	assert.False(t, written.FinishedAt.Before(written.StartedAt))
+
+	assert.Equal(t, filepath.Join(dir, "manifest.json"), Path(dir))
+	assert.Equal(t, filepath.Join("output", "manifest.json"), Path("output/"))
+}
@@ -0,0 +1,98 @@
+// Package cli implements the pricingdiff command, see toolkit/cmd/pricingdiff.
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"toolkit/manifest"
+	"toolkit/pricingdiff"
+)
+
+var (
+	resultA    = "./data/dev_result.json"
+	resultB    = "./data/prod_result.json"
+	requestA   = "./data/dev_request.json"
+	requestB   = "./data/prod_request.json"
+	tolerances = ""
+	sections   = strings.Join(pricingdiff.DefaultSections, ",")
+	outputPath = ""
+	limit      = 50
+)
+
+// Main parses the command line and runs the pricingdiff command.
+func Main() {
+	flag.StringVar(&resultA, "a", resultA, "Eve result of the first environment")
+	flag.StringVar(&resultB, "b", resultB, "Eve result of the second environment")
+	flag.StringVar(&requestA, "request-a", requestA, "Eve request of the first environment (empty to skip the request)")
+	flag.StringVar(&requestB, "request-b", requestB, "Eve request of the second environment (empty to skip the request)")
+	flag.StringVar(&tolerances, "tolerance", tolerances, "comma-separated metric=abs:rel tolerances, e.g. main.NPV=1e-8:1e-6,scenarios=:1e-4,default=1e-12:")
+	flag.StringVar(&sections, "sections", sections, "comma-separated result sections to compare")
+	flag.StringVar(&outputPath, "output", outputPath, "optional JSON report")
+	flag.IntVar(&limit, "limit", limit, "number of result differences printed")
+	flag.Parse()
+
+	run := manifest.New("pricing diff")
+	run.RecordFlags(flag.CommandLine)
+
+	tol, err := pricingdiff.ParseTolerances(tolerances)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	comparer := pricingdiff.Comparer{Tolerances: tol}
+	report := pricingdiff.Report{A: resultA, B: resultB, Tolerances: tol}
+
+	a, b := read(run, resultA), read(run, resultB)
+	report.Results, err = comparer.CompareResults(a, b, strings.Split(sections, ",")...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report.ResultsSummary = pricingdiff.Summarize(report.Results)
+
+	if requestA != "" && requestB != "" {
+		diffs, err := comparer.CompareRequests(read(run, requestA), read(run, requestB))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		report.Request = pricingdiff.Summarize(diffs)
+	}
+
+	if err := report.WriteText(os.Stdout, limit); err != nil {
+		log.Fatal(err)
+	}
+
+	if outputPath == "" {
+		return
+	}
+
+	raw, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatalf("could not marshal report: %v", err)
+	}
+
+	if err := os.WriteFile(outputPath, raw, 0o644); err != nil {
+		log.Fatalf("could not write %s: %v", outputPath, err)
+	}
+
+	if err := run.Write(outputPath); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func read(run *manifest.Manifest, path string) []byte {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("could not read %s: %v", path, err)
+	}
+
+	if err := run.AddInput(path); err != nil {
+		log.Fatal(err)
+	}
+
+	return raw
+}
@@ -0,0 +1,270 @@
+/*
+Package pricingdiff compares Eve request and result documents of two
+environments, typically DEV and PROD.
+
+Results are walked under the sections of interest (results.main,
+results.probabilities and results.scenarios by default). Every {value, status}
+leaf is a metric: a status mismatch is reported as such, otherwise values are
+compared within the tolerance of the metric. Requests are walked entirely and
+every differing field is reported, numbers within the default tolerance being
+equal.
+
+Paths are dotted, list items being written [i]. Scenario IDs, currencies and
+UUIDs are path segments, so that results.scenarios.6500 and
+results.main.NPV are both paths; Pattern collapses the IDs to group
+differences.
+*/
+package pricingdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of differences.
+const (
+	// KindValue is a value out of tolerance.
+	KindValue = "value"
+	// KindStatus is a metric whose status differs.
+	KindStatus = "status"
+	// KindMissing is a field present on one side only.
+	KindMissing = "missing"
+	// KindType is a field of different JSON types.
+	KindType = "type"
+)
+
+// DefaultSections are the result sections compared by CompareResults.
+var DefaultSections = []string{"main", "probabilities", "scenarios"}
+
+// Difference is a field that differs between the two documents. A and B hold
+// the values of each side, nil when missing.
+type Difference struct {
+	Path    string  `json:"path"`
+	Kind    string  `json:"kind"`
+	A       any     `json:"a"`
+	B       any     `json:"b"`
+	AbsDiff float64 `json:"absDiff,omitempty"`
+	RelDiff float64 `json:"relDiff,omitempty"`
+}
+
+// Comparer compares documents with tolerances.
+type Comparer struct {
+	Tolerances Tolerances
+}
+
+// CompareResults compares two Eve results on the sections, DefaultSections
+// when none is given, along with their statusKey and error.
+func (c Comparer) CompareResults(a, b []byte, sections ...string) ([]Difference, error) {
+	var docA, docB map[string]any
+	if err := json.Unmarshal(a, &docA); err != nil {
+		return nil, fmt.Errorf("could not unmarshal first result: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &docB); err != nil {
+		return nil, fmt.Errorf("could not unmarshal second result: %w", err)
+	}
+
+	if len(sections) == 0 {
+		sections = DefaultSections
+	}
+
+	diffs := make([]Difference, 0)
+	for _, key := range []string{"statusKey", "error"} {
+		c.walk(key, docA[key], docB[key], &diffs)
+	}
+
+	resultsA, _ := docA["results"].(map[string]any)
+	resultsB, _ := docB["results"].(map[string]any)
+	for _, section := range sections {
+		c.walk("results."+section, resultsA[section], resultsB[section], &diffs)
+	}
+
+	sortDifferences(diffs)
+
+	return diffs, nil
+}
+
+// CompareRequests compares two Eve requests field by field.
+func (c Comparer) CompareRequests(a, b []byte) ([]Difference, error) {
+	var docA, docB any
+	if err := json.Unmarshal(a, &docA); err != nil {
+		return nil, fmt.Errorf("could not unmarshal first request: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &docB); err != nil {
+		return nil, fmt.Errorf("could not unmarshal second request: %w", err)
+	}
+
+	diffs := make([]Difference, 0)
+	c.walk("", docA, docB, &diffs)
+	sortDifferences(diffs)
+
+	return diffs, nil
+}
+
+func (c Comparer) walk(path string, a, b any, diffs *[]Difference) {
+	if a == nil || b == nil {
+		if a != nil || b != nil {
+			*diffs = append(*diffs, Difference{Path: path, Kind: KindMissing, A: a, B: b})
+		}
+
+		return
+	}
+
+	switch va := a.(type) {
+	case map[string]any:
+		vb, ok := b.(map[string]any)
+		if !ok {
+			*diffs = append(*diffs, Difference{Path: path, Kind: KindType, A: a, B: b})
+
+			return
+		}
+
+		if isMetric(va) && isMetric(vb) {
+			c.metric(path, va, vb, diffs)
+
+			return
+		}
+
+		for _, key := range unionKeys(va, vb) {
+			c.walk(join(path, key), va[key], vb[key], diffs)
+		}
+	case []any:
+		vb, ok := b.([]any)
+		if !ok {
+			*diffs = append(*diffs, Difference{Path: path, Kind: KindType, A: a, B: b})
+
+			return
+		}
+
+		for i := 0; i < len(va) || i < len(vb); i++ {
+			var ia, ib any
+			if i < len(va) {
+				ia = va[i]
+			}
+			if i < len(vb) {
+				ib = vb[i]
+			}
+
+			c.walk(fmt.Sprintf("%s[%d]", path, i), ia, ib, diffs)
+		}
+	case float64:
+		vb, ok := b.(float64)
+		if !ok {
+			*diffs = append(*diffs, Difference{Path: path, Kind: KindType, A: a, B: b})
+
+			return
+		}
+
+		c.number(path, va, vb, diffs)
+	default:
+		if !reflect.DeepEqual(a, b) {
+			kind := KindValue
+			if reflect.TypeOf(a) != reflect.TypeOf(b) {
+				kind = KindType
+			}
+
+			*diffs = append(*diffs, Difference{Path: path, Kind: kind, A: a, B: b})
+		}
+	}
+}
+
+// metric compares two {value, status} leaves.
+func (c Comparer) metric(path string, a, b map[string]any, diffs *[]Difference) {
+	if a["status"] != b["status"] {
+		*diffs = append(*diffs, Difference{Path: path, Kind: KindStatus, A: a["status"], B: b["status"]})
+
+		return
+	}
+
+	c.walk(path, a["value"], b["value"], diffs)
+}
+
+func (c Comparer) number(path string, a, b float64, diffs *[]Difference) {
+	if a == b {
+		return
+	}
+
+	abs, rel := Deviation(a, b)
+	if c.Tolerances.For(path).Within(a, b) {
+		return
+	}
+
+	*diffs = append(*diffs, Difference{Path: path, Kind: KindValue, A: a, B: b, AbsDiff: abs, RelDiff: rel})
+}
+
+// Deviation returns the absolute difference of the values and the difference
+// relative to the larger in magnitude.
+func Deviation(a, b float64) (float64, float64) {
+	abs := math.Abs(a - b)
+	scale := math.Max(math.Abs(a), math.Abs(b))
+	if scale == 0 {
+		return abs, 0
+	}
+
+	return abs, abs / scale
+}
+
+func isMetric(m map[string]any) bool {
+	_, hasStatus := m["status"]
+	_, hasValue := m["value"]
+
+	return hasStatus && (hasValue || len(m) == 1)
+}
+
+func unionKeys(a, b map[string]any) []string {
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// sortDifferences orders differences by path, numeric segments compared as
+// numbers so that scenario 999 comes before scenario 1000.
+func sortDifferences(diffs []Difference) {
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return lessPath(diffs[i].Path, diffs[j].Path)
+	})
+}
+
+func lessPath(a, b string) bool {
+	sa, sb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(sa) && i < len(sb); i++ {
+		if sa[i] == sb[i] {
+			continue
+		}
+
+		na, errA := strconv.ParseFloat(sa[i], 64)
+		nb, errB := strconv.ParseFloat(sb[i], 64)
+		if errA == nil && errB == nil {
+			return na < nb
+		}
+
+		return sa[i] < sb[i]
+	}
+
+	return len(sa) < len(sb)
+}
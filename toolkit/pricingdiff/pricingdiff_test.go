@@ -0,0 +1,109 @@
+package pricingdiff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	resultA = `{
+		"results": {
+			"main": {"NPV": {"value": 100.0, "status": "Success"}, "theta": {"value": 0.01, "status": "Success"}},
+			"probabilities": {"barrierHit": {"u1": {"value": 0.5, "status": "Success"}}},
+			"scenarios": {"1000": {"value": 90.0, "status": "Success"}, "999": {"value": 95.0, "status": "Success"}},
+			"sensitivities": {"delta": {"u1": {"value": 1.0, "status": "Success"}}}
+		},
+		"statusKey": "Success"
+	}`
+	resultB = `{
+		"results": {
+			"main": {"NPV": {"value": 100.05, "status": "Success"}, "theta": {"value": 0.01, "status": "Success"}},
+			"probabilities": {"barrierHit": {"u1": {"status": "NotComputed"}}},
+			"scenarios": {"1000": {"value": 80.0, "status": "Success"}, "999": {"value": 95.0000000001, "status": "Success"}},
+			"sensitivities": {"delta": {"u1": {"value": 2.0, "status": "Success"}}}
+		},
+		"statusKey": "Success"
+	}`
+)
+
+func Test_CompareResults(t *testing.T) {
+	t.Parallel()
+
+	diffs, err := Comparer{Tolerances: Tolerances{Default: DefaultTolerance}}.CompareResults([]byte(resultA), []byte(resultB))
+	require.NoError(t, err)
+
+	require.Len(t, diffs, 3)
+	assert.Equal(t, Difference{Path: "results.main.NPV", Kind: KindValue, A: 100.0, B: 100.05, AbsDiff: diffs[0].AbsDiff, RelDiff: diffs[0].RelDiff}, diffs[0])
+	assert.InDelta(t, 0.05, diffs[0].AbsDiff, 1e-9)
+	assert.Equal(t, Difference{Path: "results.probabilities.barrierHit.u1", Kind: KindStatus, A: "Success", B: "NotComputed"}, diffs[1])
+	assert.Equal(t, "results.scenarios.1000", diffs[2].Path)
+
+	tol, err := ParseTolerances("main.NPV=0.1:,scenarios=:0.2")
+	require.NoError(t, err)
+
+	diffs, err = Comparer{Tolerances: tol}.CompareResults([]byte(resultA), []byte(resultB), "main", "scenarios", "sensitivities")
+	require.NoError(t, err)
+	require.Len(t, diffs, 1)
+	assert.Equal(t, "results.sensitivities.delta.u1", diffs[0].Path)
+}
+
+func Test_CompareRequests(t *testing.T) {
+	t.Parallel()
+
+	a := `{"asset": {"id": "x", "coupons": [1, 2], "maturity": "2025-04-22"}, "spot": {"2024-01-01": 10}}`
+	b := `{"asset": {"id": "x", "coupons": [1, 2, 3], "maturity": "2025-04-23"}, "spot": {"2024-01-01": "10"}}`
+
+	diffs, err := Comparer{Tolerances: Tolerances{Default: DefaultTolerance}}.CompareRequests([]byte(a), []byte(b))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Difference{
+		{Path: "asset.coupons[2]", Kind: KindMissing, B: 3.0},
+		{Path: "asset.maturity", Kind: KindValue, A: "2025-04-22", B: "2025-04-23"},
+		{Path: "spot.2024-01-01", Kind: KindType, A: 10.0, B: "10"},
+	}, diffs)
+}
+
+func Test_Tolerances(t *testing.T) {
+	t.Parallel()
+
+	tol, err := ParseTolerances("default=1e-6:,main=1:,main.NPV=:0.01,probabilities.*.u1=0.5:")
+	require.NoError(t, err)
+
+	assert.Equal(t, Tolerance{Abs: 1e-6}, tol.For("results.scenarios.6500"))
+	assert.Equal(t, Tolerance{Abs: 1}, tol.For("results.main.theta"))
+	assert.Equal(t, Tolerance{Rel: 0.01}, tol.For("results.main.NPV"))
+	assert.Equal(t, Tolerance{Abs: 0.5}, tol.For("results.probabilities.barrierHit.u1"))
+
+	assert.True(t, Tolerance{Rel: 0.01}.Within(100, 100.5))
+	assert.False(t, Tolerance{Rel: 0.01}.Within(100, 102))
+
+	_, err = ParseTolerances("main.NPV")
+	assert.Error(t, err)
+}
+
+func Test_Summarize(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "underlyings.*.spot.scenarios.*", Pattern("underlyings.4968068a-3c09-4863-a6f6-982233d71e0e.spot.scenarios.6500"))
+	assert.Equal(t, "asset.coupons[*].date", Pattern("asset.coupons[2].date"))
+	assert.Equal(t, "spot.timeseries.*", Pattern("spot.timeseries.2022-10-19"))
+
+	groups := Summarize([]Difference{
+		{Path: "results.main.NPV", Kind: KindValue, AbsDiff: 0.1},
+		{Path: "results.scenarios.1", Kind: KindValue, AbsDiff: 0.1},
+		{Path: "results.scenarios.2", Kind: KindValue, AbsDiff: 0.3, RelDiff: 0.2},
+	})
+
+	require.Len(t, groups, 2)
+	assert.Equal(t, "results.scenarios.*", groups[0].Pattern)
+	assert.Equal(t, 2, groups[0].Count)
+	assert.Equal(t, "results.scenarios.2", groups[0].Worst.Path)
+	assert.InDelta(t, 0.2, groups[0].MaxRelDiff, 1e-12)
+
+	var out bytes.Buffer
+	require.NoError(t, Report{A: "a", B: "b", Results: []Difference{{Path: "results.main.NPV", Kind: KindStatus}}, ResultsSummary: groups}.WriteText(&out, 10))
+	assert.Contains(t, out.String(), "1 status mismatches")
+}
@@ -0,0 +1,133 @@
+package pricingdiff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// Group gathers the differences of a kind sharing a path pattern.
+type Group struct {
+	Pattern    string  `json:"pattern"`
+	Kind       string  `json:"kind"`
+	Count      int     `json:"count"`
+	MaxAbsDiff float64 `json:"maxAbsDiff,omitempty"`
+	MaxRelDiff float64 `json:"maxRelDiff,omitempty"`
+
+	// Worst is the difference of the group with the largest absolute
+	// deviation, or the first one.
+	Worst Difference `json:"worst"`
+}
+
+// Summarize groups the differences by pattern and kind, the most populated
+// groups first.
+func Summarize(diffs []Difference) []Group {
+	index := make(map[string]int)
+	groups := make([]Group, 0)
+	for _, d := range diffs {
+		key := d.Kind + " " + Pattern(d.Path)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, Group{Pattern: Pattern(d.Path), Kind: d.Kind, Worst: d})
+		}
+
+		g := &groups[i]
+		g.Count++
+		if d.AbsDiff > g.MaxAbsDiff {
+			g.MaxAbsDiff = d.AbsDiff
+			g.Worst = d
+		}
+		if d.RelDiff > g.MaxRelDiff {
+			g.MaxRelDiff = d.RelDiff
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Count > groups[j].Count
+	})
+
+	return groups
+}
+
+// Report is the comparison of the requests and results of two environments.
+type Report struct {
+	A string `json:"a"`
+	B string `json:"b"`
+
+	Tolerances Tolerances `json:"tolerances"`
+
+	// Results lists every result difference, Request only the groups since
+	// market data differences easily run into the tens of thousands.
+	Results        []Difference `json:"results"`
+	ResultsSummary []Group      `json:"resultsSummary"`
+	Request        []Group      `json:"request,omitempty"`
+}
+
+// StatusMismatches returns the number of metrics whose status differs.
+func (r Report) StatusMismatches() int {
+	n := 0
+	for _, d := range r.Results {
+		if d.Kind == KindStatus {
+			n++
+		}
+	}
+
+	return n
+}
+
+// WriteText writes the report for a human reader, listing at most limit
+// result differences.
+func (r Report) WriteText(w io.Writer, limit int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Comparing %s (A) to %s (B)\n\n", r.A, r.B)
+
+	if len(r.Results) == 0 {
+		fmt.Fprintln(tw, "Results: identical within tolerances")
+	} else {
+		fmt.Fprintf(tw, "Results: %d differences, %d status mismatches\n\n", len(r.Results), r.StatusMismatches())
+
+		fmt.Fprintln(tw, "PATTERN\tKIND\tCOUNT\tMAX ABS\tMAX REL\tWORST")
+		for _, g := range r.ResultsSummary {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%.3g\t%.3g\t%s\n", g.Pattern, g.Kind, g.Count, g.MaxAbsDiff, g.MaxRelDiff, g.Worst.Path)
+		}
+
+		fmt.Fprintln(tw, "\nPATH\tKIND\tA\tB\tABS\tREL")
+		for i, d := range r.Results {
+			if i == limit {
+				fmt.Fprintf(tw, "... %d more\n", len(r.Results)-limit)
+
+				break
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%v\t%v\t%.3g\t%.3g\n", d.Path, d.Kind, d.A, d.B, d.AbsDiff, d.RelDiff)
+		}
+	}
+
+	if r.Request != nil {
+		if len(r.Request) == 0 {
+			fmt.Fprintln(tw, "\nRequest: identical within tolerances")
+		} else {
+			fmt.Fprintln(tw, "\nRequest:\nPATTERN\tKIND\tCOUNT\tMAX ABS\tMAX REL\tEXAMPLE\tA\tB")
+			for _, g := range r.Request {
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%.3g\t%.3g\t%s\t%s\t%s\n",
+					g.Pattern, g.Kind, g.Count, g.MaxAbsDiff, g.MaxRelDiff, g.Worst.Path, short(g.Worst.A), short(g.Worst.B))
+			}
+		}
+	}
+
+	return tw.Flush()
+}
+
+// short formats a value on a table cell.
+func short(v any) string {
+	s := fmt.Sprint(v)
+	if len(s) > 40 {
+		return s[:37] + "..."
+	}
+
+	return s
+}
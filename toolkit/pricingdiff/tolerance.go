@@ -0,0 +1,135 @@
+package pricingdiff
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Tolerance bounds the deviation under which two numbers are equal: either
+// the absolute difference is at most Abs or the relative one at most Rel.
+type Tolerance struct {
+	Abs float64 `json:"abs"`
+	Rel float64 `json:"rel"`
+}
+
+// Within tells whether the values are equal within the tolerance.
+func (t Tolerance) Within(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+
+	abs, rel := Deviation(a, b)
+
+	return abs <= t.Abs || rel <= t.Rel
+}
+
+// DefaultTolerance only absorbs floating-point noise.
+var DefaultTolerance = Tolerance{Abs: 1e-12, Rel: 1e-10}
+
+// Tolerances holds the tolerances per metric. Metrics are path prefixes, with
+// or without the leading "results.", whose segments may be "*": "main.NPV",
+// "scenarios", "probabilities.*.7ea5a5f1-4440-418b-9579-68b2f620cb4e". The
+// longest matching metric applies, Default otherwise.
+type Tolerances struct {
+	Default   Tolerance            `json:"default"`
+	PerMetric map[string]Tolerance `json:"perMetric,omitempty"`
+}
+
+// For returns the tolerance of the path.
+func (t Tolerances) For(path string) Tolerance {
+	segments := strings.Split(strings.TrimPrefix(path, "results."), ".")
+
+	best, bestLen := t.Default, -1
+	for metric, tol := range t.PerMetric {
+		keys := strings.Split(strings.TrimPrefix(metric, "results."), ".")
+		if len(keys) <= bestLen || !matchPrefix(keys, segments) {
+			continue
+		}
+
+		best, bestLen = tol, len(keys)
+	}
+
+	return best
+}
+
+func matchPrefix(keys, segments []string) bool {
+	if len(keys) > len(segments) {
+		return false
+	}
+
+	for i, k := range keys {
+		if k != "*" && k != segments[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseTolerances parses comma-separated metric=abs:rel tolerances, either
+// bound being optional, e.g. "main.NPV=1e-6:1e-4,scenarios=:1e-3". The
+// metric "default" sets the default tolerance.
+func ParseTolerances(value string) (Tolerances, error) {
+	t := Tolerances{Default: DefaultTolerance, PerMetric: make(map[string]Tolerance)}
+	if strings.TrimSpace(value) == "" {
+		return t, nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		metric, bounds, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return t, fmt.Errorf("tolerance %q is not of the form metric=abs:rel", part)
+		}
+
+		absPart, relPart, _ := strings.Cut(bounds, ":")
+
+		var tol Tolerance
+		var err error
+		if absPart != "" {
+			if tol.Abs, err = strconv.ParseFloat(absPart, 64); err != nil {
+				return t, fmt.Errorf("invalid absolute tolerance of %s: %w", metric, err)
+			}
+		}
+
+		if relPart != "" {
+			if tol.Rel, err = strconv.ParseFloat(relPart, 64); err != nil {
+				return t, fmt.Errorf("invalid relative tolerance of %s: %w", metric, err)
+			}
+		}
+
+		if metric == "default" {
+			t.Default = tol
+
+			continue
+		}
+
+		t.PerMetric[metric] = tol
+	}
+
+	return t, nil
+}
+
+var (
+	idSegment    = regexp.MustCompile(`^([0-9]+|[0-9]{4}-[0-9]{2}-[0-9]{2}|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
+	indexSegment = regexp.MustCompile(`\[[0-9]+\]`)
+)
+
+// Pattern replaces the scenario IDs, UUIDs, dates and list indexes of the path
+// by "*", so that results.scenarios.6500 and results.scenarios.6501 share the
+// pattern results.scenarios.*.
+func Pattern(path string) string {
+	segments := strings.Split(path, ".")
+	for i, s := range segments {
+		s = indexSegment.ReplaceAllString(s, "[*]")
+		if idSegment.MatchString(s) {
+			s = "*"
+		}
+
+		segments[i] = s
+	}
+
+	return strings.Join(segments, ".")
+}
@@ -0,0 +1,64 @@
+package risk
+
+import "sort"
+
+// Contribution is the part of the ES of a total coming from one of its
+// components, a position or a risk factor.
+type Contribution struct {
+	Name string  `json:"name"`
+	ES   float64 `json:"es"`
+
+	// Share is the contribution relative to the ES of the total.
+	Share float64 `json:"share"`
+}
+
+// Contributions splits the ES of the total at the confidence level between
+// its components, given the P&L of each component per scenario. The
+// contribution of a component is its mean P&L over the tail of the total, so
+// that contributions of components adding up to the total add up to its ES.
+// Scenarios missing from a component count as a zero P&L. Contributions are
+// sorted from the largest loss.
+func Contributions(total []Observation, components map[string]map[uint32]float64, confidence float64) ([]Contribution, float64, error) {
+	tail, err := Tail(total, confidence)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var weight, es float64
+	for _, o := range tail {
+		weight += o.Weight
+		es += o.Weight * o.PnL
+	}
+	es /= weight
+
+	contributions := make([]Contribution, 0, len(components))
+	for name, pnl := range components {
+		c := Contribution{Name: name}
+		for _, o := range tail {
+			c.ES += o.Weight * pnl[o.ID]
+		}
+		c.ES /= weight
+
+		if es != 0 {
+			c.Share = c.ES / es
+		}
+
+		contributions = append(contributions, c)
+	}
+
+	SortContributions(contributions)
+
+	return contributions, es, nil
+}
+
+// SortContributions sorts contributions from the largest loss to the largest
+// gain, ties broken by name.
+func SortContributions(contributions []Contribution) {
+	sort.SliceStable(contributions, func(i, j int) bool {
+		if contributions[i].ES != contributions[j].ES {
+			return contributions[i].ES < contributions[j].ES
+		}
+
+		return contributions[i].Name < contributions[j].Name
+	})
+}
@@ -0,0 +1,286 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+)
+
+// Parametric models.
+const (
+	Gaussian      = "gaussian"
+	StudentT      = "student-t"
+	CornishFisher = "cornish-fisher"
+)
+
+// Moments are the weighted moments of the P&L. Kurtosis is the excess
+// kurtosis, zero for a Gaussian.
+type Moments struct {
+	Mean     float64 `json:"mean"`
+	StdDev   float64 `json:"stdDev"`
+	Skewness float64 `json:"skewness"`
+	Kurtosis float64 `json:"kurtosis"`
+}
+
+// Parametric is the VaR and ES of a parametric model fitted on the P&L.
+type Parametric struct {
+	Model string  `json:"model"`
+	VaR   float64 `json:"var"`
+	ES    float64 `json:"es"`
+
+	// DoF are the fitted degrees of freedom of the Student-t.
+	DoF float64 `json:"dof,omitempty"`
+}
+
+// ComputeMoments returns the weighted moments of the observations.
+func ComputeMoments(obs []Observation) (Moments, error) {
+	sorted, total, err := prepare(obs)
+	if err != nil {
+		return Moments{}, err
+	}
+
+	var m Moments
+	for _, o := range sorted {
+		m.Mean += o.Weight * o.PnL
+	}
+	m.Mean /= total
+
+	var m2, m3, m4 float64
+	for _, o := range sorted {
+		d := o.PnL - m.Mean
+		m2 += o.Weight * d * d
+		m3 += o.Weight * d * d * d
+		m4 += o.Weight * d * d * d * d
+	}
+	m2, m3, m4 = m2/total, m3/total, m4/total
+
+	m.StdDev = math.Sqrt(m2)
+	if m2 > 0 {
+		m.Skewness = m3 / math.Pow(m2, 1.5)
+		m.Kurtosis = m4/(m2*m2) - 3
+	}
+
+	return m, nil
+}
+
+// ParametricMeasures returns the Gaussian, Student-t and Cornish-Fisher VaR
+// and ES of the observations at the confidence level, with the P&L sign of
+// Compute.
+//
+// The Student-t has the mean and variance of the P&L, its degrees of freedom
+// maximising the likelihood. The Cornish-Fisher VaR expands the Gaussian
+// quantile with the skewness and kurtosis; its ES averages the expanded
+// quantiles over the tail.
+func ParametricMeasures(obs []Observation, confidence float64) (Moments, []Parametric, error) {
+	if confidence <= 0 || confidence >= 1 || math.IsNaN(confidence) {
+		return Moments{}, nil, fmt.Errorf("confidence level %v is not in (0, 1)", confidence)
+	}
+
+	m, err := ComputeMoments(obs)
+	if err != nil {
+		return Moments{}, nil, err
+	}
+
+	alpha := 1 - confidence
+
+	// Gaussian.
+	z := NormalQuantile(alpha)
+	gaussian := Parametric{
+		Model: Gaussian,
+		VaR:   m.Mean + m.StdDev*z,
+		ES:    m.Mean - m.StdDev*normalPDF(z)/alpha,
+	}
+
+	// Student-t scaled to the variance of the P&L.
+	nu := fitDoF(obs, m)
+	q := StudentQuantile(alpha, nu)
+	scale := m.StdDev * math.Sqrt((nu-2)/nu)
+	student := Parametric{
+		Model: StudentT,
+		VaR:   m.Mean + scale*q,
+		ES:    m.Mean - scale*studentPDF(q, nu)/alpha*(nu+q*q)/(nu-1),
+		DoF:   nu,
+	}
+
+	// Cornish-Fisher, the ES being the mean of the expanded quantiles over a
+	// midpoint grid of the tail.
+	const steps = 1000
+	es := 0.0
+	for i := 0; i < steps; i++ {
+		es += cornishFisher(NormalQuantile(alpha*(float64(i)+0.5)/steps), m.Skewness, m.Kurtosis)
+	}
+	cf := Parametric{
+		Model: CornishFisher,
+		VaR:   m.Mean + m.StdDev*cornishFisher(z, m.Skewness, m.Kurtosis),
+		ES:    m.Mean + m.StdDev*es/steps,
+	}
+
+	return m, []Parametric{gaussian, student, cf}, nil
+}
+
+func cornishFisher(z, skew, kurt float64) float64 {
+	return z + (z*z-1)*skew/6 + (z*z*z-3*z)*kurt/24 - (2*z*z*z-5*z)*skew*skew/36
+}
+
+func normalPDF(z float64) float64 {
+	return math.Exp(-z*z/2) / math.Sqrt(2*math.Pi)
+}
+
+// Bounds of the fitted degrees of freedom, the upper one being close enough
+// to a Gaussian.
+const (
+	minDoF = 2.05
+	maxDoF = 500
+)
+
+// fitDoF returns the degrees of freedom of the Student-t of the mean and
+// variance of the P&L maximising the weighted likelihood, by golden-section
+// search on their logarithm.
+func fitDoF(obs []Observation, m Moments) float64 {
+	if m.StdDev == 0 {
+		return maxDoF
+	}
+
+	loglik := func(logNu float64) float64 {
+		nu := math.Exp(logNu)
+		scale := m.StdDev * math.Sqrt((nu-2)/nu)
+
+		ll := 0.0
+		for _, o := range obs {
+			if o.Weight > 0 {
+				ll += o.Weight * math.Log(studentPDF((o.PnL-m.Mean)/scale, nu)/scale)
+			}
+		}
+
+		return ll
+	}
+
+	a, b := math.Log(minDoF), math.Log(maxDoF)
+	ratio := (math.Sqrt(5) - 1) / 2
+	c, d := b-ratio*(b-a), a+ratio*(b-a)
+	fc, fd := loglik(c), loglik(d)
+	for i := 0; i < 100 && b-a > 1e-6; i++ {
+		if fc > fd {
+			b, d, fd = d, c, fc
+			c = b - ratio*(b-a)
+			fc = loglik(c)
+		} else {
+			a, c, fc = c, d, fd
+			d = a + ratio*(b-a)
+			fd = loglik(d)
+		}
+	}
+
+	return math.Exp((a + b) / 2)
+}
+
+func studentPDF(t, nu float64) float64 {
+	a, _ := math.Lgamma((nu + 1) / 2)
+	b, _ := math.Lgamma(nu / 2)
+
+	return math.Exp(a - b - 0.5*math.Log(nu*math.Pi) - (nu+1)/2*math.Log1p(t*t/nu))
+}
+
+// StudentCDF returns P(T <= t) for a Student-t of nu degrees of freedom.
+func StudentCDF(t, nu float64) float64 {
+	tail := 0.5 * regularizedBeta(nu/(nu+t*t), nu/2, 0.5)
+	if t > 0 {
+		return 1 - tail
+	}
+
+	return tail
+}
+
+// StudentQuantile returns the quantile at p in (0, 1) of a Student-t of nu
+// degrees of freedom, by bisection of the CDF.
+func StudentQuantile(p, nu float64) float64 {
+	lo, hi := -1.0, 1.0
+	for StudentCDF(lo, nu) > p {
+		lo *= 2
+	}
+	for StudentCDF(hi, nu) < p {
+		hi *= 2
+	}
+
+	for i := 0; i < 200 && hi-lo > 1e-12*math.Max(1, math.Abs(lo)); i++ {
+		mid := (lo + hi) / 2
+		if StudentCDF(mid, nu) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return (lo + hi) / 2
+}
+
+// regularizedBeta returns the regularized incomplete beta function I_x(a, b)
+// from its continued fraction.
+func regularizedBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log1p(-x))
+
+	if x > (a+1)/(a+b+2) {
+		return 1 - regularizedBeta(1-x, b, a)
+	}
+
+	return front * betaFraction(x, a, b) / a
+}
+
+// betaFraction evaluates the continued fraction of the incomplete beta
+// function by the modified Lentz method.
+func betaFraction(x, a, b float64) float64 {
+	const tiny = 1e-300
+
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	f := d
+
+	for m := 1; m <= 300; m++ {
+		fm := float64(m)
+
+		// Even step.
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		f *= d * c
+
+		// Odd step.
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		f *= delta
+
+		if math.Abs(delta-1) < 1e-14 {
+			break
+		}
+	}
+
+	return f
+}
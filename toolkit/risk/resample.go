@@ -0,0 +1,197 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Interval is a confidence interval around the estimate of a measure.
+type Interval struct {
+	Estimate float64 `json:"estimate"`
+	Lower    float64 `json:"lower"`
+	Upper    float64 `json:"upper"`
+	StdErr   float64 `json:"stdErr"`
+
+	// Bias is the jackknife estimate of the bias of the estimate, zero for
+	// the bootstrap.
+	Bias float64 `json:"bias,omitempty"`
+}
+
+// Intervals are the confidence intervals of the measures of a set of
+// observations.
+type Intervals struct {
+	Method    string  `json:"method"`
+	Level     float64 `json:"level"`
+	Resamples int     `json:"resamples"`
+	Seed      int64   `json:"seed,omitempty"`
+
+	VaR        Interval `json:"var"`
+	ES         Interval `json:"es"`
+	Volatility Interval `json:"volatility"`
+}
+
+// Bootstrap returns percentile bootstrap intervals at the level for the
+// measures at the confidence level. Each of the resamples draws as many
+// observations as given, with replacement, from a generator seeded with seed
+// so that runs are reproducible.
+func Bootstrap(obs []Observation, confidence, level float64, resamples int, seed int64) (Intervals, error) {
+	if resamples < 2 {
+		return Intervals{}, fmt.Errorf("%d resamples, at least 2 are needed", resamples)
+	}
+
+	if level <= 0 || level >= 1 {
+		return Intervals{}, fmt.Errorf("interval level %v is not in (0, 1)", level)
+	}
+
+	point, err := Compute(obs, confidence)
+	if err != nil {
+		return Intervals{}, err
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	sample := make([]Observation, len(obs))
+	estimates := make([]Measures, 0, resamples)
+	for i := 0; i < resamples; i++ {
+		for j := range sample {
+			sample[j] = obs[rng.Intn(len(obs))]
+		}
+
+		m, err := Compute(sample, confidence)
+		if err != nil {
+			// A resample may only draw zero weights.
+			continue
+		}
+
+		estimates = append(estimates, m)
+	}
+
+	if len(estimates) < 2 {
+		return Intervals{}, fmt.Errorf("fewer than 2 valid resamples")
+	}
+
+	percentile := func(point float64, get func(Measures) float64) Interval {
+		values := make([]float64, len(estimates))
+		for i, m := range estimates {
+			values[i] = get(m)
+		}
+		sort.Float64s(values)
+
+		_, sd := meanStd(values, 1)
+
+		return Interval{
+			Estimate: point,
+			Lower:    quantile(values, (1-level)/2),
+			Upper:    quantile(values, (1+level)/2),
+			StdErr:   sd,
+		}
+	}
+
+	return Intervals{
+		Method:     "bootstrap",
+		Level:      level,
+		Resamples:  len(estimates),
+		Seed:       seed,
+		VaR:        percentile(point.VaR, func(m Measures) float64 { return m.VaR }),
+		ES:         percentile(point.ES, func(m Measures) float64 { return m.ES }),
+		Volatility: percentile(point.Volatility, func(m Measures) float64 { return m.Volatility }),
+	}, nil
+}
+
+// Jackknife returns normal intervals at the level for the measures at the
+// confidence level, from the leave-one-out estimates. The VaR, a quantile, is
+// not smooth and its jackknife error is known to be unreliable; the bootstrap
+// should be preferred for it.
+func Jackknife(obs []Observation, confidence, level float64) (Intervals, error) {
+	if level <= 0 || level >= 1 {
+		return Intervals{}, fmt.Errorf("interval level %v is not in (0, 1)", level)
+	}
+
+	if len(obs) < 2 {
+		return Intervals{}, fmt.Errorf("%d observations, at least 2 are needed", len(obs))
+	}
+
+	point, err := Compute(obs, confidence)
+	if err != nil {
+		return Intervals{}, err
+	}
+
+	n := len(obs)
+	sample := make([]Observation, n-1)
+	estimates := make([]Measures, 0, n)
+	for i := range obs {
+		copy(sample, obs[:i])
+		copy(sample[i:], obs[i+1:])
+
+		m, err := Compute(sample, confidence)
+		if err != nil {
+			return Intervals{}, fmt.Errorf("could not leave scenario %d out: %w", obs[i].ID, err)
+		}
+
+		estimates = append(estimates, m)
+	}
+
+	z := NormalQuantile((1 + level) / 2)
+	normal := func(point float64, get func(Measures) float64) Interval {
+		values := make([]float64, n)
+		for i, m := range estimates {
+			values[i] = get(m)
+		}
+
+		mean, sd := meanStd(values, 0)
+		se := math.Sqrt(float64(n-1)) * sd
+
+		return Interval{
+			Estimate: point,
+			Lower:    point - z*se,
+			Upper:    point + z*se,
+			StdErr:   se,
+			Bias:     float64(n-1) * (mean - point),
+		}
+	}
+
+	return Intervals{
+		Method:     "jackknife",
+		Level:      level,
+		Resamples:  n,
+		VaR:        normal(point.VaR, func(m Measures) float64 { return m.VaR }),
+		ES:         normal(point.ES, func(m Measures) float64 { return m.ES }),
+		Volatility: normal(point.Volatility, func(m Measures) float64 { return m.Volatility }),
+	}, nil
+}
+
+// NormalQuantile returns the quantile of the standard normal distribution at
+// p in (0, 1).
+func NormalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// quantile returns the quantile at p of sorted values, interpolating linearly
+// between order statistics.
+func quantile(sorted []float64, p float64) float64 {
+	h := p * float64(len(sorted)-1)
+	i := int(math.Floor(h))
+	if i >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+
+	return sorted[i] + (h-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// meanStd returns the mean and standard deviation of the values, the variance
+// being divided by n-ddof.
+func meanStd(values []float64, ddof int) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+
+	return mean, math.Sqrt(variance / float64(len(values)-ddof))
+}
@@ -0,0 +1,232 @@
+/*
+Package risk computes historical risk measures from scenario P&L.
+
+Measures follow the P&L sign: a loss is negative, so VaR and ES are negative
+for a position losing money in its tail. At confidence level c, the tail holds
+the lowest 1-c of the scenario weight; VaR is the P&L at which the tail is
+reached and ES is the weighted mean P&L of the tail, the boundary scenario
+counting for the part of its weight inside the tail. With n equally weighted
+scenarios and (1-c)·n whole, VaR is the (1-c)·n-th lowest P&L and ES the mean of
+the (1-c)·n lowest.
+*/
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Observation is the P&L of a scenario.
+type Observation struct {
+	ID     uint32  `json:"id"`
+	PnL    float64 `json:"pnl"`
+	Weight float64 `json:"weight"`
+}
+
+// Measures are the risk measures of a set of observations at a confidence
+// level.
+type Measures struct {
+	Confidence float64 `json:"confidence"`
+	VaR        float64 `json:"var"`
+	ES         float64 `json:"es"`
+	Volatility float64 `json:"volatility"`
+
+	// Scenarios is the number of observations, TailScenarios the number of
+	// observations at least partly in the tail.
+	Scenarios     int `json:"scenarios"`
+	TailScenarios int `json:"tailScenarios"`
+
+	// VaRScenario is the scenario at which the tail is reached.
+	VaRScenario uint32 `json:"varScenario"`
+}
+
+var (
+	// ErrNoObservation is returned for an empty set of observations.
+	ErrNoObservation = errors.New("no observation")
+
+	// ErrInvalidWeight is returned for negative or non-finite weights, or
+	// weights summing to zero.
+	ErrInvalidWeight = errors.New("invalid weight")
+)
+
+// EqualWeights returns observations of the P&L with unit weights, in order.
+func EqualWeights(pnl []float64) []Observation {
+	obs := make([]Observation, len(pnl))
+	for i, v := range pnl {
+		obs[i] = Observation{ID: uint32(i), PnL: v, Weight: 1.0}
+	}
+
+	return obs
+}
+
+// Compute returns the measures of the observations at the confidence level,
+// which must be in (0, 1).
+func Compute(obs []Observation, confidence float64) (Measures, error) {
+	if confidence <= 0 || confidence >= 1 || math.IsNaN(confidence) {
+		return Measures{}, fmt.Errorf("confidence level %v is not in (0, 1)", confidence)
+	}
+
+	sorted, total, err := prepare(obs)
+	if err != nil {
+		return Measures{}, err
+	}
+
+	m := Measures{
+		Confidence: confidence,
+		Scenarios:  len(sorted),
+		Volatility: volatility(sorted, total),
+	}
+
+	tail := tailOf(sorted, total, confidence)
+
+	var cumulated, tailSum float64
+	for _, o := range tail {
+		tailSum += o.Weight * o.PnL
+		cumulated += o.Weight
+	}
+
+	last := tail[len(tail)-1]
+	m.VaR, m.VaRScenario, m.TailScenarios = last.PnL, last.ID, len(tail)
+	m.ES = tailSum / cumulated
+
+	return m, nil
+}
+
+// Tail returns the observations of the tail at the confidence level, sorted
+// by increasing P&L, each weighted by the part of its weight inside the tail.
+func Tail(obs []Observation, confidence float64) ([]Observation, error) {
+	if confidence <= 0 || confidence >= 1 || math.IsNaN(confidence) {
+		return nil, fmt.Errorf("confidence level %v is not in (0, 1)", confidence)
+	}
+
+	sorted, total, err := prepare(obs)
+	if err != nil {
+		return nil, err
+	}
+
+	return tailOf(sorted, total, confidence), nil
+}
+
+// tailOf accumulates the sorted observations up to a weight of 1-confidence
+// of the total. A relative tolerance keeps 50 scenarios of weight 1/500 a
+// tail of exactly 50 at 90%. When the confidence level leaves no weight in
+// the tail, the lowest observation alone makes it.
+func tailOf(sorted []Observation, total, confidence float64) []Observation {
+	alpha := (1 - confidence) * total
+	eps := 1e-12 * total
+
+	tail := make([]Observation, 0)
+	cumulated := 0.0
+	for _, o := range sorted {
+		inTail := math.Min(o.Weight, alpha-cumulated)
+		if inTail <= eps {
+			break
+		}
+
+		tail = append(tail, Observation{ID: o.ID, PnL: o.PnL, Weight: inTail})
+		cumulated += inTail
+
+		if cumulated >= alpha-eps {
+			break
+		}
+	}
+
+	if len(tail) == 0 {
+		tail = append(tail, sorted[0])
+	}
+
+	return tail
+}
+
+// VaR returns the value at risk of the observations at the confidence level.
+func VaR(obs []Observation, confidence float64) (float64, error) {
+	m, err := Compute(obs, confidence)
+
+	return m.VaR, err
+}
+
+// ES returns the expected shortfall of the observations at the confidence
+// level.
+func ES(obs []Observation, confidence float64) (float64, error) {
+	m, err := Compute(obs, confidence)
+
+	return m.ES, err
+}
+
+// Volatility returns the weighted standard deviation of the P&L around its
+// weighted mean.
+func Volatility(obs []Observation) (float64, error) {
+	sorted, total, err := prepare(obs)
+	if err != nil {
+		return 0, err
+	}
+
+	return volatility(sorted, total), nil
+}
+
+// Sorted returns a copy of the observations sorted by increasing P&L, ties
+// broken by scenario ID so that results do not depend on the input order.
+func Sorted(obs []Observation) []Observation {
+	sorted := make([]Observation, len(obs))
+	copy(sorted, obs)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].PnL != sorted[j].PnL {
+			return sorted[i].PnL < sorted[j].PnL
+		}
+
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	return sorted
+}
+
+// prepare validates the observations, drops those of zero weight and returns
+// them sorted with their total weight.
+func prepare(obs []Observation) ([]Observation, float64, error) {
+	kept := make([]Observation, 0, len(obs))
+	total := 0.0
+	for _, o := range obs {
+		if math.IsNaN(o.PnL) || math.IsInf(o.PnL, 0) {
+			return nil, 0, fmt.Errorf("scenario %d has a non-finite P&L %v", o.ID, o.PnL)
+		}
+
+		if o.Weight < 0 || math.IsNaN(o.Weight) || math.IsInf(o.Weight, 0) {
+			return nil, 0, fmt.Errorf("%w: scenario %d has weight %v", ErrInvalidWeight, o.ID, o.Weight)
+		}
+
+		if o.Weight == 0 {
+			continue
+		}
+
+		kept = append(kept, o)
+		total += o.Weight
+	}
+
+	if len(obs) == 0 {
+		return nil, 0, ErrNoObservation
+	}
+
+	if total == 0 {
+		return nil, 0, fmt.Errorf("%w: weights sum to zero", ErrInvalidWeight)
+	}
+
+	return Sorted(kept), total, nil
+}
+
+func volatility(obs []Observation, total float64) float64 {
+	mean := 0.0
+	for _, o := range obs {
+		mean += o.Weight * o.PnL
+	}
+	mean /= total
+
+	variance := 0.0
+	for _, o := range obs {
+		variance += o.Weight * (o.PnL - mean) * (o.PnL - mean)
+	}
+
+	return math.Sqrt(variance / total)
+}
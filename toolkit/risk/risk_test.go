@@ -0,0 +1,206 @@
+package risk
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Compute_EqualWeights(t *testing.T) {
+	t.Parallel()
+
+	// P&L of -1, -2, ..., -500 in shuffled order.
+	pnl := make([]float64, 500)
+	for i := range pnl {
+		pnl[i] = -float64((i*7)%500 + 1)
+	}
+
+	m, err := Compute(EqualWeights(pnl), 0.9)
+	require.NoError(t, err)
+
+	// The 50 lowest are -500..-451.
+	assert.InDelta(t, -451.0, m.VaR, 1e-12)
+	assert.InDelta(t, -475.5, m.ES, 1e-12)
+	assert.Equal(t, 50, m.TailScenarios)
+	assert.Equal(t, 500, m.Scenarios)
+	assert.InDelta(t, math.Sqrt((500*500-1)/12.0), m.Volatility, 1e-9)
+}
+
+func Test_Compute_Weights(t *testing.T) {
+	t.Parallel()
+
+	obs := []Observation{
+		{ID: 1, PnL: -10, Weight: 1},
+		{ID: 2, PnL: -5, Weight: 3},
+		{ID: 3, PnL: 0, Weight: 4},
+		{ID: 4, PnL: 5, Weight: 2},
+	}
+
+	// Tail weight of 2: all of scenario 1 and a third of scenario 2.
+	m, err := Compute(obs, 0.8)
+	require.NoError(t, err)
+	assert.InDelta(t, -5.0, m.VaR, 1e-12)
+	assert.Equal(t, uint32(2), m.VaRScenario)
+	assert.InDelta(t, -7.5, m.ES, 1e-12)
+	assert.Equal(t, 2, m.TailScenarios)
+
+	// Tail weight of 0.5: half of scenario 1.
+	m, err = Compute(obs, 0.95)
+	require.NoError(t, err)
+	assert.InDelta(t, -10.0, m.VaR, 1e-12)
+	assert.InDelta(t, -10.0, m.ES, 1e-12)
+
+	// Zero weights are ignored.
+	m, err = Compute(append(obs, Observation{ID: 5, PnL: -100}), 0.8)
+	require.NoError(t, err)
+	assert.InDelta(t, -7.5, m.ES, 1e-12)
+}
+
+func Test_Compute_Errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Compute(nil, 0.9)
+	assert.ErrorIs(t, err, ErrNoObservation)
+
+	_, err = Compute(EqualWeights([]float64{1}), 1.0)
+	assert.Error(t, err)
+
+	_, err = Compute([]Observation{{PnL: 1, Weight: -1}}, 0.9)
+	assert.ErrorIs(t, err, ErrInvalidWeight)
+
+	_, err = Compute([]Observation{{PnL: 1}}, 0.9)
+	assert.ErrorIs(t, err, ErrInvalidWeight)
+
+	_, err = Compute(EqualWeights([]float64{math.NaN()}), 0.9)
+	assert.Error(t, err)
+}
+
+func Test_Volatility(t *testing.T) {
+	t.Parallel()
+
+	vol, err := Volatility(EqualWeights([]float64{1, 3}))
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, vol, 1e-12)
+
+	vol, err = Volatility([]Observation{{PnL: 1, Weight: 3}, {PnL: 5, Weight: 1}})
+	require.NoError(t, err)
+	assert.InDelta(t, math.Sqrt(3.0), vol, 1e-12)
+}
+
+func Test_Tail_Contributions(t *testing.T) {
+	t.Parallel()
+
+	obs := []Observation{
+		{ID: 1, PnL: -10, Weight: 1},
+		{ID: 2, PnL: -5, Weight: 3},
+		{ID: 3, PnL: 0, Weight: 4},
+		{ID: 4, PnL: 5, Weight: 2},
+	}
+
+	tail, err := Tail(obs, 0.8)
+	require.NoError(t, err)
+	require.Len(t, tail, 2)
+	assert.Equal(t, uint32(2), tail[1].ID)
+	assert.InDelta(t, 1.0, tail[1].Weight, 1e-12)
+
+	components := map[string]map[uint32]float64{
+		"a": {1: -12, 2: -1, 3: 1, 4: 2},
+		"b": {1: 2, 2: -4, 3: -1},
+	}
+
+	contributions, es, err := Contributions(obs, components, 0.8)
+	require.NoError(t, err)
+	assert.InDelta(t, -7.5, es, 1e-12)
+	require.Len(t, contributions, 2)
+	assert.Equal(t, "a", contributions[0].Name)
+	assert.InDelta(t, -6.5, contributions[0].ES, 1e-12)
+	assert.InDelta(t, -1.0, contributions[1].ES, 1e-12)
+	assert.InDelta(t, 1.0, contributions[0].Share+contributions[1].Share, 1e-12)
+}
+
+func Test_Bootstrap_Jackknife(t *testing.T) {
+	t.Parallel()
+
+	pnl := make([]float64, 500)
+	for i := range pnl {
+		pnl[i] = -float64((i*7)%500 + 1)
+	}
+	obs := EqualWeights(pnl)
+
+	b, err := Bootstrap(obs, 0.9, 0.95, 200, 42)
+	require.NoError(t, err)
+	assert.Equal(t, "bootstrap", b.Method)
+	assert.Equal(t, 200, b.Resamples)
+	assert.InDelta(t, -475.5, b.ES.Estimate, 1e-12)
+	assert.Less(t, b.ES.Lower, b.ES.Estimate)
+	assert.Greater(t, b.ES.Upper, b.ES.Estimate)
+	assert.Less(t, b.VaR.Lower, b.VaR.Upper)
+
+	// Same seed, same intervals.
+	again, err := Bootstrap(obs, 0.9, 0.95, 200, 42)
+	require.NoError(t, err)
+	assert.Equal(t, b, again)
+
+	j, err := Jackknife(obs, 0.9, 0.95)
+	require.NoError(t, err)
+	assert.Equal(t, 500, j.Resamples)
+	assert.InDelta(t, -475.5, j.ES.Estimate, 1e-12)
+	assert.Positive(t, j.ES.StdErr)
+	assert.InDelta(t, j.ES.Estimate-1.959964*j.ES.StdErr, j.ES.Lower, 1e-4)
+
+	_, err = Bootstrap(obs, 0.9, 0.95, 1, 42)
+	assert.Error(t, err)
+
+	assert.InDelta(t, 1.959964, NormalQuantile(0.975), 1e-6)
+}
+
+func Test_StudentQuantile(t *testing.T) {
+	t.Parallel()
+
+	assert.InDelta(t, -2.228139, StudentQuantile(0.025, 10), 1e-6)
+	assert.InDelta(t, -6.313752, StudentQuantile(0.05, 1), 1e-6)
+	assert.InDelta(t, 1.644854, StudentQuantile(0.95, 1e6), 1e-4)
+	assert.InDelta(t, 0.5, StudentCDF(0, 3), 1e-12)
+}
+
+func Test_ParametricMeasures(t *testing.T) {
+	t.Parallel()
+
+	// Normal quantiles on an even grid: no skew, no excess kurtosis.
+	pnl := make([]float64, 10000)
+	for i := range pnl {
+		pnl[i] = 2 * NormalQuantile((float64(i)+0.5)/float64(len(pnl)))
+	}
+
+	m, models, err := ParametricMeasures(EqualWeights(pnl), 0.975)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.0, m.Mean, 1e-9)
+	assert.InDelta(t, 2.0, m.StdDev, 1e-2)
+	assert.InDelta(t, 0.0, m.Skewness, 1e-9)
+	assert.InDelta(t, 0.0, m.Kurtosis, 5e-2)
+	require.Len(t, models, 3)
+
+	// VaR = -1.96 σ, ES = -σ φ(1.96) / 2.5%.
+	for _, p := range models {
+		assert.InDelta(t, -3.92, p.VaR, 5e-2, p.Model)
+		assert.InDelta(t, -4.67, p.ES, 5e-2, p.Model)
+	}
+	assert.Greater(t, models[1].DoF, 50.0)
+
+	// Fat tails: the Student-t and Cornish-Fisher ES go beyond the Gaussian.
+	for i := range pnl {
+		pnl[i] = StudentQuantile((float64(i)+0.5)/float64(len(pnl)), 4)
+	}
+
+	m, models, err = ParametricMeasures(EqualWeights(pnl), 0.99)
+	require.NoError(t, err)
+	assert.Greater(t, m.Kurtosis, 1.0)
+	assert.InDelta(t, 4.0, models[1].DoF, 1)
+	assert.Less(t, models[1].ES, models[0].ES)
+	assert.Less(t, models[2].ES, models[0].ES)
+
+	_, _, err = ParametricMeasures(EqualWeights(pnl), 1)
+	assert.Error(t, err)
+}
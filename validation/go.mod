@@ -0,0 +1,102 @@
+module validation
+
+go 1.24.0
+
+require (
+	cdsanalysis v0.0.0
+	curveassets v0.0.0
+	fire v0.0.0
+	issuers v0.0.0
+	issuersratings v0.0.0
+	liquidityvalidation v0.0.0
+	manualblocked v0.0.0
+	positionCashFlowScript v0.0.0
+	pricingcompare v0.0.0
+	removecocosuspects v0.0.0
+	scripts v0.0.0
+	tocalladam v0.0.0
+	tocalleve v0.0.0
+	toolkit v0.0.0
+	ytmValidation v0.0.0
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.32.6 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.25 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.25 // indirect
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.36.5 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.12.1 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.10.4 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sqs v1.37.0 // indirect
+	github.com/aws/smithy-go v1.22.1 // indirect
+	github.com/cenkalti/backoff/v4 v4.3.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/edgelaboratories/eve/pkg/asset v0.21.3 // indirect
+	github.com/edgelaboratories/eve/pkg/fx v0.2.0 // indirect
+	github.com/edgelaboratories/eve/pkg/marketdata v0.12.0 // indirect
+	github.com/edgelaboratories/go-errors v1.9.1 // indirect
+	github.com/edgelaboratories/go-libraries/date v1.0.1 // indirect
+	github.com/edgelaboratories/go-libraries/daycount v1.0.0 // indirect
+	github.com/edgelaboratories/go-libraries/go-client/httpreq v1.2.0 // indirect
+	github.com/edgelaboratories/go-libraries/interpolator v1.1.0 // indirect
+	github.com/edgelaboratories/go-opentelemetry/v2 v2.12.1 // indirect
+	github.com/felixge/httpsnoop v1.0.4 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/gogo/protobuf v1.3.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/gorilla/mux v1.8.1 // indirect
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.23.0 // indirect
+	github.com/hashicorp/errwrap v1.0.0 // indirect
+	github.com/hashicorp/go-multierror v1.1.1 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/mattn/go-sqlite3 v1.14.22 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/sirupsen/logrus v1.9.3 // indirect
+	github.com/stretchr/testify v1.10.0 // indirect
+	go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws v0.57.0 // indirect
+	go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux v0.57.0 // indirect
+	go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc v0.57.0 // indirect
+	go.opentelemetry.io/contrib/instrumentation/net/http/httptrace/otelhttptrace v0.57.0 // indirect
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.57.0 // indirect
+	go.opentelemetry.io/contrib/propagators/jaeger v1.32.0 // indirect
+	go.opentelemetry.io/contrib/samplers/jaegerremote v0.26.0 // indirect
+	go.opentelemetry.io/otel v1.32.0 // indirect
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace v1.32.0 // indirect
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.32.0 // indirect
+	go.opentelemetry.io/otel/metric v1.32.0 // indirect
+	go.opentelemetry.io/otel/sdk v1.32.0 // indirect
+	go.opentelemetry.io/otel/trace v1.32.0 // indirect
+	go.opentelemetry.io/proto/otlp v1.3.1 // indirect
+	golang.org/x/exp v0.0.0-20240604190554-fc45aab8b7f8 // indirect
+	golang.org/x/net v0.33.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	golang.org/x/tools v0.22.0 // indirect
+	gonum.org/v1/gonum v0.15.1 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20241202173237-19429a94021a // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250207221924-e9438ea467c6 // indirect
+	google.golang.org/grpc v1.70.0 // indirect
+	google.golang.org/protobuf v1.36.4 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	cdsanalysis => ../cdsanalysis
+	curveassets => ../curveassets
+	fire => ../fire
+	issuers => ../issuers
+	issuersratings => ../issuersratings
+	liquidityvalidation => ../liquidityvalidation
+	manualblocked => ../manualblocked
+	positionCashFlowScript => ../positionCashFlowScript
+	pricingcompare => ../pricingcompare
+	removecocosuspects => ../removecocosuspects
+	scripts => ../esVarScript
+	tocalladam => ../tocalladam
+	tocalleve => ../tocalleve
+	toolkit => ../toolkit
+	ytmValidation => ../ytmValidation
+)
@@ -0,0 +1,126 @@
+/*
+Command validation is the single entry point of the validation scripts.
+
+Every script is linked into the binary and run in process with the arguments
+left after its subcommand, from the current directory:
+
+	validation cds calibrate -env DEV -input issuers.csv
+	validation liquidity validate -resume
+	validation esvar -h
+
+The scripts keep their own module and a main package under their cmd folder,
+so each of them still builds on its own.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"cdsanalysis"
+	"curveassets"
+	"fire"
+	"issuers"
+	"issuersratings"
+	"liquidityvalidation"
+	"manualblocked"
+	"positionCashFlowScript"
+	"pricingcompare"
+	"removecocosuspects"
+	"scripts"
+	"tocalladam"
+	"tocalleve"
+	"ytmValidation"
+
+	backtest "toolkit/backtest/cli"
+	cache "toolkit/cache/cli"
+	health "toolkit/health/cli"
+	pricingdiff "toolkit/pricingdiff/cli"
+)
+
+type command struct {
+	path        []string
+	run         func()
+	description string
+}
+
+var commands = []command{
+	{[]string{"cds", "calibrate"}, cdsanalysis.Main, "calibrate the CDS credit curves against Scalpel"},
+	{[]string{"ytm", "validate"}, ytmvalidation.Main, "compare Arcanist yields to maturity"},
+	{[]string{"liquidity", "validate"}, liquidityvalidation.Main, "compare Eve liquidity horizons to Arcanist and Recco ES"},
+	{[]string{"tocall", "eve"}, tocalleve.Main, "price the to-call assets with Eve"},
+	{[]string{"tocall", "adam"}, tocalladam.Main, "fetch the to-call sensitivities from Adam"},
+	{[]string{"issuers", "dump"}, issuers.Main, "dump the issuer descriptions from Cerberus"},
+	{[]string{"ratings", "dump"}, issuersratings.Main, "dump the issuer ratings from Cerberus"},
+	{[]string{"curveassets", "count"}, curveassets.Main, "count the Scalpel curve assets per issuer"},
+	{[]string{"proxies", "check"}, manualblocked.Main, "list the manually blocked proxies from Hippo"},
+	{[]string{"coco", "filter"}, removecocosuspects.Main, "remove the CoCo bonds from the credit suspects"},
+	{[]string{"pricing", "retrigger"}, fire.Main, "retrigger the pricing through Maestro"},
+	{[]string{"cashflows"}, positioncashflowscript.Main, "compute the position cash flows with Arcanist"},
+	{[]string{"pricing", "compare"}, pricingcompare.Main, "price assets in DEV and PROD and rank the gaps per asset type"},
+	{[]string{"pricing", "diff"}, pricingdiff.Main, "compare the Eve results and requests of two environments"},
+	{[]string{"esvar"}, scripts.Main, "compute VaR, ES and volatility from an Eve result"},
+	{[]string{"health"}, health.Main, "count scenario statuses and anomalies and score Eve results"},
+	{[]string{"backtest"}, backtest.Main, "backtest predicted VaR and ES against realised P&L"},
+	{[]string{"cache"}, cache.Main, "inspect and invalidate the response cache"},
+}
+
+func main() {
+	args := os.Args[1:]
+
+	cmd, rest, ok := lookup(args)
+	if !ok {
+		printUsage()
+
+		if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+			return
+		}
+
+		os.Exit(2)
+	}
+
+	// The scripts parse os.Args themselves, as they would in their own binary.
+	os.Args = append([]string{"validation " + strings.Join(cmd.path, " ")}, rest...)
+	cmd.run()
+}
+
+// lookup returns the command whose path prefixes the arguments, and the
+// arguments left for the script.
+func lookup(args []string) (command, []string, bool) {
+	for _, cmd := range commands {
+		if len(args) < len(cmd.path) {
+			continue
+		}
+
+		match := true
+		for i, word := range cmd.path {
+			if args[i] != word {
+				match = false
+
+				break
+			}
+		}
+
+		if match {
+			return cmd, args[len(cmd.path):], true
+		}
+	}
+
+	return command{}, nil, false
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: validation <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", strings.Join(cmd.path, " "), cmd.description)
+	}
+	w.Flush()
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'validation <command> -h' for the flags of a command.")
+}
@@ -0,0 +1,9 @@
+// Command ytmValidation runs the ytmValidation script on its own, outside of the
+// validation command.
+package main
+
+import "ytmValidation"
+
+func main() {
+	ytmvalidation.Main()
+}
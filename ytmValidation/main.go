@@ -1,29 +1,38 @@
-package main
+package ytmvalidation
 
 import (
-	"encoding/csv"
-	"os"
+	"flag"
 
 	log "github.com/sirupsen/logrus"
+
+	"toolkit/input"
+	"toolkit/manifest"
 )
 
-func main() {
-	file, err := os.Open("input.csv")
-	if err != nil {
-		log.Fatal("Error while reading the file", err)
-	}
+// cerberusHost resolves the ISINs given in the input.
+var cerberusHost = "https://api.edgelab.ch/cerberus"
 
-	defer file.Close()
+// Main parses the command line flags and runs the script.
+func Main() {
+	inputPath := flag.String("input", "input.csv", "CSV or JSON list of bond IDs or ISINs, - for stdin")
+	column := flag.String("column", "", "name or index of the ID column of a CSV input")
+	flag.StringVar(&cerberusHost, "cerberus-url", cerberusHost, "Cerberus host resolving the ISINs")
+	outputPath := flag.String("output", "output.csv", "output CSV file")
+	flag.StringVar(&snapshot, "snapshot", snapshot, "snapshot of the yields")
+	flag.StringVar(&requestURL, "arcanist-url", requestURL, "Arcanist instrument metric endpoint")
+	flag.StringVar(&token, "token", token, "bearer token")
+	flag.Parse()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	run := manifest.New("ytm validate")
+	run.RecordFlags(flag.CommandLine)
+
+	bondIDs, err := input.Read(*inputPath, input.Options{Column: *column, Resolver: input.Cerberus(cerberusHost, token)})
 	if err != nil {
 		log.Fatal("Error while reading the file", err)
 	}
 
-	bondIDs := make([]string, 0)
-	for _, record := range records[1:] {
-		bondIDs = append(bondIDs, record[0])
+	if err := run.AddInput(*inputPath); err != nil {
+		log.Fatal("Error while hashing the input", err)
 	}
 
 	log.Info("Starting the process in Arcanist")
@@ -31,12 +40,17 @@ func main() {
 	if err != nil {
 		log.Fatal("Error while calling request", err)
 	}
+	run.Count("arcanist", len(bondIDs), len(output))
 
 	log.Info("Starting the process in Eve")
 
 	// Write output csv.
-	err = outputToCsv(output)
+	err = outputToCsv(*outputPath, output)
 	if err != nil {
 		log.Fatalf("Error converting results to csv: %v", err)
 	}
+
+	if err := run.Write(*outputPath); err != nil {
+		log.Fatalf("Error writing the run manifest: %v", err)
+	}
 }